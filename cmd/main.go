@@ -3,20 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 
 	"todolist/config"
+	"todolist/internal/domain"
 	"todolist/internal/handler/telegram"
+	"todolist/internal/repository/memory"
 	"todolist/internal/repository/postgres"
 	"todolist/internal/scheduler"
+	"todolist/internal/sender"
 	"todolist/internal/usecase"
+	"todolist/migrations"
 )
 
 func main() {
@@ -40,21 +49,53 @@ func main() {
 	}
 	defer db.Close()
 
-	// Создание таблиц
-	if err = db.CreateTables(); err != nil {
-		logger.Fatal("failed to create tables", zap.Error(err))
+	// Миграции базы данных
+	migrator, err := postgres.NewMigrator(db, migrations.FS, map[string]string{
+		"todolist.legacy_timezone": cfg.Database.LegacyTimezone,
+	})
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+
+	// Подкоманда "migrate up|down [N]|status" выполняет миграции и завершает работу
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(migrator, os.Args[2:]); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		logger.Fatal("failed to apply migrations", zap.Error(err))
+	}
+	for _, migration := range applied {
+		logger.Info("migration applied", zap.Int("version", migration.Version), zap.String("name", migration.Name))
 	}
 
 	// Инициализация репозиториев
 	userRepo := postgres.NewUserRepository(db)
 	sessionRepo := postgres.NewSessionRepository(db)
 	taskRepo := postgres.NewTaskRepository(db)
+	reminderRepo := postgres.NewReminderRepository(db)
+	outboxRepo := postgres.NewNotificationOutboxRepository(db)
+	jobLocker := postgres.NewJobLocker(db)
 	noteRepo := postgres.NewNoteRepository(db)
+	inviteRepo := postgres.NewInviteRepository(db)
+	loginAttemptRepo := postgres.NewLoginAttemptRepository(db)
+	statsRepo := postgres.NewStatsRepository(db)
+	recoveryCodeRepo := postgres.NewRecoveryCodeRepository(db)
 
-	// Инициализация сервисов
-	authService := usecase.NewAuthService(userRepo, sessionRepo, cfg, logger)
-	taskService := usecase.NewTaskService(taskRepo, logger)
-	noteService := usecase.NewNoteService(noteRepo)
+	// Состояния диалогов хранятся в базе, чтобы незаконченный диалог переживал перезапуск
+	var stateRepo domain.ConversationStateRepository
+	switch cfg.Bot.StateStorage {
+	case "memory":
+		stateRepo = memory.NewConversationStateRepository()
+	case "postgres":
+		stateRepo = postgres.NewConversationStateRepository(db)
+	default:
+		logger.Fatal("unknown state storage, use postgres or memory", zap.String("storage", cfg.Bot.StateStorage))
+	}
 
 	// Инициализация телеграм бота
 	bot, err := tgbotapi.NewBotAPI(cfg.Bot.Token)
@@ -65,14 +106,30 @@ func main() {
 
 	logger.Info("bot authorized", zap.String("username", bot.Self.UserName))
 
-	// Инициализация сервиса уведомлений
-	notificationService := usecase.NewNotificationService(bot, taskService, logger)
+	// Все исходящие сообщения проходят через Sender, который соблюдает лимиты Telegram
+	messageSender := sender.New(bot, cfg.Sender, logger)
+
+	// Инициализация сервисов. Сервис уведомлений создается раньше сервиса авторизации,
+	// чтобы тот мог предупреждать администраторов о подборе пароля
+	taskService := usecase.NewTaskService(taskRepo, reminderRepo, userRepo, cfg, logger)
+	notificationService := usecase.NewNotificationService(messageSender, taskService, outboxRepo, userRepo, cfg, logger)
+	twoFactorService, err := usecase.NewTwoFactorService(userRepo, recoveryCodeRepo, cfg, logger)
+	if err != nil {
+		logger.Fatal("failed to create two-factor service", zap.Error(err))
+	}
+	if !twoFactorService.Available() {
+		logger.Warn("AUTH_TOTP_KEY is not set, two-factor authentication cannot be enabled")
+	}
+	authService := usecase.NewAuthService(userRepo, sessionRepo, inviteRepo, loginAttemptRepo, twoFactorService, notificationService, cfg, logger)
+	userService := usecase.NewUserService(userRepo, cfg, logger)
+	noteService := usecase.NewNoteService(noteRepo)
+	adminService := usecase.NewAdminService(userRepo, sessionRepo, statsRepo, notificationService, logger)
 
 	// Инициализация обработчика телеграм бота
-	telegramHandler := telegram.NewBot(bot, authService, taskService, noteService, notificationService, cfg, logger)
+	telegramHandler := telegram.NewBot(bot, messageSender, authService, twoFactorService, taskService, noteService, userService, adminService, notificationService, stateRepo, cfg, logger)
 
 	// Инициализация планировщика
-	cronScheduler := scheduler.NewCronScheduler(notificationService, authService, logger)
+	cronScheduler := scheduler.NewCronScheduler(notificationService, authService, stateRepo, jobLocker, cfg, logger)
 
 	// Создание контекста для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -102,6 +159,17 @@ func main() {
 		}
 	}()
 
+	// Запуск сервера метрик
+	if cfg.Metrics.Listen != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if mErr := runMetricsServer(ctx, cfg.Metrics.Listen, logger); mErr != nil {
+				logger.Error("metrics server error", zap.Error(mErr))
+			}
+		}()
+	}
+
 	logger.Info("application started")
 
 	// Ожидание сигнала завершения
@@ -114,3 +182,88 @@ func main() {
 
 	logger.Info("application stopped")
 }
+
+// runMetricsServer публикует метрики expvar по адресу /debug/vars до отмены ctx
+func runMetricsServer(ctx context.Context, listen string, logger *zap.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	logger.Info("metrics server started", zap.String("listen", listen))
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
+
+// runMigrateCommand выполняет подкоманду migrate: up, down [N] (по умолчанию 1) или status
+func runMigrateCommand(migrator *postgres.Migrator, args []string) error {
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %03d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-30s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down [N] or status", command)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -13,13 +14,80 @@ type Config struct {
 	Bot      BotConfig
 	Database DatabaseConfig
 	Auth     AuthConfig
+	Tasks    TasksConfig
+	Notify   NotifyConfig
+	Webhook  WebhookConfig
+	Sender   SenderConfig
+	Metrics  MetricsConfig
 }
 
+// Режимы получения обновлений
+const (
+	BotModePolling = "polling"
+	BotModeWebhook = "webhook"
+)
+
 // BotConfig содержит настройки телеграм бота
 type BotConfig struct {
 	Token   string
 	Debug   bool
 	Timeout time.Duration
+	// Mode - способ получения обновлений: polling (long polling) или webhook (HTTP-сервер, настройки в WebhookConfig)
+	Mode string
+	// Timezone - часовой пояс по умолчанию для пользователей, не выбравших свой
+	Timezone string
+	// StateStorage - где хранить состояния диалогов: postgres (переживают перезапуск) или memory
+	StateStorage string
+	// StateTimeout - сколько ждать ответа пользователя в многошаговом диалоге
+	StateTimeout time.Duration
+	// Workers - число воркеров, обрабатывающих обновления; обновления одного пользователя
+	// обрабатывает один воркер по порядку
+	Workers int
+	// WorkerQueueSize - размер очереди каждого воркера, при заполнении прием обновлений приостанавливается
+	WorkerQueueSize int
+	// ShutdownTimeout - сколько ждать обработки принятых обновлений при остановке
+	ShutdownTimeout time.Duration
+	// UserRateLimit - сколько команд и нажатий кнопок в минуту допускается от одного пользователя, 0 - без ограничения
+	UserRateLimit int
+	// UserRateBurst - сколько команд подряд пользователь может отправить без ожидания
+	UserRateBurst int
+	// CallbackSecret - секрет для подписи данных inline-кнопок; пустой - кнопки не подписываются
+	CallbackSecret string
+}
+
+// WebhookConfig содержит настройки приема обновлений через webhook
+type WebhookConfig struct {
+	// Listen - адрес HTTP-сервера, например ":8443"
+	Listen string
+	// Path - путь, на который Telegram отправляет обновления
+	Path string
+	// URL - публичный адрес webhook (обычно адрес ingress); пустой - webhook не регистрируется в Telegram при запуске
+	URL string
+	// Secret - секрет, который Telegram передает в заголовке X-Telegram-Bot-Api-Secret-Token
+	Secret string
+	// TLSCert и TLSKey - сертификат и ключ для HTTPS; пустые - сервер работает по HTTP (TLS завершается на ingress)
+	TLSCert string
+	TLSKey  string
+	// UploadCert - передать сертификат в Telegram при регистрации (нужно для самоподписанного сертификата)
+	UploadCert bool
+}
+
+// SenderConfig содержит ограничения частоты исходящих запросов к Telegram
+type SenderConfig struct {
+	// GlobalRate - сколько сообщений в секунду бот отправляет во все чаты (Telegram допускает около 30)
+	GlobalRate int
+	// ChatInterval - интервал между сообщениями в один чат (Telegram допускает около одного в секунду)
+	ChatInterval time.Duration
+	// ChatBurst - сколько сообщений подряд можно отправить в чат без ожидания, например ответ и меню
+	ChatBurst int
+	// MaxRetries - сколько раз повторить запрос после ответа 429 с retry_after
+	MaxRetries int
+}
+
+// MetricsConfig содержит настройки публикации метрик
+type MetricsConfig struct {
+	// Listen - адрес HTTP-сервера с метриками expvar (/debug/vars), пустой - метрики не публикуются
+	Listen string
 }
 
 // DatabaseConfig содержит настройки базы данных
@@ -30,18 +98,89 @@ type DatabaseConfig struct {
 	Password string
 	Database string
 	SSLMode  string
+	// LegacyTimezone - часовой пояс, в котором прежние версии бота записывали время в колонки TIMESTAMP.
+	// Используется один раз миграцией 018 при переводе колонок в TIMESTAMPTZ.
+	LegacyTimezone string
 }
 
 // AuthConfig содержит настройки авторизации
 type AuthConfig struct {
-	Password       string
+	// Password - общий пароль для входа в аккаунты без личного пароля и для первого входа администраторов
+	Password string
+	// SessionTimeout - через сколько без действий сессия истекает; каждое действие продлевает ее
 	SessionTimeout time.Duration
+	// SessionMaxLifetime - абсолютный срок сессии с момента входа, после него нужно войти заново
+	SessionMaxLifetime time.Duration
+	// AdminIDs - Telegram ID пользователей, которые получают роль администратора при входе
+	AdminIDs []int64
+	// InviteTTL - срок действия кода приглашения
+	InviteTTL time.Duration
+	// LoginMaxAttempts - число неудачных попыток входа подряд, после которого Telegram ID блокируется
+	LoginMaxAttempts int
+	// LoginLockoutBase и LoginLockoutMax задают блокировку, которая удваивается с каждой следующей ошибкой
+	LoginLockoutBase time.Duration
+	LoginLockoutMax  time.Duration
+	// LoginGlobalLimit - число неудачных попыток всех пользователей за LoginGlobalWindow,
+	// после которого администраторы получают предупреждение. Вход при этом не блокируется.
+	LoginGlobalLimit  int
+	LoginGlobalWindow time.Duration
+	// TOTPKey - ключ шифрования секретов двухфакторной аутентификации, пустой ключ отключает подключение 2FA
+	TOTPKey string
+	// TOTPIssuer - название сервиса, которое показывает приложение-аутентификатор
+	TOTPIssuer string
+}
+
+// TasksConfig содержит настройки задач
+type TasksConfig struct {
+	// ReminderOffsets - за сколько до срока выполнения отправлять напоминания,
+	// по одному напоминанию на каждый интервал (0 - в момент срока)
+	ReminderOffsets []time.Duration
+}
+
+// NotifyConfig содержит настройки доставки уведомлений
+type NotifyConfig struct {
+	// PollInterval - как часто обработчик очереди проверяет новые уведомления
+	PollInterval time.Duration
+	// MaxAttempts - количество попыток отправки, после которого уведомление считается недоставленным
+	MaxAttempts int
+	// RetryBaseDelay и RetryMaxDelay задают экспоненциальную задержку между попытками
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
 }
 
 const (
-	_botTokenKey   = "BOT_TOKEN"
-	_botDebugKey   = "BOT_DEBUG"
-	_botTimeoutKey = "BOT_TIMEOUT"
+	_botTokenKey    = "BOT_TOKEN"
+	_botDebugKey    = "BOT_DEBUG"
+	_botTimeoutKey  = "BOT_TIMEOUT"
+	_botTimezoneKey = "BOT_TIMEZONE"
+	_botModeKey     = "BOT_MODE"
+
+	_botStateStorageKey = "BOT_STATE_STORAGE"
+	_botStateTimeoutKey = "BOT_STATE_TIMEOUT"
+
+	_botWorkersKey         = "BOT_WORKERS"
+	_botWorkerQueueSizeKey = "BOT_WORKER_QUEUE_SIZE"
+	_botShutdownTimeoutKey = "BOT_SHUTDOWN_TIMEOUT"
+
+	_botUserRateLimitKey = "BOT_USER_RATE_LIMIT"
+	_botUserRateBurstKey = "BOT_USER_RATE_BURST"
+
+	_botCallbackSecretKey = "BOT_CALLBACK_SECRET"
+
+	_webhookListenKey     = "BOT_WEBHOOK_LISTEN"
+	_webhookPathKey       = "BOT_WEBHOOK_PATH"
+	_webhookURLKey        = "BOT_WEBHOOK_URL"
+	_webhookSecretKey     = "BOT_WEBHOOK_SECRET"
+	_webhookTLSCertKey    = "BOT_WEBHOOK_TLS_CERT"
+	_webhookTLSKeyKey     = "BOT_WEBHOOK_TLS_KEY"
+	_webhookUploadCertKey = "BOT_WEBHOOK_UPLOAD_CERT"
+
+	_senderGlobalRateKey   = "SENDER_GLOBAL_RATE"
+	_senderChatIntervalKey = "SENDER_CHAT_INTERVAL"
+	_senderChatBurstKey    = "SENDER_CHAT_BURST"
+	_senderMaxRetriesKey   = "SENDER_MAX_RETRIES"
+
+	_metricsListenKey = "METRICS_LISTEN"
 
 	_dbHostKey     = "DB_HOST"
 	_dbPortKey     = "DB_PORT"
@@ -50,8 +189,31 @@ const (
 	_dbNameKey     = "DB_NAME"
 	_dbSSLModeKey  = "DB_SSLMODE"
 
+	_dbLegacyTimezoneKey = "DB_LEGACY_TIMEZONE"
+
 	_authPasswordKey    = "AUTH_PASSWORD"
 	_authSessionTimeout = "AUTH_SESSION_TIMEOUT"
+	_authSessionMaxKey  = "AUTH_SESSION_MAX_LIFETIME"
+	_authAdminIDsKey    = "AUTH_ADMIN_IDS"
+	_authInviteTTLKey   = "AUTH_INVITE_TTL"
+
+	_authLoginMaxAttemptsKey  = "AUTH_LOGIN_MAX_ATTEMPTS"
+	_authLoginLockoutBaseKey  = "AUTH_LOGIN_LOCKOUT_BASE"
+	_authLoginLockoutMaxKey   = "AUTH_LOGIN_LOCKOUT_MAX"
+	_authLoginGlobalLimitKey  = "AUTH_LOGIN_GLOBAL_LIMIT"
+	_authLoginGlobalWindowKey = "AUTH_LOGIN_GLOBAL_WINDOW"
+
+	_authTOTPKeyKey    = "AUTH_TOTP_KEY"
+	_authTOTPIssuerKey = "AUTH_TOTP_ISSUER"
+
+	_tasksReminderOffsetsKey = "TASKS_REMINDER_OFFSETS"
+	// _tasksReminderOffsetKey - устаревшая настройка с одним интервалом
+	_tasksReminderOffsetKey = "TASKS_REMINDER_OFFSET"
+
+	_notifyPollIntervalKey   = "NOTIFY_POLL_INTERVAL"
+	_notifyMaxAttemptsKey    = "NOTIFY_MAX_ATTEMPTS"
+	_notifyRetryBaseDelayKey = "NOTIFY_RETRY_BASE_DELAY"
+	_notifyRetryMaxDelayKey  = "NOTIFY_RETRY_MAX_DELAY"
 )
 
 // Load загружает конфигурацию из переменных окружения
@@ -63,9 +225,41 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		Bot: BotConfig{
-			Token:   getEnv(_botTokenKey, ""),
-			Debug:   getEnvBool(_botDebugKey, false),
-			Timeout: getEnvDuration(_botTimeoutKey, 60*time.Second),
+			Token:    getEnv(_botTokenKey, ""),
+			Debug:    getEnvBool(_botDebugKey, false),
+			Timeout:  getEnvDuration(_botTimeoutKey, 60*time.Second),
+			Timezone: getEnv(_botTimezoneKey, "Local"),
+			Mode:     getEnv(_botModeKey, BotModePolling),
+
+			StateStorage: getEnv(_botStateStorageKey, "postgres"),
+			StateTimeout: getEnvDuration(_botStateTimeoutKey, 30*time.Minute),
+
+			Workers:         getEnvInt(_botWorkersKey, 8),
+			WorkerQueueSize: getEnvInt(_botWorkerQueueSizeKey, 64),
+			ShutdownTimeout: getEnvDuration(_botShutdownTimeoutKey, 30*time.Second),
+
+			UserRateLimit: getEnvInt(_botUserRateLimitKey, 30),
+			UserRateBurst: getEnvInt(_botUserRateBurstKey, 10),
+
+			CallbackSecret: getEnv(_botCallbackSecretKey, ""),
+		},
+		Webhook: WebhookConfig{
+			Listen:     getEnv(_webhookListenKey, ":8443"),
+			Path:       getEnv(_webhookPathKey, "/telegram/webhook"),
+			URL:        getEnv(_webhookURLKey, ""),
+			Secret:     getEnv(_webhookSecretKey, ""),
+			TLSCert:    getEnv(_webhookTLSCertKey, ""),
+			TLSKey:     getEnv(_webhookTLSKeyKey, ""),
+			UploadCert: getEnvBool(_webhookUploadCertKey, false),
+		},
+		Sender: SenderConfig{
+			GlobalRate:   getEnvInt(_senderGlobalRateKey, 25),
+			ChatInterval: getEnvDuration(_senderChatIntervalKey, time.Second),
+			ChatBurst:    getEnvInt(_senderChatBurstKey, 3),
+			MaxRetries:   getEnvInt(_senderMaxRetriesKey, 3),
+		},
+		Metrics: MetricsConfig{
+			Listen: getEnv(_metricsListenKey, ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv(_dbHostKey, "localhost"),
@@ -74,16 +268,59 @@ func Load() (*Config, error) {
 			Password: getEnv(_dbPasswordKey, "password"),
 			Database: getEnv(_dbNameKey, "todolist"),
 			SSLMode:  getEnv(_dbSSLModeKey, "disable"),
+
+			LegacyTimezone: getEnv(_dbLegacyTimezoneKey, "UTC"),
 		},
 		Auth: AuthConfig{
-			Password:       getEnv(_authPasswordKey, "password123"),
-			SessionTimeout: getEnvDuration(_authSessionTimeout, 24*time.Hour),
+			Password:           getEnv(_authPasswordKey, "password123"),
+			SessionTimeout:     getEnvDuration(_authSessionTimeout, 24*time.Hour),
+			SessionMaxLifetime: getEnvDuration(_authSessionMaxKey, 30*24*time.Hour),
+			AdminIDs:           getEnvInt64s(_authAdminIDsKey),
+			InviteTTL:          getEnvDuration(_authInviteTTLKey, 72*time.Hour),
+
+			LoginMaxAttempts:  getEnvInt(_authLoginMaxAttemptsKey, 5),
+			LoginLockoutBase:  getEnvDuration(_authLoginLockoutBaseKey, time.Minute),
+			LoginLockoutMax:   getEnvDuration(_authLoginLockoutMaxKey, 24*time.Hour),
+			LoginGlobalLimit:  getEnvInt(_authLoginGlobalLimitKey, 200),
+			LoginGlobalWindow: getEnvDuration(_authLoginGlobalWindowKey, 5*time.Minute),
+
+			TOTPKey:    getEnv(_authTOTPKeyKey, ""),
+			TOTPIssuer: getEnv(_authTOTPIssuerKey, "TodoList Bot"),
+		},
+		Tasks: TasksConfig{
+			ReminderOffsets: getEnvDurations(_tasksReminderOffsetsKey,
+				[]time.Duration{getEnvDuration(_tasksReminderOffsetKey, time.Hour)}),
+		},
+		Notify: NotifyConfig{
+			PollInterval:   getEnvDuration(_notifyPollIntervalKey, 5*time.Second),
+			MaxAttempts:    getEnvInt(_notifyMaxAttemptsKey, 8),
+			RetryBaseDelay: getEnvDuration(_notifyRetryBaseDelayKey, 30*time.Second),
+			RetryMaxDelay:  getEnvDuration(_notifyRetryMaxDelayKey, time.Hour),
 		},
 	}
 
 	return cfg, nil
 }
 
+// Location возвращает часовой пояс по умолчанию, при ошибке - часовой пояс сервера
+func (c BotConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// IsAdmin проверяет, указан ли Telegram ID в списке администраторов
+func (c AuthConfig) IsAdmin(telegramID int64) bool {
+	for _, id := range c.AdminIDs {
+		if id == telegramID {
+			return true
+		}
+	}
+	return false
+}
+
 // getEnv получает значение переменной окружения или возвращает значение по умолчанию
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -121,3 +358,32 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvDurations получает список duration значений через запятую ("24h,1h,0")
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || duration < 0 {
+			return defaultValue
+		}
+		durations = append(durations, duration)
+	}
+	return durations
+}
+
+// getEnvInt64s получает список целых чисел через запятую ("123,456"), некорректные значения пропускаются
+func getEnvInt64s(key string) []int64 {
+	var values []int64
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if value, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,250 @@
+// Package callbackdata кодирует данные inline-кнопок (callback_data) Telegram.
+//
+// Кнопка описывается структурой Data: сущность, действие над ней, ID и дополнительные аргументы.
+// В Telegram она передается строкой вида
+//
+//	1:task:show:42
+//	1:task:snooze:42:10m~Vx3kQ0aB9cE
+//
+// где 1 - версия формата, а после "~" идет подпись, если задан секрет.
+// Telegram ограничивает callback_data 64 байтами, поэтому поля короткие, а ID записывается числом.
+//
+// Обработчик выбирается по паре сущность+действие целиком, поэтому кнопки разных сущностей
+// не могут перепутаться, как это бывало с префиксами "show_" и "show_note_".
+package callbackdata
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Version - текущая версия формата
+	Version = "1"
+	// MaxLength - ограничение Telegram на длину callback_data в байтах
+	MaxLength = 64
+
+	// separator разделяет поля, signatureSeparator отделяет подпись
+	separator          = ":"
+	signatureSeparator = "~"
+	// signatureSize - длина подписи в байтах до кодирования base64 (64 бита)
+	signatureSize = 8
+)
+
+var (
+	// ErrTooLong - закодированные данные не помещаются в 64 байта
+	ErrTooLong = errors.New("callback data is too long")
+	// ErrInvalid - строка не является данными кнопки или поле содержит разделитель
+	ErrInvalid = errors.New("invalid callback data")
+	// ErrVersion - неизвестная версия формата
+	ErrVersion = errors.New("unsupported callback data version")
+	// ErrSignature - подпись отсутствует или не совпадает
+	ErrSignature = errors.New("invalid callback data signature")
+)
+
+// Entity - сущность, к которой относится кнопка
+type Entity string
+
+const (
+	EntityMenu     Entity = "menu"
+	EntityTask     Entity = "task"
+	EntitySubtask  Entity = "subtask"
+	EntityReminder Entity = "reminder"
+	EntityNote     Entity = "note"
+	EntitySession  Entity = "session"
+)
+
+// Action - действие над сущностью
+type Action string
+
+// Действия главного меню
+const (
+	ActionMain      Action = "main"
+	ActionTasks     Action = "tasks"
+	ActionAddTask   Action = "add_task"
+	ActionNotes     Action = "notes"
+	ActionAddNote   Action = "add_note"
+	ActionPending   Action = "pending"
+	ActionCompleted Action = "completed"
+	ActionSearch    Action = "search"
+	ActionFavorites Action = "favorites"
+	ActionHelp      Action = "help"
+	ActionLogout    Action = "logout"
+	ActionLogoutAll Action = "logout_all"
+	ActionCancel    Action = "cancel"
+)
+
+// Действия над задачами, заметками, подзадачами и напоминаниями
+const (
+	ActionShow           Action = "show"
+	ActionComplete       Action = "complete"
+	ActionDelete         Action = "delete"
+	ActionNotify         Action = "notify"
+	ActionSnooze         Action = "snooze"
+	ActionRecurrence     Action = "recur"
+	ActionRepeat         Action = "repeat"
+	ActionAddSubtask     Action = "addsub"
+	ActionToggle         Action = "toggle"
+	ActionRemove         Action = "remove"
+	ActionPriority       Action = "priority"
+	ActionCategory       Action = "category"
+	ActionFavoriteAdd    Action = "fav_add"
+	ActionFavoriteRemove Action = "fav_remove"
+	ActionDownload       Action = "download"
+	// ActionConfirm - подтверждение действия, подтверждаемое действие передается первым аргументом
+	ActionConfirm Action = "confirm"
+)
+
+// Data - данные inline-кнопки
+type Data struct {
+	Entity Entity
+	Action Action
+	// ID - идентификатор сущности, 0 - кнопка не относится к конкретной записи
+	ID int
+	// Args - дополнительные аргументы, например вариант откладывания напоминания
+	Args []string
+}
+
+// New создает данные кнопки
+func New(entity Entity, action Action, id int, args ...string) Data {
+	return Data{Entity: entity, Action: action, ID: id, Args: args}
+}
+
+// Menu создает данные кнопки главного меню
+func Menu(action Action) Data {
+	return New(EntityMenu, action, 0)
+}
+
+// Confirm создает данные кнопки подтверждения действия action над сущностью
+func Confirm(entity Entity, id int, action Action) Data {
+	return New(entity, ActionConfirm, id, string(action))
+}
+
+// Key возвращает пару сущность+действие, по которой выбирается обработчик кнопки
+func (d Data) Key() string {
+	return Key(d.Entity, d.Action)
+}
+
+// Key возвращает ключ обработчика для сущности и действия
+func Key(entity Entity, action Action) string {
+	return string(entity) + separator + string(action)
+}
+
+// Arg возвращает i-й аргумент или пустую строку, если аргументов меньше
+func (d Data) Arg(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
+
+// Codec кодирует и разбирает данные кнопок. С секретом данные подписываются HMAC-SHA256,
+// и разбираются только кнопки с верной подписью.
+type Codec struct {
+	secret []byte
+}
+
+// NewCodec создает кодек. Пустой secret - данные не подписываются.
+func NewCodec(secret string) *Codec {
+	c := &Codec{}
+	if secret != "" {
+		c.secret = []byte(secret)
+	}
+	return c
+}
+
+// Encode кодирует данные кнопки
+func (c *Codec) Encode(d Data) (string, error) {
+	fields := make([]string, 0, 4+len(d.Args))
+	fields = append(fields, Version, string(d.Entity), string(d.Action), strconv.Itoa(d.ID))
+	fields = append(fields, d.Args...)
+
+	for i, field := range fields {
+		if i < 3 && field == "" {
+			return "", fmt.Errorf("%w: empty entity or action", ErrInvalid)
+		}
+		if strings.Contains(field, separator) || strings.Contains(field, signatureSeparator) {
+			return "", fmt.Errorf("%w: field %q contains a separator", ErrInvalid, field)
+		}
+	}
+
+	encoded := strings.Join(fields, separator)
+	if c.secret != nil {
+		encoded += signatureSeparator + c.sign(encoded)
+	}
+
+	if len(encoded) > MaxLength {
+		return "", fmt.Errorf("%w: %d bytes", ErrTooLong, len(encoded))
+	}
+	return encoded, nil
+}
+
+// EncodeOrMenu кодирует данные кнопки, а если это не удается, возвращает вместе с ошибкой
+// данные кнопки главного меню: сообщение отправляется с рабочей кнопкой, а не обрывает обработчик.
+func (c *Codec) EncodeOrMenu(d Data) (string, error) {
+	encoded, err := c.Encode(d)
+	if err == nil {
+		return encoded, nil
+	}
+
+	// Кнопка меню короткая и собрана из констант, поэтому всегда кодируется
+	menu, menuErr := c.Encode(Menu(ActionMain))
+	if menuErr != nil {
+		return "", errors.Join(err, menuErr)
+	}
+	return menu, err
+}
+
+// Decode разбирает данные кнопки. Кнопки в формате до версии 1 ("show_42", "cmd_menu")
+// разбираются, только если подпись не требуется: иначе их можно было бы подделать.
+func (c *Codec) Decode(s string) (Data, error) {
+	if len(s) > MaxLength {
+		return Data{}, fmt.Errorf("%w: %d bytes", ErrTooLong, len(s))
+	}
+
+	payload, signature, signed := strings.Cut(s, signatureSeparator)
+	fields := strings.Split(payload, separator)
+
+	if len(fields) == 1 && !signed {
+		if c.secret != nil {
+			return Data{}, ErrSignature
+		}
+		return decodeLegacy(s)
+	}
+
+	if fields[0] != Version {
+		return Data{}, fmt.Errorf("%w: %q", ErrVersion, fields[0])
+	}
+
+	// Без секрета подпись не проверяется: кнопки остаются рабочими после отключения подписи
+	if c.secret != nil && (!signed || !hmac.Equal([]byte(signature), []byte(c.sign(payload)))) {
+		return Data{}, ErrSignature
+	}
+
+	if len(fields) < 4 || fields[1] == "" || fields[2] == "" {
+		return Data{}, ErrInvalid
+	}
+
+	id, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return Data{}, fmt.Errorf("%w: id %q", ErrInvalid, fields[3])
+	}
+
+	d := Data{Entity: Entity(fields[1]), Action: Action(fields[2]), ID: id}
+	if len(fields) > 4 {
+		d.Args = fields[4:]
+	}
+	return d, nil
+}
+
+// sign возвращает подпись данных: первые 8 байт HMAC-SHA256 в base64url
+func (c *Codec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureSize])
+}
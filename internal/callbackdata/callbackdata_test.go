@@ -0,0 +1,236 @@
+package callbackdata
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var allEntities = []Entity{
+	EntityMenu, EntityTask, EntitySubtask, EntityReminder, EntityNote, EntitySession,
+}
+
+var allActions = []Action{
+	ActionMain, ActionTasks, ActionAddTask, ActionNotes, ActionAddNote, ActionPending,
+	ActionCompleted, ActionSearch, ActionFavorites, ActionHelp, ActionLogout, ActionLogoutAll, ActionCancel,
+	ActionShow, ActionComplete, ActionDelete, ActionNotify, ActionSnooze, ActionRecurrence,
+	ActionRepeat, ActionAddSubtask, ActionToggle, ActionRemove, ActionPriority, ActionCategory,
+	ActionFavoriteAdd, ActionFavoriteRemove, ActionDownload, ActionConfirm,
+}
+
+func TestEncodeDecodeAllPairs(t *testing.T) {
+	for _, codec := range []*Codec{NewCodec(""), NewCodec("secret")} {
+		for _, entity := range allEntities {
+			for _, action := range allActions {
+				want := New(entity, action, math.MaxInt, "10m")
+
+				encoded, err := codec.Encode(want)
+				if err != nil {
+					t.Fatalf("Encode(%s) error: %v", want.Key(), err)
+				}
+				got, err := codec.Decode(encoded)
+				if err != nil {
+					t.Fatalf("Decode(%q) error: %v", encoded, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("Decode(Encode(%+v)) = %+v", want, got)
+				}
+			}
+		}
+	}
+}
+
+// TestEncodeMaxLength проверяет границу 64 байт на самой длинной паре с максимальным ID
+func TestEncodeMaxLength(t *testing.T) {
+	codec := NewCodec("secret")
+	base := New(EntityReminder, ActionFavoriteRemove, math.MaxInt)
+
+	encoded, err := codec.Encode(base)
+	if err != nil {
+		t.Fatalf("Encode without args: %v", err)
+	}
+
+	// Аргумент добавляет разделитель и свою длину
+	room := MaxLength - len(encoded) - 1
+	if room < 1 {
+		t.Fatalf("no room for arguments: %d bytes without args", len(encoded))
+	}
+
+	fits := New(base.Entity, base.Action, base.ID, strings.Repeat("a", room))
+	encoded, err = codec.Encode(fits)
+	if err != nil {
+		t.Fatalf("Encode with %d-byte arg: %v", room, err)
+	}
+	if len(encoded) != MaxLength {
+		t.Fatalf("encoded length = %d, want %d", len(encoded), MaxLength)
+	}
+	if _, err := codec.Decode(encoded); err != nil {
+		t.Fatalf("Decode of %d bytes: %v", MaxLength, err)
+	}
+
+	tooLong := New(base.Entity, base.Action, base.ID, strings.Repeat("a", room+1))
+	if _, err := codec.Encode(tooLong); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("Encode over the limit: got %v, want ErrTooLong", err)
+	}
+}
+
+func TestEncodeOrMenu(t *testing.T) {
+	codec := NewCodec("secret")
+
+	encoded, err := codec.EncodeOrMenu(New(EntityNote, ActionCategory, 0, strings.Repeat("категория", 10)))
+	if !errors.Is(err, ErrTooLong) {
+		t.Fatalf("EncodeOrMenu error = %v, want ErrTooLong", err)
+	}
+
+	got, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode of fallback %q: %v", encoded, err)
+	}
+	if got.Key() != Menu(ActionMain).Key() {
+		t.Fatalf("fallback button = %s, want main menu", got.Key())
+	}
+}
+
+func TestDecodeRejectsTamperedSignature(t *testing.T) {
+	codec := NewCodec("secret")
+
+	encoded, err := codec.Encode(New(EntityTask, ActionDelete, 42))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	payload, signature, _ := strings.Cut(encoded, signatureSeparator)
+
+	tampered := []struct {
+		name string
+		data string
+	}{
+		{"другой ID", strings.Replace(payload, ":42", ":43", 1) + signatureSeparator + signature},
+		{"измененная подпись", payload + signatureSeparator + flipFirst(signature)},
+		{"без подписи", payload},
+		{"подпись другим секретом", mustEncode(t, NewCodec("other"), New(EntityTask, ActionDelete, 42))},
+		{"старый формат", "delete_42"},
+	}
+
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Decode(tt.data); !errors.Is(err, ErrSignature) {
+				t.Fatalf("Decode(%q) error = %v, want ErrSignature", tt.data, err)
+			}
+		})
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Data
+	}{
+		{"cmd_menu", Menu(ActionMain)},
+		{"cmd_tasks", Menu(ActionTasks)},
+		{"cmd_add_task", Menu(ActionAddTask)},
+		{"cmd_notes", Menu(ActionNotes)},
+		{"cmd_add_note", Menu(ActionAddNote)},
+		{"cmd_pending", Menu(ActionPending)},
+		{"cmd_completed", Menu(ActionCompleted)},
+		{"cmd_search", Menu(ActionSearch)},
+		{"cmd_favorites", Menu(ActionFavorites)},
+		{"cmd_help", Menu(ActionHelp)},
+		{"cmd_logout", Menu(ActionLogout)},
+		{"cmd_logout_all", Menu(ActionLogoutAll)},
+		{"cancel_delete_42", Menu(ActionCancel)},
+
+		{"show_note_7", New(EntityNote, ActionShow, 7)},
+		{"delete_note_7", New(EntityNote, ActionDelete, 7)},
+		{"favorite_add_7", New(EntityNote, ActionFavoriteAdd, 7)},
+		{"favorite_remove_7", New(EntityNote, ActionFavoriteRemove, 7)},
+		{"category_work", New(EntityNote, ActionCategory, 0, "work")},
+		{"complete_42", New(EntityTask, ActionComplete, 42)},
+		{"show_42", New(EntityTask, ActionShow, 42)},
+		{"delete_42", New(EntityTask, ActionDelete, 42)},
+		{"notify_42", New(EntityTask, ActionNotify, 42)},
+		{"recur_42", New(EntityTask, ActionRecurrence, 42)},
+		{"addsub_42", New(EntityTask, ActionAddSubtask, 42)},
+		{"snooze_42_10m", New(EntityTask, ActionSnooze, 42, "10m")},
+		{"repeat_42_weekly", New(EntityTask, ActionRepeat, 42, "weekly")},
+		{"priority_high", New(EntityTask, ActionPriority, 0, "high")},
+		{"subtask_5", New(EntitySubtask, ActionToggle, 5)},
+		{"rmrem_9", New(EntityReminder, ActionRemove, 9)},
+
+		{"confirm_complete_task_42", Confirm(EntityTask, 42, ActionComplete)},
+		{"confirm_delete_task_42", Confirm(EntityTask, 42, ActionDelete)},
+		{"confirm_delete_note_7", Confirm(EntityNote, 7, ActionDelete)},
+		{"confirm_logout_1", Confirm(EntitySession, 1, ActionLogout)},
+		{"confirm_logoutall_1", Confirm(EntitySession, 1, ActionLogoutAll)},
+	}
+
+	codec := NewCodec("")
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := codec.Decode(tt.in)
+			if err != nil {
+				t.Fatalf("Decode(%q) error: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Decode(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDecodeLegacyLongestPrefix проверяет, что вложенные префиксы разбираются одинаково
+// при любом порядке обхода: заметка не может стать задачей с ID "note_7"
+func TestDecodeLegacyLongestPrefix(t *testing.T) {
+	codec := NewCodec("")
+
+	// Порядок обхода map случаен, поэтому разбор повторяется
+	for i := 0; i < 100; i++ {
+		for in, want := range map[string]Data{
+			"show_note_7":   New(EntityNote, ActionShow, 7),
+			"delete_note_7": New(EntityNote, ActionDelete, 7),
+			"show_7":        New(EntityTask, ActionShow, 7),
+			"delete_7":      New(EntityTask, ActionDelete, 7),
+		} {
+			got, err := codec.Decode(in)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Decode(%q) = %+v, %v, want %+v", in, got, err, want)
+			}
+		}
+	}
+
+	// Каждый префикс с ID разбирается своим форматом, а не более коротким вложенным
+	for prefix := range legacyFormats {
+		if found, _, _ := longestPrefix(legacyFormats, prefix+"1"); found != prefix {
+			t.Errorf("prefix %q resolved to %q", prefix, found)
+		}
+	}
+}
+
+func TestDecodeLegacyInvalid(t *testing.T) {
+	codec := NewCodec("")
+
+	for _, in := range []string{"edit_note_7", "show_abc", "snooze_42", "confirm_unknown_1", "whatever"} {
+		if _, err := codec.Decode(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Decode(%q) error = %v, want ErrInvalid", in, err)
+		}
+	}
+}
+
+func mustEncode(t *testing.T, codec *Codec, d Data) string {
+	t.Helper()
+
+	encoded, err := codec.Encode(d)
+	if err != nil {
+		t.Fatalf("Encode(%s): %v", d.Key(), err)
+	}
+	return encoded
+}
+
+// flipFirst меняет первый символ подписи на другой символ base64url
+func flipFirst(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
@@ -0,0 +1,135 @@
+package callbackdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// legacyMenu - кнопки главного меню в формате до версии 1
+var legacyMenu = map[string]Action{
+	"cmd_menu":       ActionMain,
+	"cmd_tasks":      ActionTasks,
+	"cmd_add_task":   ActionAddTask,
+	"cmd_notes":      ActionNotes,
+	"cmd_add_note":   ActionAddNote,
+	"cmd_pending":    ActionPending,
+	"cmd_completed":  ActionCompleted,
+	"cmd_search":     ActionSearch,
+	"cmd_favorites":  ActionFavorites,
+	"cmd_help":       ActionHelp,
+	"cmd_logout":     ActionLogout,
+	"cmd_logout_all": ActionLogoutAll,
+}
+
+// legacyFormat - кнопка в формате до версии 1: что означает префикс и что идет после него
+type legacyFormat struct {
+	entity Entity
+	action Action
+	// withID - после префикса идет ID
+	withID bool
+	// withArg - после префикса (и ID через "_") идет аргумент
+	withArg bool
+}
+
+// legacyFormats - кнопки в формате до версии 1 по префиксу. Префиксы вкладываются друг в друга
+// ("show_" и "show_note_"), поэтому выбирается самый длинный подходящий, независимо от порядка.
+// Кнопка "edit_note_" не перенесена: редактирования заметок в боте нет.
+var legacyFormats = map[string]legacyFormat{
+	"show_note_":       {entity: EntityNote, action: ActionShow, withID: true},
+	"delete_note_":     {entity: EntityNote, action: ActionDelete, withID: true},
+	"favorite_add_":    {entity: EntityNote, action: ActionFavoriteAdd, withID: true},
+	"favorite_remove_": {entity: EntityNote, action: ActionFavoriteRemove, withID: true},
+	"category_":        {entity: EntityNote, action: ActionCategory, withArg: true},
+	"complete_":        {entity: EntityTask, action: ActionComplete, withID: true},
+	"show_":            {entity: EntityTask, action: ActionShow, withID: true},
+	"delete_":          {entity: EntityTask, action: ActionDelete, withID: true},
+	"notify_":          {entity: EntityTask, action: ActionNotify, withID: true},
+	"recur_":           {entity: EntityTask, action: ActionRecurrence, withID: true},
+	"addsub_":          {entity: EntityTask, action: ActionAddSubtask, withID: true},
+	"snooze_":          {entity: EntityTask, action: ActionSnooze, withID: true, withArg: true},
+	"repeat_":          {entity: EntityTask, action: ActionRepeat, withID: true, withArg: true},
+	"priority_":        {entity: EntityTask, action: ActionPriority, withArg: true},
+	"subtask_":         {entity: EntitySubtask, action: ActionToggle, withID: true},
+	"rmrem_":           {entity: EntityReminder, action: ActionRemove, withID: true},
+}
+
+// legacyConfirmations - подтверждения в формате до версии 1: "confirm_delete_task_42"
+var legacyConfirmations = map[string]struct {
+	entity Entity
+	action Action
+}{
+	"complete_task_": {EntityTask, ActionComplete},
+	"delete_task_":   {EntityTask, ActionDelete},
+	"delete_note_":   {EntityNote, ActionDelete},
+	"logout_":        {EntitySession, ActionLogout},
+	"logoutall_":     {EntitySession, ActionLogoutAll},
+}
+
+// decodeLegacy разбирает кнопки, отправленные до появления версии 1, чтобы они продолжали
+// работать в уже отправленных сообщениях, например в напоминаниях
+func decodeLegacy(s string) (Data, error) {
+	if action, ok := legacyMenu[s]; ok {
+		return Menu(action), nil
+	}
+
+	if strings.HasPrefix(s, "cancel_") {
+		return Menu(ActionCancel), nil
+	}
+
+	if rest, ok := strings.CutPrefix(s, "confirm_"); ok {
+		prefix, confirmation, ok := longestPrefix(legacyConfirmations, rest)
+		if !ok {
+			return Data{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		idStr := strings.TrimPrefix(rest, prefix)
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return Data{}, fmt.Errorf("%w: id %q", ErrInvalid, idStr)
+		}
+		return Confirm(confirmation.entity, id, confirmation.action), nil
+	}
+
+	prefix, format, ok := longestPrefix(legacyFormats, s)
+	if !ok {
+		return Data{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	rest := strings.TrimPrefix(s, prefix)
+
+	d := Data{Entity: format.entity, Action: format.action}
+	if format.withID {
+		idStr := rest
+		if format.withArg {
+			var arg string
+			if idStr, arg, ok = strings.Cut(rest, "_"); !ok {
+				return Data{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+			}
+			d.Args = []string{arg}
+		}
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return Data{}, fmt.Errorf("%w: id %q", ErrInvalid, idStr)
+		}
+		d.ID = id
+	} else if format.withArg {
+		d.Args = []string{rest}
+	}
+
+	return d, nil
+}
+
+// longestPrefix находит в formats самый длинный ключ, с которого начинается s
+func longestPrefix[T any](formats map[string]T, s string) (string, T, bool) {
+	var (
+		best  string
+		value T
+		found bool
+	)
+	for prefix, v := range formats {
+		if strings.HasPrefix(s, prefix) && len(prefix) > len(best) {
+			best, value, found = prefix, v, true
+		}
+	}
+	return best, value, found
+}
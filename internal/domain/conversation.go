@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// ConversationState хранит состояние многошагового диалога пользователя с ботом
+// (создание задачи, заметки, ввод времени напоминания и т.п.)
+type ConversationState struct {
+	UserID int64  `json:"user_id" db:"user_id"`
+	Action string `json:"action" db:"action"`
+	Step   int    `json:"step" db:"step"`
+	// Data - данные диалога в JSON, их формат определяет обработчик телеграм бота
+	Data      []byte    `json:"data" db:"data"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// IsExpired проверяет, истекло ли время ожидания ответа пользователя
+func (s *ConversationState) IsExpired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound возвращается, если запрошенная сущность не существует
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden возвращается, если сущность принадлежит другому пользователю
+	ErrForbidden = errors.New("forbidden")
+	// ErrConflict возвращается, если операция противоречит текущему состоянию данных
+	ErrConflict = errors.New("conflict")
+	// ErrValidation возвращается, если входные данные некорректны
+	ErrValidation = errors.New("validation failed")
+	// ErrRateLimited возвращается, если действие временно заблокировано из-за частых попыток
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Entity обозначает тип сущности, к которой относится ошибка
+type Entity string
+
+const (
+	EntityUser     Entity = "user"
+	EntitySession  Entity = "session"
+	EntityTask     Entity = "task"
+	EntityReminder Entity = "reminder"
+	EntityNote     Entity = "note"
+	EntityInvite   Entity = "invite"
+	// EntityRecoveryCode - код восстановления двухфакторной аутентификации
+	EntityRecoveryCode Entity = "recovery_code"
+	// EntityConversation - состояние многошагового диалога с ботом
+	EntityConversation Entity = "conversation"
+)
+
+// EntityError описывает ошибку при обращении к конкретной сущности.
+// Err - одна из ErrNotFound, ErrForbidden или ErrConflict, поэтому ошибку можно проверять через errors.Is.
+type EntityError struct {
+	Entity Entity
+	ID     int64
+	Reason string
+	Err    error
+}
+
+// Error возвращает текст ошибки, например "task 5: not found"
+func (e *EntityError) Error() string {
+	msg := string(e.Entity)
+	if e.ID != 0 {
+		msg += fmt.Sprintf(" %d", e.ID)
+	}
+	msg += ": " + e.Err.Error()
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Unwrap возвращает базовую ошибку для errors.Is
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+// NotFound создает ошибку "сущность не найдена"
+func NotFound(entity Entity, id int64) error {
+	return &EntityError{Entity: entity, ID: id, Err: ErrNotFound}
+}
+
+// Forbidden создает ошибку "сущность принадлежит другому пользователю"
+func Forbidden(entity Entity, id int64) error {
+	return &EntityError{Entity: entity, ID: id, Err: ErrForbidden}
+}
+
+// Conflict создает ошибку "операция невозможна в текущем состоянии сущности".
+// reason показывается пользователю, поэтому пишется по-русски.
+func Conflict(entity Entity, id int64, reason string) error {
+	return &EntityError{Entity: entity, ID: id, Reason: reason, Err: ErrConflict}
+}
+
+// ValidationError описывает некорректное значение поля.
+// Message показывается пользователю, поэтому пишется по-русски.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// NewValidationError создает ошибку валидации поля
+func NewValidationError(field, message string) error {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// Error возвращает текст ошибки вместе с именем поля
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrValidation)
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// RateLimitError сообщает, до какого времени действие заблокировано
+type RateLimitError struct {
+	Until time.Time
+}
+
+// Error возвращает текст ошибки с временем окончания блокировки
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited until %s", e.Until.Format(time.RFC3339))
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrRateLimited)
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
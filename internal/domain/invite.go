@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Invite представляет одноразовое приглашение, выданное администратором.
+// Хранится только хеш кода, сам код показывается администратору один раз.
+type Invite struct {
+	ID        int        `json:"id" db:"id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	CreatedBy int64      `json:"created_by" db:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedBy    *int64     `json:"used_by,omitempty" db:"used_by"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsUsed проверяет, было ли приглашение использовано
+func (i *Invite) IsUsed() bool {
+	return i.UsedAt != nil
+}
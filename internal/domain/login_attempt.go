@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// LoginAttempt представляет попытку входа через /start
+type LoginAttempt struct {
+	ID         int64     `json:"id" db:"id"`
+	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
+	Success    bool      `json:"success" db:"success"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// LoginFailures описывает неудачные попытки входа подряд, начиная с последнего успешного входа
+type LoginFailures struct {
+	Count       int
+	LastFailure time.Time
+}
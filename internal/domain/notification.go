@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// OutboxStatus представляет статус уведомления в очереди отправки
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusSending   OutboxStatus = "sending"
+	OutboxStatusSent      OutboxStatus = "sent"
+	OutboxStatusDead      OutboxStatus = "dead"
+	OutboxStatusCancelled OutboxStatus = "cancelled"
+)
+
+// OutboxMessage представляет уведомление о задаче в очереди отправки (outbox).
+// Запись создается вместе с пометкой напоминания, поэтому каждое напоминание
+// попадает в очередь ровно один раз.
+type OutboxMessage struct {
+	ID            int64        `json:"id" db:"id"`
+	ReminderID    int          `json:"reminder_id" db:"reminder_id"`
+	TaskID        int          `json:"task_id" db:"task_id"`
+	Status        OutboxStatus `json:"status" db:"status"`
+	Attempts      int          `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time    `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string       `json:"last_error,omitempty" db:"last_error"`
+	LockedAt      *time.Time   `json:"locked_at,omitempty" db:"locked_at"`
+	SentAt        *time.Time   `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+}
+
+// MarkSent помечает уведомление доставленным
+func (m *OutboxMessage) MarkSent() {
+	now := time.Now()
+	m.Status = OutboxStatusSent
+	m.SentAt = &now
+	m.LockedAt = nil
+	m.LastError = ""
+}
+
+// Retry возвращает уведомление в очередь для повторной попытки в момент at
+func (m *OutboxMessage) Retry(at time.Time, reason string) {
+	m.Status = OutboxStatusPending
+	m.NextAttemptAt = at
+	m.LockedAt = nil
+	m.LastError = reason
+}
+
+// Fail окончательно завершает доставку уведомления с указанным статусом
+func (m *OutboxMessage) Fail(status OutboxStatus, reason string) {
+	m.Status = status
+	m.LockedAt = nil
+	m.LastError = reason
+}
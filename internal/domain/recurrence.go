@@ -0,0 +1,470 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurrenceFrequency представляет частоту повторения задачи
+type RecurrenceFrequency string
+
+const (
+	RecurrenceDaily   RecurrenceFrequency = "DAILY"
+	RecurrenceWeekly  RecurrenceFrequency = "WEEKLY"
+	RecurrenceMonthly RecurrenceFrequency = "MONTHLY"
+	RecurrenceYearly  RecurrenceFrequency = "YEARLY"
+)
+
+// maxRecurrenceInterval ограничивает интервал повторения, чтобы поиск следующей даты был конечным
+const maxRecurrenceInterval = 365
+
+// Recurrence представляет правило повторения задачи в подмножестве формата RRULE (RFC 5545).
+// Поддерживаются FREQ, INTERVAL, BYDAY, BYMONTH, BYMONTHDAY, BYHOUR и BYMINUTE.
+type Recurrence struct {
+	Frequency  RecurrenceFrequency
+	Interval   int
+	ByWeekday  []time.Weekday
+	ByMonth    time.Month
+	ByMonthDay int
+	Hour       int
+	Minute     int
+	HasTime    bool
+}
+
+var (
+	rruleWeekdays = map[string]time.Weekday{
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+		"SU": time.Sunday,
+	}
+	workWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+	everyNDaysRegex = regexp.MustCompile(`^(?:every (\d+) days?|каждые (\d+) (?:дня|дней|день)|(\d+)d)$`)
+	monthlyRegex    = regexp.MustCompile(`^(?:monthly|ежемесячно|каждый месяц)(?: (-?\d{1,2}))?$`)
+	ruleTimeRegex   = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+)
+
+// ParseRecurrence разбирает правило в формате RRULE, например "FREQ=WEEKLY;BYDAY=MO,FR"
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimSpace(rule)
+	rule = strings.TrimPrefix(strings.ToUpper(rule), "RRULE:")
+	if rule == "" {
+		return nil, fmt.Errorf("пустое правило повторения")
+	}
+
+	r := &Recurrence{Interval: 1}
+	hasHour, hasMinute := false, false
+
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("неверный фрагмент правила: %s", part)
+		}
+
+		switch key {
+		case "FREQ":
+			switch RecurrenceFrequency(value) {
+			case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly, RecurrenceYearly:
+				r.Frequency = RecurrenceFrequency(value)
+			default:
+				return nil, fmt.Errorf("неподдерживаемая частота повторения: %s", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > maxRecurrenceInterval {
+				return nil, fmt.Errorf("интервал должен быть числом от 1 до %d", maxRecurrenceInterval)
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("неизвестный день недели: %s", day)
+				}
+				r.ByWeekday = append(r.ByWeekday, weekday)
+			}
+		case "BYMONTH":
+			month, err := strconv.Atoi(value)
+			if err != nil || month < 1 || month > 12 {
+				return nil, fmt.Errorf("месяц должен быть от 1 до 12")
+			}
+			r.ByMonth = time.Month(month)
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(value)
+			if err != nil || day == 0 || day < -31 || day > 31 {
+				return nil, fmt.Errorf("день месяца должен быть от 1 до 31 или от -31 до -1")
+			}
+			r.ByMonthDay = day
+		case "BYHOUR":
+			hour, err := strconv.Atoi(value)
+			if err != nil || hour < 0 || hour > 23 {
+				return nil, fmt.Errorf("час должен быть от 0 до 23")
+			}
+			r.Hour = hour
+			hasHour = true
+		case "BYMINUTE":
+			minute, err := strconv.Atoi(value)
+			if err != nil || minute < 0 || minute > 59 {
+				return nil, fmt.Errorf("минута должна быть от 0 до 59")
+			}
+			r.Minute = minute
+			hasMinute = true
+		default:
+			return nil, fmt.Errorf("параметр %s не поддерживается", key)
+		}
+	}
+
+	if r.Frequency == "" {
+		return nil, fmt.Errorf("в правиле не указана частота (FREQ)")
+	}
+	if r.ByMonthDay != 0 && r.Frequency != RecurrenceMonthly && r.Frequency != RecurrenceYearly {
+		return nil, fmt.Errorf("BYMONTHDAY поддерживается только для FREQ=MONTHLY и FREQ=YEARLY")
+	}
+	if r.ByMonth != 0 && r.Frequency != RecurrenceYearly {
+		return nil, fmt.Errorf("BYMONTH поддерживается только для FREQ=YEARLY")
+	}
+	// Минута без часа не задает время повторения, а молча терять ее нельзя
+	if hasMinute && !hasHour {
+		return nil, fmt.Errorf("BYMINUTE указывается только вместе с BYHOUR")
+	}
+
+	r.HasTime = hasHour
+	return r, nil
+}
+
+// ParseRecurrenceRule разбирает правило повторения, введенное пользователем.
+// Помимо RRULE понимает короткие формы: daily, weekdays, weekly, every N days,
+// monthly D, yearly и их русские аналоги. В конце можно указать время в формате HH:MM.
+func ParseRecurrenceRule(input string) (*Recurrence, error) {
+	input = strings.ToLower(strings.Join(strings.Fields(input), " "))
+	if input == "" {
+		return nil, fmt.Errorf("пустое правило повторения")
+	}
+
+	if strings.HasPrefix(input, "rrule:") || strings.HasPrefix(input, "freq=") {
+		return ParseRecurrence(input)
+	}
+
+	hour, minute, hasTime := 0, 0, false
+	if idx := strings.LastIndex(input, " "); idx > 0 {
+		if matches := ruleTimeRegex.FindStringSubmatch(input[idx+1:]); matches != nil {
+			hour, _ = strconv.Atoi(matches[1])
+			minute, _ = strconv.Atoi(matches[2])
+			if hour > 23 || minute > 59 {
+				return nil, fmt.Errorf("неверное время: %s", input[idx+1:])
+			}
+			hasTime = true
+			input = input[:idx]
+		}
+	}
+
+	r := &Recurrence{Interval: 1, Hour: hour, Minute: minute, HasTime: hasTime}
+
+	switch input {
+	case "daily", "ежедневно", "каждый день":
+		r.Frequency = RecurrenceDaily
+	case "weekdays", "будни", "по будням":
+		r.Frequency = RecurrenceWeekly
+		r.ByWeekday = append([]time.Weekday(nil), workWeekdays...)
+	case "weekly", "еженедельно", "каждую неделю":
+		r.Frequency = RecurrenceWeekly
+	case "yearly", "ежегодно", "каждый год":
+		r.Frequency = RecurrenceYearly
+	default:
+		if matches := everyNDaysRegex.FindStringSubmatch(input); matches != nil {
+			interval, _ := strconv.Atoi(matches[1] + matches[2] + matches[3])
+			if interval < 1 || interval > maxRecurrenceInterval {
+				return nil, fmt.Errorf("интервал должен быть числом от 1 до %d", maxRecurrenceInterval)
+			}
+			r.Frequency = RecurrenceDaily
+			r.Interval = interval
+			break
+		}
+
+		if matches := monthlyRegex.FindStringSubmatch(input); matches != nil {
+			r.Frequency = RecurrenceMonthly
+			if matches[1] != "" {
+				day, _ := strconv.Atoi(matches[1])
+				if day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("день месяца должен быть от 1 до 31 или от -31 до -1")
+				}
+				r.ByMonthDay = day
+			}
+			break
+		}
+
+		return nil, fmt.Errorf("неизвестное правило повторения: %s", input)
+	}
+
+	return r, nil
+}
+
+// String возвращает правило в каноническом формате RRULE
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	if len(r.ByWeekday) > 0 {
+		days := make([]string, 0, len(r.ByWeekday))
+		for _, weekday := range r.ByWeekday {
+			days = append(days, weekdayCode(weekday))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.ByMonth != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTH=%d", r.ByMonth))
+	}
+
+	if r.ByMonthDay != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", r.ByMonthDay))
+	}
+
+	if r.HasTime {
+		parts = append(parts, fmt.Sprintf("BYHOUR=%d", r.Hour), fmt.Sprintf("BYMINUTE=%d", r.Minute))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// PinDate закрепляет в правиле день месяца (а для ежегодного правила и месяц) по дате t,
+// если они не заданы явно. Без этого после короткого месяца повторение сдвигается:
+// 31 января -> 28 февраля -> 28 марта и дальше 28-го числа.
+func (r *Recurrence) PinDate(t time.Time) {
+	switch r.Frequency {
+	case RecurrenceMonthly:
+		if r.ByMonthDay == 0 {
+			r.ByMonthDay = t.Day()
+		}
+	case RecurrenceYearly:
+		if r.ByMonth == 0 {
+			r.ByMonth = t.Month()
+		}
+		if r.ByMonthDay == 0 {
+			r.ByMonthDay = t.Day()
+		}
+	}
+}
+
+// Next возвращает первое повторение строго после from.
+// Время суток берется из правила, а если оно не задано - из from.
+func (r *Recurrence) Next(from time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	hour, minute := from.Hour(), from.Minute()
+	if r.HasTime {
+		hour, minute = r.Hour, r.Minute
+	}
+
+	anchor := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	lookahead := 366*interval + 31
+
+	for i := 0; i <= lookahead; i++ {
+		day := anchor.AddDate(0, 0, i)
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, from.Location())
+		if !candidate.After(from) {
+			continue
+		}
+		if r.matches(day, anchor, interval) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+// matches проверяет, попадает ли день под правило относительно опорного дня
+func (r *Recurrence) matches(day, anchor time.Time, interval int) bool {
+	switch r.Frequency {
+	case RecurrenceDaily:
+		if daysBetween(anchor, day)%interval != 0 {
+			return false
+		}
+		return len(r.ByWeekday) == 0 || containsWeekday(r.ByWeekday, day.Weekday())
+
+	case RecurrenceWeekly:
+		if daysBetween(weekStart(anchor), weekStart(day))/7%interval != 0 {
+			return false
+		}
+		if len(r.ByWeekday) == 0 {
+			return day.Weekday() == anchor.Weekday()
+		}
+		return containsWeekday(r.ByWeekday, day.Weekday())
+
+	case RecurrenceMonthly:
+		months := (day.Year()-anchor.Year())*12 + int(day.Month()) - int(anchor.Month())
+		if months%interval != 0 {
+			return false
+		}
+		monthDay := r.ByMonthDay
+		if monthDay == 0 {
+			monthDay = anchor.Day()
+		}
+		return day.Day() == resolveMonthDay(day, monthDay)
+
+	case RecurrenceYearly:
+		if (day.Year()-anchor.Year())%interval != 0 {
+			return false
+		}
+		month, monthDay := r.ByMonth, r.ByMonthDay
+		if month == 0 {
+			month = anchor.Month()
+		}
+		if monthDay == 0 {
+			monthDay = anchor.Day()
+		}
+		// 29 февраля в невисокосный год переносится на 28-е, иначе серия бы остановилась
+		return day.Month() == month && day.Day() == resolveMonthDay(day, monthDay)
+	}
+
+	return false
+}
+
+// Describe возвращает описание правила для отображения пользователю
+func (r *Recurrence) Describe() string {
+	var result string
+
+	switch r.Frequency {
+	case RecurrenceDaily:
+		if r.Interval > 1 {
+			result = fmt.Sprintf("каждые %d дн.", r.Interval)
+		} else {
+			result = "каждый день"
+		}
+	case RecurrenceWeekly:
+		switch {
+		case isWorkWeek(r.ByWeekday) && r.Interval == 1:
+			result = "по будням"
+		case r.Interval > 1:
+			result = fmt.Sprintf("каждые %d нед.", r.Interval)
+		default:
+			result = "каждую неделю"
+		}
+		if len(r.ByWeekday) > 0 && !isWorkWeek(r.ByWeekday) {
+			days := make([]string, 0, len(r.ByWeekday))
+			for _, weekday := range r.ByWeekday {
+				days = append(days, weekdayShortNames[weekday])
+			}
+			result += " (" + strings.Join(days, ", ") + ")"
+		}
+	case RecurrenceMonthly:
+		if r.Interval > 1 {
+			result = fmt.Sprintf("каждые %d мес.", r.Interval)
+		} else {
+			result = "каждый месяц"
+		}
+		switch {
+		case r.ByMonthDay == -1:
+			result += ", в последний день"
+		case r.ByMonthDay > 0:
+			result += fmt.Sprintf(", %d-го числа", r.ByMonthDay)
+		}
+	case RecurrenceYearly:
+		if r.Interval > 1 {
+			result = fmt.Sprintf("каждые %d г.", r.Interval)
+		} else {
+			result = "каждый год"
+		}
+		if r.ByMonth != 0 && r.ByMonthDay > 0 {
+			result += fmt.Sprintf(", %02d.%02d", r.ByMonthDay, r.ByMonth)
+		}
+	}
+
+	if r.HasTime {
+		result += fmt.Sprintf(" в %02d:%02d", r.Hour, r.Minute)
+	}
+
+	return result
+}
+
+var weekdayShortNames = map[time.Weekday]string{
+	time.Monday:    "пн",
+	time.Tuesday:   "вт",
+	time.Wednesday: "ср",
+	time.Thursday:  "чт",
+	time.Friday:    "пт",
+	time.Saturday:  "сб",
+	time.Sunday:    "вс",
+}
+
+// weekdayCode возвращает двухбуквенный код дня недели для RRULE
+func weekdayCode(weekday time.Weekday) string {
+	for code, day := range rruleWeekdays {
+		if day == weekday {
+			return code
+		}
+	}
+	return ""
+}
+
+// containsWeekday проверяет, входит ли день недели в список
+func containsWeekday(weekdays []time.Weekday, weekday time.Weekday) bool {
+	for _, day := range weekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// isWorkWeek проверяет, совпадает ли список дней с рабочей неделей
+func isWorkWeek(weekdays []time.Weekday) bool {
+	if len(weekdays) != len(workWeekdays) {
+		return false
+	}
+	for _, day := range workWeekdays {
+		if !containsWeekday(weekdays, day) {
+			return false
+		}
+	}
+	return true
+}
+
+// daysBetween возвращает количество календарных дней между датами
+func daysBetween(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// weekStart возвращает понедельник недели, в которую попадает дата
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// resolveMonthDay переводит день месяца из правила в реальный день месяца даты.
+// Отрицательные значения отсчитываются с конца месяца, а слишком большие
+// ограничиваются последним днем месяца.
+func resolveMonthDay(t time.Time, monthDay int) int {
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	if monthDay < 0 {
+		monthDay = lastDay + monthDay + 1
+		if monthDay < 1 {
+			monthDay = 1
+		}
+	}
+
+	if monthDay > lastDay {
+		return lastDay
+	}
+	return monthDay
+}
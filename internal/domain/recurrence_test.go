@@ -0,0 +1,229 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "rrule:freq=weekly;byday=mo,fr", want: "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{rule: "FREQ=DAILY;INTERVAL=3", want: "FREQ=DAILY;INTERVAL=3"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=-1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", want: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29"},
+		{rule: "FREQ=DAILY;BYHOUR=9", want: "FREQ=DAILY;BYHOUR=9;BYMINUTE=0"},
+		{rule: "FREQ=DAILY;BYHOUR=9;BYMINUTE=30", want: "FREQ=DAILY;BYHOUR=9;BYMINUTE=30"},
+
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=366", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=5", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTH=5", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{rule: "FREQ=YEARLY;BYMONTH=13", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=24", wantErr: true},
+		{rule: "FREQ=DAILY;BYMINUTE=30", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRecurrence(%q) = %s, want error", tt.rule, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) error: %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Fatalf("ParseRecurrence(%q) = %s, want %s", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "daily", want: "FREQ=DAILY"},
+		{input: "по будням 09:30", want: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9;BYMINUTE=30"},
+		{input: "every 3 days", want: "FREQ=DAILY;INTERVAL=3"},
+		{input: "каждые 2 дня", want: "FREQ=DAILY;INTERVAL=2"},
+		{input: "monthly -1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{input: "ежегодно", want: "FREQ=YEARLY"},
+		{input: "FREQ=WEEKLY;BYDAY=MO", want: "FREQ=WEEKLY;BYDAY=MO"},
+
+		{input: "daily 25:00", wantErr: true},
+		{input: "monthly 40", wantErr: true},
+		{input: "every 0 days", wantErr: true},
+		{input: "иногда", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseRecurrenceRule(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRecurrenceRule(%q) = %s, want error", tt.input, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q) error: %v", tt.input, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Fatalf("ParseRecurrenceRule(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRecurrenceNext проверяет цепочки повторений: каждое следующее считается от предыдущего,
+// как при выполнении задачи
+func TestRecurrenceNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	date := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+	utcDate := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return date(time.UTC, year, month, day, hour, minute)
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		pin  bool
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "ежедневно с интервалом",
+			rule: "FREQ=DAILY;INTERVAL=3",
+			from: utcDate(2025, time.December, 30, 8, 15),
+			want: []time.Time{utcDate(2026, time.January, 2, 8, 15), utcDate(2026, time.January, 5, 8, 15)},
+		},
+		{
+			name: "по будням через выходные",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9;BYMINUTE=0",
+			from: utcDate(2026, time.January, 9, 9, 0),
+			want: []time.Time{utcDate(2026, time.January, 12, 9, 0), utcDate(2026, time.January, 13, 9, 0)},
+		},
+		{
+			name: "раз в две недели",
+			rule: "FREQ=WEEKLY;INTERVAL=2",
+			from: utcDate(2026, time.January, 7, 18, 0),
+			want: []time.Time{utcDate(2026, time.January, 21, 18, 0), utcDate(2026, time.February, 4, 18, 0)},
+		},
+		{
+			name: "31-е число закреплено и не смещается",
+			rule: "FREQ=MONTHLY",
+			pin:  true,
+			from: utcDate(2026, time.January, 31, 10, 0),
+			want: []time.Time{
+				utcDate(2026, time.February, 28, 10, 0),
+				utcDate(2026, time.March, 31, 10, 0),
+				utcDate(2026, time.April, 30, 10, 0),
+				utcDate(2026, time.May, 31, 10, 0),
+			},
+		},
+		{
+			name: "последний день месяца",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1",
+			from: utcDate(2028, time.January, 31, 10, 0),
+			want: []time.Time{utcDate(2028, time.February, 29, 10, 0), utcDate(2028, time.March, 31, 10, 0)},
+		},
+		{
+			name: "29 февраля",
+			rule: "FREQ=YEARLY",
+			pin:  true,
+			from: utcDate(2028, time.February, 29, 12, 0),
+			want: []time.Time{
+				utcDate(2029, time.February, 28, 12, 0),
+				utcDate(2030, time.February, 28, 12, 0),
+				utcDate(2031, time.February, 28, 12, 0),
+				utcDate(2032, time.February, 29, 12, 0),
+			},
+		},
+		{
+			name: "29 февраля без закрепления",
+			rule: "FREQ=YEARLY;INTERVAL=2",
+			from: utcDate(2028, time.February, 29, 12, 0),
+			want: []time.Time{utcDate(2030, time.February, 28, 12, 0)},
+		},
+		{
+			name: "переход на летнее время",
+			rule: "FREQ=DAILY;BYHOUR=9;BYMINUTE=0",
+			from: date(berlin, 2026, time.March, 28, 9, 0),
+			want: []time.Time{date(berlin, 2026, time.March, 29, 9, 0), date(berlin, 2026, time.March, 30, 9, 0)},
+		},
+		{
+			name: "переход на зимнее время",
+			rule: "FREQ=WEEKLY",
+			from: date(berlin, 2026, time.October, 21, 7, 30),
+			want: []time.Time{date(berlin, 2026, time.October, 28, 7, 30), date(berlin, 2026, time.November, 4, 7, 30)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) error: %v", tt.rule, err)
+			}
+			if tt.pin {
+				r.PinDate(tt.from)
+			}
+
+			from := tt.from
+			for i, want := range tt.want {
+				got := r.Next(from)
+				if !got.Equal(want) || got.Location() != want.Location() {
+					t.Fatalf("occurrence %d after %v = %v, want %v", i+1, from, got, want)
+				}
+				from = got
+			}
+		})
+	}
+}
+
+// TestRecurrenceNextDSTGap проверяет, что время, пропущенное при переходе на летнее время,
+// сдвигается вперед, а серия не прерывается
+func TestRecurrenceNextDSTGap(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	r, err := ParseRecurrence("FREQ=DAILY;BYHOUR=2;BYMINUTE=30")
+	if err != nil {
+		t.Fatalf("ParseRecurrence error: %v", err)
+	}
+
+	from := time.Date(2026, time.March, 28, 2, 30, 0, 0, berlin)
+	got := r.Next(from)
+	if want := time.Date(2026, time.March, 29, 3, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Fatalf("occurrence in the DST gap = %v, want %v", got, want)
+	}
+
+	if next, want := r.Next(got), time.Date(2026, time.March, 30, 2, 30, 0, 0, berlin); !next.Equal(want) {
+		t.Fatalf("occurrence after the DST gap = %v, want %v", next, want)
+	}
+}
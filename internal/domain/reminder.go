@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Reminder представляет одно напоминание о задаче.
+// У задачи может быть несколько напоминаний, каждое отправляется один раз:
+// SentAt заполняется, когда напоминание передано в очередь отправки уведомлений.
+type Reminder struct {
+	ID        int        `json:"id" db:"id"`
+	TaskID    int        `json:"task_id" db:"task_id"`
+	RemindAt  time.Time  `json:"remind_at" db:"remind_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsSent проверяет, было ли напоминание отправлено
+func (r *Reminder) IsSent() bool {
+	return r.SentAt != nil
+}
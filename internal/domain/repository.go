@@ -11,36 +11,133 @@ type TaskRepository interface {
 	GetByID(ctx context.Context, id int) (*Task, error)
 	GetByUserID(ctx context.Context, userID int64, status TaskStatus) ([]*Task, error)
 	GetAll(ctx context.Context, userID int64) ([]*Task, error)
+	GetSubtasks(ctx context.Context, parentID int) ([]*Task, error)
+	GetOverdue(ctx context.Context, userID int64, now time.Time) ([]*Task, error)
 	Update(ctx context.Context, task *Task) error
 	Delete(ctx context.Context, id int) error
-	GetTasksForNotification(ctx context.Context, beforeTime time.Time) ([]*Task, error)
+}
+
+// ReminderRepository определяет интерфейс для работы с напоминаниями о задачах
+type ReminderRepository interface {
+	Create(ctx context.Context, reminder *Reminder) error
+	GetByID(ctx context.Context, id int) (*Reminder, error)
+	GetByTaskID(ctx context.Context, taskID int) ([]*Reminder, error)
+	Delete(ctx context.Context, id int) error
+	DeletePending(ctx context.Context, taskID int) error
+}
+
+// NotificationOutboxRepository определяет интерфейс очереди отправки уведомлений
+type NotificationOutboxRepository interface {
+	EnqueueDueReminders(ctx context.Context, now time.Time) (int64, error)
+	Claim(ctx context.Context, now time.Time, limit int) ([]*OutboxMessage, error)
+	Update(ctx context.Context, message *OutboxMessage) error
+	FailStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+}
+
+// JobLocker определяет интерфейс блокировок фоновых задач между несколькими экземплярами бота
+type JobLocker interface {
+	// TryLock захватывает блокировку задачи name без ожидания.
+	// Если блокировку держит другой экземпляр, возвращает ok == false.
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
 }
 
 // UserRepository определяет интерфейс для работы с пользователями
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id int64) (*User, error)
 	GetByTelegramID(ctx context.Context, telegramID int64) (*User, error)
-	Update(ctx context.Context, user *User) error
+	// List возвращает всех пользователей, начиная с недавно входивших
+	List(ctx context.Context) ([]*User, error)
+	// UpdateProfile обновляет имя пользователя в Telegram и время последнего входа
+	UpdateProfile(ctx context.Context, user *User) error
+	SetActive(ctx context.Context, userID int64, active bool) error
+	SetRole(ctx context.Context, userID int64, role UserRole) error
+	SetTimezone(ctx context.Context, userID int64, timezone string) error
+	SetPasswordHash(ctx context.Context, userID int64, hash string) error
+	// SetTOTPState сохраняет секрет TOTP и признак включения.
+	// Шаг принятого кода меняет только UseTOTPStep.
+	SetTOTPState(ctx context.Context, userID int64, secret string, enabled bool) error
+	// UseTOTPStep атомарно запоминает шаг принятого кода TOTP.
+	// Возвращает ErrConflict, если код этого или более позднего шага уже принят.
+	UseTOTPStep(ctx context.Context, userID int64, step int64) error
+}
+
+// RecoveryCodeRepository определяет интерфейс для работы с кодами восстановления
+type RecoveryCodeRepository interface {
+	// Replace заменяет все коды восстановления пользователя новыми, пустой список удаляет коды
+	Replace(ctx context.Context, userID int64, codeHashes []string) error
+	// Use атомарно погашает неиспользованный код восстановления.
+	// Возвращает ErrNotFound, если код не существует или уже использован.
+	Use(ctx context.Context, userID int64, codeHash string, now time.Time) error
+	// CountUnused возвращает количество неиспользованных кодов пользователя
+	CountUnused(ctx context.Context, userID int64) (int, error)
 }
 
-// SessionRepository определяет интерфейс для работы с сессиями
+// InviteRepository определяет интерфейс для работы с приглашениями
+type InviteRepository interface {
+	Create(ctx context.Context, invite *Invite) error
+	// Redeem атомарно погашает действующее приглашение с указанным хешем кода и создает по нему
+	// пользователя: либо выполняется и то и другое, либо ничего.
+	// Возвращает ErrNotFound, если приглашение не существует, уже использовано или истекло.
+	Redeem(ctx context.Context, codeHash string, now time.Time, user *User) (*Invite, error)
+}
+
+// LoginAttemptRepository определяет интерфейс для работы с журналом попыток входа
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *LoginAttempt) error
+	// GetFailures возвращает неудачные попытки пользователя после since, не раньше последнего успешного входа
+	GetFailures(ctx context.Context, telegramID int64, since time.Time) (*LoginFailures, error)
+	// CountFailuresSince возвращает количество неудачных попыток всех пользователей после since
+	CountFailuresSince(ctx context.Context, since time.Time) (int, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) error
+}
+
+// SessionRepository определяет интерфейс для работы с сессиями.
+// У пользователя по одной сессии в каждом чате, где он вошел.
 type SessionRepository interface {
+	// Create создает сессию или заменяет сессию того же пользователя в том же чате
 	Create(ctx context.Context, session *Session) error
-	GetByTelegramID(ctx context.Context, telegramID int64) (*Session, error)
+	Get(ctx context.Context, telegramID, chatID int64) (*Session, error)
+	// ListActive возвращает действующие сессии, новые - первыми
+	ListActive(ctx context.Context) ([]*Session, error)
+	// ListByUserID возвращает действующие сессии пользователя, новые - первыми
+	ListByUserID(ctx context.Context, userID int64) ([]*Session, error)
 	Update(ctx context.Context, session *Session) error
-	Delete(ctx context.Context, telegramID int64) error
+	Delete(ctx context.Context, telegramID, chatID int64) error
+	// DeleteByID удаляет сессию пользователя по ID. Возвращает ErrNotFound, если у пользователя нет такой сессии.
+	DeleteByID(ctx context.Context, id, userID int64) error
+	// DeleteByUserID удаляет все сессии пользователя и возвращает их количество
+	DeleteByUserID(ctx context.Context, userID int64) (int64, error)
 	CleanupExpired(ctx context.Context) error
 }
 
-// NoteRepository определяет интерфейс для работы с заметками
+// ConversationStateRepository определяет интерфейс для хранения состояний диалогов.
+// У пользователя не больше одного диалога: Save заменяет предыдущий.
+type ConversationStateRepository interface {
+	// Get возвращает состояние диалога или ErrNotFound. Истекшие состояния тоже возвращаются,
+	// чтобы обработчик мог сообщить пользователю об отмене диалога.
+	Get(ctx context.Context, userID int64) (*ConversationState, error)
+	Save(ctx context.Context, state *ConversationState) error
+	Delete(ctx context.Context, userID int64) error
+	// DeleteExpiredBefore удаляет диалоги, истекшие раньше before, и возвращает их количество
+	DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// NoteRepository определяет интерфейс для работы с заметками.
+// Методы, принимающие ID заметки, проверяют владельца и возвращают ErrNotFound или ErrForbidden.
 type NoteRepository interface {
 	Create(ctx context.Context, note *Note) error
-	GetByID(ctx context.Context, id int) (*Note, error)
+	GetByID(ctx context.Context, id int, userID int64) (*Note, error)
 	GetByUserID(ctx context.Context, userID int64) ([]*Note, error)
 	GetByCategory(ctx context.Context, userID int64, category NoteCategory) ([]*Note, error)
 	GetByType(ctx context.Context, userID int64, noteType NoteType) ([]*Note, error)
 	GetFavorites(ctx context.Context, userID int64) ([]*Note, error)
 	Search(ctx context.Context, userID int64, query string) ([]*Note, error)
-	Update(ctx context.Context, note *Note) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, note *Note, userID int64) error
+	Delete(ctx context.Context, id int, userID int64) error
+}
+
+// StatsRepository определяет интерфейс для получения общей статистики
+type StatsRepository interface {
+	Get(ctx context.Context) (*Stats, error)
 }
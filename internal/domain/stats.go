@@ -0,0 +1,12 @@
+package domain
+
+// Stats содержит общую статистику бота для администраторов
+type Stats struct {
+	Users          int `json:"users"`
+	ActiveUsers    int `json:"active_users"`
+	Admins         int `json:"admins"`
+	ActiveSessions int `json:"active_sessions"`
+	PendingTasks   int `json:"pending_tasks"`
+	CompletedTasks int `json:"completed_tasks"`
+	Notes          int `json:"notes"`
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 )
 
@@ -24,16 +25,26 @@ const (
 
 // Task представляет задачу в системе
 type Task struct {
-	ID          int          `json:"id" db:"id"`
-	Title       string       `json:"title" db:"title"`
-	Description string       `json:"description" db:"description"`
-	Status      TaskStatus   `json:"status" db:"status"`
-	Priority    TaskPriority `json:"priority" db:"priority"`
-	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
-	CompletedAt *time.Time   `json:"completed_at" db:"completed_at"`
-	NotifyAt    *time.Time   `json:"notify_at" db:"notify_at"`
-	UserID      int64        `json:"user_id" db:"user_id"`
+	ID              int          `json:"id" db:"id"`
+	Title           string       `json:"title" db:"title"`
+	Description     string       `json:"description" db:"description"`
+	Status          TaskStatus   `json:"status" db:"status"`
+	Priority        TaskPriority `json:"priority" db:"priority"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at" db:"updated_at"`
+	CompletedAt     *time.Time   `json:"completed_at" db:"completed_at"`
+	NextReminderAt  *time.Time   `json:"next_reminder_at,omitempty" db:"next_reminder_at"`
+	DueAt           *time.Time   `json:"due_at,omitempty" db:"due_at"`
+	Recurrence      string       `json:"recurrence,omitempty" db:"recurrence"`
+	Tags            string       `json:"tags,omitempty" db:"tags"`
+	EstimateMinutes int          `json:"estimate_minutes,omitempty" db:"estimate_minutes"`
+	ParentID        *int         `json:"parent_id,omitempty" db:"parent_id"`
+	UserID          int64        `json:"user_id" db:"user_id"`
+
+	// Subtasks и Reminders заполняются отдельно и не хранятся в таблице tasks,
+	// NextReminderAt вычисляется по таблице task_reminders и доступно только для чтения
+	Subtasks  []*Task     `json:"subtasks,omitempty" db:"-"`
+	Reminders []*Reminder `json:"reminders,omitempty" db:"-"`
 }
 
 // IsCompleted проверяет, завершена ли задача
@@ -46,10 +57,44 @@ func (t *Task) IsDeleted() bool {
 	return t.Status == TaskStatusDeleted
 }
 
+// IsOverdue проверяет, просрочена ли задача
+func (t *Task) IsOverdue() bool {
+	return t.DueAt != nil &&
+		t.DueAt.Before(time.Now()) &&
+		!t.IsCompleted() &&
+		!t.IsDeleted()
+}
+
+// IsRecurring проверяет, повторяется ли задача
+func (t *Task) IsRecurring() bool {
+	return t.Recurrence != ""
+}
+
+// IsSubtask проверяет, является ли задача подзадачей
+func (t *Task) IsSubtask() bool {
+	return t.ParentID != nil
+}
+
+// SubtaskProgress возвращает количество выполненных и общее количество подзадач
+func (t *Task) SubtaskProgress() (done, total int) {
+	for _, subtask := range t.Subtasks {
+		if subtask.IsCompleted() {
+			done++
+		}
+	}
+	return done, len(t.Subtasks)
+}
+
+// HasOpenSubtasks проверяет, есть ли у задачи невыполненные подзадачи
+func (t *Task) HasOpenSubtasks() bool {
+	done, total := t.SubtaskProgress()
+	return done < total
+}
+
 // CanNotify проверяет, нужно ли отправить уведомление
 func (t *Task) CanNotify() bool {
-	return t.NotifyAt != nil &&
-		t.NotifyAt.Before(time.Now()) &&
+	return t.NextReminderAt != nil &&
+		t.NextReminderAt.Before(time.Now()) &&
 		!t.IsCompleted() &&
 		!t.IsDeleted()
 }
@@ -62,14 +107,72 @@ func (t *Task) Complete() {
 	t.CompletedAt = &now
 }
 
+// Reopen возвращает выполненную задачу в работу
+func (t *Task) Reopen() {
+	t.Status = TaskStatusPending
+	t.UpdatedAt = time.Now()
+	t.CompletedAt = nil
+}
+
 // Delete помечает задачу как удаленную
 func (t *Task) Delete() {
 	t.Status = TaskStatusDeleted
 	t.UpdatedAt = time.Now()
 }
 
-// SetNotification устанавливает время уведомления
-func (t *Task) SetNotification(notifyAt time.Time) {
-	t.NotifyAt = &notifyAt
+// PendingReminders возвращает еще не отправленные напоминания задачи
+func (t *Task) PendingReminders() []*Reminder {
+	var pending []*Reminder
+	for _, reminder := range t.Reminders {
+		if !reminder.IsSent() {
+			pending = append(pending, reminder)
+		}
+	}
+	return pending
+}
+
+// SetDueDate устанавливает срок выполнения задачи (nil убирает срок)
+func (t *Task) SetDueDate(dueAt *time.Time) {
+	t.DueAt = dueAt
+	t.UpdatedAt = time.Now()
+}
+
+// TagList возвращает теги задачи списком
+func (t *Task) TagList() []string {
+	if t.Tags == "" {
+		return nil
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(t.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// SetTags устанавливает теги задачи, убирая "#" и повторы
+func (t *Task) SetTags(tags []string) {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	t.Tags = strings.Join(result, ",")
+}
+
+// SetRecurrence устанавливает правило повторения задачи (nil отключает повторение)
+func (t *Task) SetRecurrence(recurrence *Recurrence) {
+	if recurrence == nil {
+		t.Recurrence = ""
+	} else {
+		t.Recurrence = recurrence.String()
+	}
 	t.UpdatedAt = time.Now()
 }
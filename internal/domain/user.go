@@ -2,26 +2,73 @@ package domain
 
 import "time"
 
+// UserRole представляет роль пользователя
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
 // User представляет пользователя системы
 type User struct {
-	ID          int64     `json:"id" db:"id"`
-	TelegramID  int64     `json:"telegram_id" db:"telegram_id"`
-	Username    string    `json:"username" db:"username"`
-	FirstName   string    `json:"first_name" db:"first_name"`
-	LastName    string    `json:"last_name" db:"last_name"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"`
-}
-
-// Session представляет сессию пользователя
+	ID         int64    `json:"id" db:"id"`
+	TelegramID int64    `json:"telegram_id" db:"telegram_id"`
+	Username   string   `json:"username" db:"username"`
+	FirstName  string   `json:"first_name" db:"first_name"`
+	LastName   string   `json:"last_name" db:"last_name"`
+	IsActive   bool     `json:"is_active" db:"is_active"`
+	Timezone   string   `json:"timezone" db:"timezone"`
+	Role       UserRole `json:"role" db:"role"`
+	// PasswordHash - bcrypt-хеш личного пароля, пустая строка - пароль не установлен
+	PasswordHash string `json:"-" db:"password_hash"`
+	// TOTPSecret - зашифрованный секрет TOTP, пустая строка - двухфакторная аутентификация не настраивалась
+	TOTPSecret string `json:"-" db:"totp_secret"`
+	// TOTPEnabled - вход требует код из приложения-аутентификатора
+	TOTPEnabled bool `json:"totp_enabled" db:"totp_enabled"`
+	// TOTPLastStep - временной шаг последнего принятого кода, более ранние коды не принимаются
+	TOTPLastStep int64     `json:"-" db:"totp_last_step"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	LastLoginAt  time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// Location возвращает часовой пояс пользователя или fallback, если он не задан
+func (u *User) Location(fallback *time.Location) *time.Location {
+	if u == nil || u.Timezone == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return fallback
+	}
+
+	return loc
+}
+
+// IsAdmin проверяет, является ли пользователь администратором
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// HasPassword проверяет, установлен ли личный пароль пользователя
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// Session представляет сессию пользователя в одном чате. Telegram не сообщает боту,
+// с какого устройства пришло сообщение, поэтому отдельным местом входа считается чат:
+// личный чат с ботом и каждая группа, где пользователь вошел, имеют свою сессию.
 type Session struct {
-	UserID     int64     `json:"user_id" db:"user_id"`
-	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
-	IsActive   bool      `json:"is_active" db:"is_active"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	ID             int64     `json:"id" db:"id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	TelegramID     int64     `json:"telegram_id" db:"telegram_id"`
+	ChatID         int64     `json:"chat_id" db:"chat_id"`
+	IsActive       bool      `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at" db:"last_activity_at"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
 }
 
 // IsExpired проверяет, истекла ли сессия
@@ -29,6 +76,19 @@ func (s *Session) IsExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
 
+// Renew продлевает сессию после действия пользователя: срок отсчитывается от now,
+// но не дольше maxLifetime с момента входа (0 - без ограничения)
+func (s *Session) Renew(now time.Time, idleTimeout, maxLifetime time.Duration) {
+	s.LastActivityAt = now
+	s.ExpiresAt = now.Add(idleTimeout)
+
+	if maxLifetime > 0 {
+		if deadline := s.CreatedAt.Add(maxLifetime); s.ExpiresAt.After(deadline) {
+			s.ExpiresAt = deadline
+		}
+	}
+}
+
 // IsValid проверяет, действительна ли сессия
 func (s *Session) IsValid() bool {
 	return s.IsActive && !s.IsExpired()
@@ -0,0 +1,199 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"todolist/internal/domain"
+	"todolist/internal/usecase"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// adminListLimit - сколько записей показывать в списках администратора, чтобы не превысить длину сообщения
+const adminListLimit = 50
+
+// handleUsersCommand обрабатывает команду /users
+func (b *Bot) handleUsersCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	users, err := b.adminService.ListUsers(ctx)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	loc := b.userLocation(admin)
+	text := fmt.Sprintf("👥 Пользователи (%d):\n\n", len(users))
+	for i, user := range users {
+		if i == adminListLimit {
+			text += fmt.Sprintf("... и еще %d\n", len(users)-adminListLimit)
+			break
+		}
+
+		status := "🟢"
+		if !user.IsActive {
+			status = "🚫"
+		}
+
+		text += fmt.Sprintf("%s [%d] %s", status, user.ID, formatUserName(user))
+		if user.IsAdmin() {
+			text += " 👑"
+		}
+		text += fmt.Sprintf("\n   Последний вход: %s\n", user.LastLoginAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	text += "\n/ban ID, /unban ID - заблокировать или разблокировать пользователя"
+	b.sendMessage(chatID, text)
+}
+
+// handleBanCommand обрабатывает команды /ban и /unban
+func (b *Bot) handleBanCommand(active bool) commandHandler {
+	return func(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+		chatID := message.Chat.ID
+
+		userID, ok := b.parseUserIDArgument(message)
+		if !ok {
+			return
+		}
+
+		user, err := b.adminService.SetUserActive(ctx, admin, userID, active)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		if active {
+			b.sendMessage(chatID, fmt.Sprintf("✅ Пользователь [%d] %s разблокирован", user.ID, formatUserName(user)))
+		} else {
+			b.sendMessage(chatID, fmt.Sprintf("🚫 Пользователь [%d] %s заблокирован, его сессии завершены", user.ID, formatUserName(user)))
+		}
+	}
+}
+
+// handleRevokeSessionsCommand обрабатывает команду /revoke ID: завершает сессии пользователя
+func (b *Bot) handleRevokeSessionsCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	userID, ok := b.parseUserIDArgument(message)
+	if !ok {
+		return
+	}
+
+	user, err := b.adminService.RevokeSession(ctx, admin, userID)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("🔒 Сессии пользователя [%d] %s завершены", user.ID, formatUserName(user)))
+}
+
+// handleAllSessionsCommand обрабатывает команду /allsessions: действующие сессии всех пользователей
+func (b *Bot) handleAllSessionsCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	sessions, err := b.adminService.ListSessions(ctx)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	loc := b.userLocation(admin)
+	text := fmt.Sprintf("🔐 Действующие сессии (%d):\n\n", len(sessions))
+	for i, session := range sessions {
+		if i == adminListLimit {
+			text += fmt.Sprintf("... и еще %d\n", len(sessions)-adminListLimit)
+			break
+		}
+
+		text += fmt.Sprintf("👤 [%d] Telegram ID %d, %s\n   Вход: %s, активность: %s, до %s\n",
+			session.UserID, session.TelegramID, sessionPlace(session),
+			session.CreatedAt.In(loc).Format("02.01.2006 15:04"),
+			session.LastActivityAt.In(loc).Format("02.01.2006 15:04"),
+			session.ExpiresAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	text += "\n/revoke ID - завершить сессии пользователя"
+	b.sendMessage(chatID, text)
+}
+
+// handleBroadcastCommand обрабатывает команду /broadcast
+func (b *Bot) handleBroadcastCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	text := strings.TrimSpace(message.CommandArguments())
+	if text == "" {
+		b.sendMessage(chatID, "❌ Укажите текст рассылки: /broadcast текст")
+		return
+	}
+
+	recipients, err := b.adminService.StartBroadcast(ctx, admin, text, func(result usecase.BroadcastResult) {
+		report := fmt.Sprintf("📢 Рассылка завершена: доставлено %d", result.Sent)
+		if result.Failed > 0 {
+			report += fmt.Sprintf(", не доставлено %d", result.Failed)
+		}
+		if result.Err != nil {
+			report = fmt.Sprintf("⚠️ Рассылка прервана: доставлено %d, не доставлено %d", result.Sent, result.Failed)
+		}
+		b.sendMessage(chatID, report)
+	})
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("📢 Рассылка запущена: получателей %d. Итог придет отдельным сообщением.", recipients))
+}
+
+// handleStatsCommand обрабатывает команду /stats
+func (b *Bot) handleStatsCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	stats, err := b.adminService.GetStats(ctx)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	text := "📊 Статистика\n\n"
+	text += fmt.Sprintf("👥 Пользователей: %d (активных %d, администраторов %d)\n", stats.Users, stats.ActiveUsers, stats.Admins)
+	text += fmt.Sprintf("🔐 Действующих сессий: %d\n", stats.ActiveSessions)
+	text += fmt.Sprintf("📝 Задач: %d в работе, %d выполнено\n", stats.PendingTasks, stats.CompletedTasks)
+	text += fmt.Sprintf("📚 Заметок: %d", stats.Notes)
+	b.sendMessage(chatID, text)
+}
+
+// parseUserIDArgument разбирает ID пользователя из первого аргумента команды.
+// При ошибке сам отправляет подсказку и возвращает false.
+func (b *Bot) parseUserIDArgument(message *tgbotapi.Message) (int64, bool) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 1 {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Укажите ID пользователя из /users: /%s 123", message.Command()))
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.sendMessage(chatID, "❌ Неверный ID пользователя")
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// formatUserName возвращает имя пользователя и его @username, если он есть
+func formatUserName(user *domain.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = fmt.Sprintf("ID %d", user.TelegramID)
+	}
+	if user.Username != "" {
+		name += " (@" + user.Username + ")"
+	}
+	return name
+}
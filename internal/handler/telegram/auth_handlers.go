@@ -3,46 +3,65 @@ package telegram
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"todolist/internal/callbackdata"
 	"todolist/internal/domain"
+	"todolist/internal/usecase"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// handleStartCommand обрабатывает команду /start
-func (b *Bot) handleStartCommand(ctx context.Context, message *tgbotapi.Message) {
+// handleStartCommand обрабатывает команду /start. Команда публичная, пользователь еще не авторизован.
+func (b *Bot) handleStartCommand(ctx context.Context, message *tgbotapi.Message, _ *domain.User) {
 	chatID := message.Chat.ID
 	userID := message.From.ID
 
 	args := strings.Fields(message.Text)
-	if len(args) < 2 {
-		b.sendMessage(chatID, "🔐 Для авторизации отправьте: /start пароль")
-		return
-	}
 
-	password := args[1]
+	// Пароль не должен оставаться в истории чата, удаляем сообщение после обработки
+	if len(args) > 1 {
+		defer b.deleteMessage(chatID, message.MessageID)
+	}
 
-	// Проверяем, есть ли уже активная сессия
-	_, err := b.authService.IsAuthenticated(ctx, userID)
-	if err == nil {
+	// Сессия проверяется до разбора пароля: авторизованному пользователю он не нужен
+	if _, err := b.authService.IsAuthenticated(ctx, userID, chatID); err == nil {
 		text := "✅ *Вы уже авторизованы!*\n\nВыберите действие в главном меню:"
-		keyboard := getMainMenuKeyboard()
+		keyboard := b.getMainMenuKeyboard()
 		b.sendMessageWithKeyboard(chatID, text, keyboard)
 		return
 	}
 
+	if len(args) < 2 {
+		b.sendMessage(chatID, "🔐 Для авторизации отправьте: /start пароль")
+		return
+	}
+
+	password := args[1]
+
 	// Аутентификация через Login
 	username := message.From.UserName
 	firstName := message.From.FirstName
 	lastName := message.From.LastName
 
-	_, err = b.authService.Login(ctx, userID, username, firstName, lastName, password)
+	user, err := b.authService.Login(ctx, userID, chatID, username, firstName, lastName, password)
+	if errors.Is(err, usecase.ErrSecondFactorRequired) {
+		b.sendMessage(chatID, "🔢 Отправьте 6-значный код из приложения-аутентификатора или код восстановления. "+
+			"Код нужно ввести в течение 5 минут.")
+		return
+	}
 	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный пароль")
+		b.sendError(chatID, err)
 		return
 	}
 
+	b.sendWelcome(chatID, user)
+}
+
+// sendWelcome отправляет приветствие и главное меню после успешного входа
+func (b *Bot) sendWelcome(chatID int64, user *domain.User) {
 	welcomeMsg := `🎉 *Добро пожаловать в TodoList Bot!*
 
 Вы успешно авторизованы в системе.
@@ -54,30 +73,131 @@ func (b *Bot) handleStartCommand(ctx context.Context, message *tgbotapi.Message)
 
 Выберите действие в главном меню:`
 
-	keyboard := getMainMenuKeyboard()
+	if !user.HasPassword() {
+		welcomeMsg += "\n\n🔑 Установите личный пароль командой /password, чтобы входить без общего пароля или приглашения."
+	} else if !user.TOTPEnabled && b.twoFactorService.Available() {
+		welcomeMsg += "\n\n🔐 Включите двухфакторную аутентификацию командой /2fa, чтобы для входа было недостаточно одного пароля."
+	}
+
+	keyboard := b.getMainMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, welcomeMsg, keyboard)
 }
 
-// handleLogoutCommand обрабатывает команду /logout
-func (b *Bot) handleLogoutCommand(ctx context.Context, chatID, userID int64) {
-	err := b.authService.Logout(ctx, userID)
+// handlePasswordCommand обрабатывает команду /password
+func (b *Bot) handlePasswordCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	password := strings.TrimSpace(message.CommandArguments())
+	if password == "" {
+		b.sendMessage(chatID, "🔑 Укажите новый пароль: /password пароль\n\nСообщение с паролем будет удалено из чата.")
+		return
+	}
+
+	// Пароль не должен оставаться в истории чата
+	b.deleteMessage(chatID, message.MessageID)
+
+	if err := b.authService.SetPassword(ctx, user.ID, password); err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendMessage(chatID, "✅ Личный пароль установлен. Теперь для входа используйте /start и этот пароль.")
+}
+
+// handleInviteCommand обрабатывает команду /invite
+func (b *Bot) handleInviteCommand(ctx context.Context, message *tgbotapi.Message, admin *domain.User) {
+	chatID := message.Chat.ID
+
+	code, invite, err := b.authService.CreateInvite(ctx, admin)
 	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("🎟 Код приглашения: %s\n\nПередайте новому пользователю команду:\n/start %s\n\n"+
+		"Код одноразовый и действует до %s.",
+		code, code, invite.ExpiresAt.In(b.userLocation(admin)).Format("02.01.2006 15:04"))
+	b.sendMessage(chatID, text)
+}
+
+// handleSessionsCommand обрабатывает команду /sessions: показывает сессии пользователя во всех чатах,
+// позволяет завершить любую из них и выйти везде. Сессии всех пользователей показывает /allsessions.
+func (b *Bot) handleSessionsCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	sessions, err := b.authService.ListSessions(ctx, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	loc := b.userLocation(user)
+	text := "🔐 Ваши сессии:\n\n"
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, session := range sessions {
+		current := session.ChatID == chatID
+		text += fmt.Sprintf("📱 [%d] %s", session.ID, sessionPlace(session))
+		if current {
+			text += " (текущая)"
+		}
+		text += fmt.Sprintf("\n   Вход: %s\n   Последняя активность: %s\n   Действует до: %s\n\n",
+			session.CreatedAt.In(loc).Format("02.01.2006 15:04"),
+			session.LastActivityAt.In(loc).Format("02.01.2006 15:04"),
+			session.ExpiresAt.In(loc).Format("02.01.2006 15:04"))
+
+		if !current {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				b.button(fmt.Sprintf("❌ Завершить [%d]", session.ID),
+					callbackdata.New(callbackdata.EntitySession, callbackdata.ActionRemove, int(session.ID))),
+			))
+		}
+	}
+
+	text += "Сессия продлевается при каждом действии"
+	if maxLifetime := b.config.Auth.SessionMaxLifetime; maxLifetime > 0 {
+		text += fmt.Sprintf(", но не дольше %s с момента входа", formatDuration(maxLifetime))
+	}
+	text += "."
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.button("🚪 Выйти", callbackdata.Menu(callbackdata.ActionLogout)),
+		b.button("🚪 Выйти везде", callbackdata.Menu(callbackdata.ActionLogoutAll)),
+	))
+	b.sendMessageWithKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// sessionPlace описывает чат сессии: личный чат с ботом или группа
+func sessionPlace(session *domain.Session) string {
+	if session.ChatID == session.TelegramID {
+		return "личный чат"
+	}
+	return fmt.Sprintf("чат %d", session.ChatID)
+}
+
+// handleLogoutCommand обрабатывает команду /logout
+func (b *Bot) handleLogoutCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	if err := b.authService.Logout(ctx, message.From.ID, chatID); err != nil {
 		b.sendMessage(chatID, "❌ Ошибка при выходе")
 		return
 	}
 
 	// Удаляем состояние пользователя
-	delete(b.userStates, userID)
+	b.clearState(ctx, user.ID)
 
 	b.sendMessage(chatID, "👋 Вы вышли из системы. Для повторной авторизации отправьте /start пароль")
 }
 
-// getUserFromTelegram получает пользователя по Telegram ID
-func (b *Bot) getUserFromTelegram(ctx context.Context, telegramID int64) (*domain.User, error) {
-	user, err := b.authService.IsAuthenticated(ctx, telegramID)
-	if err != nil {
-		return nil, errors.New("пользователь не авторизован")
+// formatDuration форматирует длительность: "30 дн", "12 ч", "45 мин"
+func formatDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d >= day && d%day == 0:
+		return fmt.Sprintf("%d дн", d/day)
+	case d >= time.Hour:
+		return fmt.Sprintf("%d ч", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d мин", int(d.Minutes()))
 	}
-
-	return user, nil
 }
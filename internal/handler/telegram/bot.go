@@ -2,63 +2,113 @@ package telegram
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 
 	"todolist/config"
+	"todolist/internal/callbackdata"
+	"todolist/internal/domain"
+	"todolist/internal/sender"
 	"todolist/internal/usecase"
 )
 
 // Bot представляет телеграм бота
 type Bot struct {
 	api                 *tgbotapi.BotAPI
+	sender              *sender.Sender
 	authService         *usecase.AuthService
+	twoFactorService    *usecase.TwoFactorService
 	taskService         *usecase.TaskService
 	noteService         *usecase.NoteService
+	userService         *usecase.UserService
+	adminService        *usecase.AdminService
 	notificationService *usecase.NotificationService
 	config              *config.Config
 	logger              *zap.Logger
-	userStates          map[int64]*UserState
-}
-
-// UserState хранит состояние пользователя для многошаговых операций
-type UserState struct {
-	Action      string
-	Step        int
-	TaskID      int
-	NoteID      int
-	TaskData    map[string]string
-	NoteData    map[string]string
-	LastCommand string
+	states              domain.ConversationStateRepository
+	router              *router
+	callbacks           *callbackdata.Codec
 }
 
 // NewBot создает новый экземпляр бота
 func NewBot(
 	api *tgbotapi.BotAPI,
+	sender *sender.Sender,
 	authService *usecase.AuthService,
+	twoFactorService *usecase.TwoFactorService,
 	taskService *usecase.TaskService,
 	noteService *usecase.NoteService,
+	userService *usecase.UserService,
+	adminService *usecase.AdminService,
 	notificationService *usecase.NotificationService,
+	states domain.ConversationStateRepository,
 	config *config.Config,
 	logger *zap.Logger,
 ) *Bot {
-	return &Bot{
+	b := &Bot{
 		api:                 api,
+		sender:              sender,
 		authService:         authService,
+		twoFactorService:    twoFactorService,
 		taskService:         taskService,
 		noteService:         noteService,
+		userService:         userService,
+		adminService:        adminService,
 		notificationService: notificationService,
 		config:              config,
 		logger:              logger,
-		userStates:          make(map[int64]*UserState),
+		states:              states,
+		callbacks:           callbackdata.NewCodec(config.Bot.CallbackSecret),
 	}
+	b.router = b.newBotRouter()
+
+	return b
 }
 
-// Start запускает бота
+// Start запускает бота в режиме BOT_MODE: long polling или webhook. Обновления обрабатываются
+// пулом из BOT_WORKERS воркеров; при отмене ctx бот перестает получать обновления
+// и дожидается обработки уже принятых.
 func (b *Bot) Start(ctx context.Context) error {
-	b.logger.Info("bot starting...")
+	mode := b.config.Bot.Mode
+	switch mode {
+	case config.BotModePolling:
+	case config.BotModeWebhook:
+		if b.config.Webhook.Secret == "" {
+			return errors.New("BOT_WEBHOOK_SECRET is required in webhook mode")
+		}
+		if !validWebhookSecret(b.config.Webhook.Secret) {
+			return errors.New("BOT_WEBHOOK_SECRET may contain only A-Z, a-z, 0-9, _ and -, up to 256 characters")
+		}
+	default:
+		return fmt.Errorf("unknown bot mode %q, use %s or %s", mode, config.BotModePolling, config.BotModeWebhook)
+	}
+
+	b.logger.Info("bot starting...", zap.String("mode", mode), zap.Int("workers", b.config.Bot.Workers))
+
+	dispatcher := newUpdateDispatcher(ctx, b.config.Bot.Workers, b.config.Bot.WorkerQueueSize, b.handleUpdate, b.logger)
+
+	var err error
+	if mode == config.BotModeWebhook {
+		err = b.runWebhook(ctx, dispatcher)
+	} else {
+		err = b.runPolling(ctx, dispatcher)
+	}
+
+	dispatcher.Shutdown(b.config.Bot.ShutdownTimeout)
+	b.logger.Info("bot stopped")
+	return err
+}
+
+// runPolling получает обновления через long polling до отмены ctx
+func (b *Bot) runPolling(ctx context.Context, dispatcher *updateDispatcher) error {
+	// Пока зарегистрирован webhook, getUpdates возвращает ошибку - например, после переключения из режима webhook
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		b.logger.Error("failed to delete webhook", zap.Error(err))
+	}
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -69,9 +119,12 @@ func (b *Bot) Start(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			b.logger.Info("bot stopping...")
+			b.api.StopReceivingUpdates()
 			return ctx.Err()
 		case update := <-updates:
-			go b.handleUpdate(ctx, update)
+			if !dispatcher.Dispatch(ctx, update) {
+				b.logger.Warn("update dropped on shutdown", zap.Int("update_id", update.UpdateID))
+			}
 		}
 	}
 }
@@ -94,154 +147,105 @@ func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	}
 }
 
+// messageLogFields описывает сообщение для журнала без его текста: в аргументах /start, /password
+// и /2fa приходят пароли, коды приглашений и коды подтверждения, а в обычных сообщениях - коды 2FA
+func messageLogFields(message *tgbotapi.Message) []zap.Field {
+	fields := []zap.Field{
+		zap.Int64("user_id", message.From.ID),
+		zap.Int64("chat_id", message.Chat.ID),
+		zap.Int("text_length", utf8.RuneCountInString(message.Text)),
+	}
+	if message.IsCommand() {
+		fields = append(fields, zap.String("command", message.Command()))
+	}
+	return fields
+}
+
 // handleMessage обрабатывает входящие сообщения
 func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 	userID := message.From.ID
 	chatID := message.Chat.ID
 
-	b.logger.Info("received message",
-		zap.Int64("user_id", userID),
-		zap.Int64("chat_id", chatID),
-		zap.String("text", message.Text))
+	b.logger.Info("received message", messageLogFields(message)...)
+
+	// Команды обрабатываются и во время диалога: незаконченный диалог можно продолжить после них.
+	// Авторизацию, роль и частоту запросов проверяют middleware маршрутизатора.
+	if message.IsCommand() {
+		if !b.router.handleCommand(ctx, message) {
+			b.sendMessage(chatID, "❓ Неизвестная команда. Используйте /help для просмотра доступных команд.")
+		}
+		return
+	}
 
-	// Проверяем авторизацию (кроме команды /start)
-	if !strings.HasPrefix(message.Text, "/start") {
-		user, err := b.authService.IsAuthenticated(ctx, userID)
-		if err != nil {
-			b.sendMessage(chatID, "🔐 Для использования бота необходимо авторизоваться. Используйте команду /start")
+	user, err := b.authService.IsAuthenticated(ctx, userID, chatID)
+	if err != nil {
+		// Сообщение без сессии может быть кодом двухфакторной аутентификации после /start
+		if message.Text != "" && b.authService.HasPendingSecondFactor(ctx, userID, chatID) {
+			b.handleSecondFactorCode(ctx, message)
 			return
 		}
-		userID = user.ID // Используем внутренний ID пользователя
+		b.sendMessage(chatID, "🔐 Для использования бота необходимо авторизоваться. Используйте команду /start")
+		return
 	}
 
-	// Обработка состояний пользователя
-	if state, exists := b.userStates[userID]; exists && state.Action != "" {
-		b.handleUserState(ctx, message, state)
+	// Геопозиция используется для определения часового пояса
+	if message.Location != nil {
+		b.handleLocationMessage(ctx, message, user)
 		return
 	}
 
-	// Обработка команд
-	if message.IsCommand() {
-		b.handleCommand(ctx, message)
+	// Обработка состояний пользователя
+	state, err := b.getState(ctx, user.ID)
+	switch {
+	case errors.Is(err, errStateExpired):
+		b.sendMessage(chatID, "⌛ Время ожидания ответа истекло, действие отменено. Начните его заново.")
+		return
+	case err != nil:
+		b.sendError(chatID, err)
+		return
+	case state != nil:
+		b.handleUserState(ctx, message, user, state)
 		return
 	}
 
 	if message.Document != nil || len(message.Photo) > 0 || message.Video != nil ||
 		message.Audio != nil || message.Voice != nil {
 		// Если это файл, создаем заметку из файла
-		b.handleCreateNoteFromFile(ctx, message)
+		b.handleCreateNoteFromFile(ctx, message, user)
 		return
 	}
 	// Если это не команда, создаем задачу из текста
-	b.handleCreateTaskFromText(ctx, message)
+	b.handleCreateTaskFromText(ctx, message, user)
 }
 
-// handleCommand обрабатывает команды бота
-func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
-	chatID := message.Chat.ID
-	userID := message.From.ID
+// send отправляет запрос через Sender с приоритетом ответа пользователю.
+// Контекст обработчика не передается: ответ на уже принятое обновление доставляется и при остановке бота,
+// ожидание ограничено числом повторов Sender.
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.sender.Send(context.Background(), c, sender.PriorityInteractive)
+}
 
-	switch message.Command() {
-	case "start":
-		b.handleStartCommand(ctx, message)
-	case "help":
-		b.handleHelpCommand(chatID)
-	case "tasks", "list":
-		b.handleListTasksCommand(ctx, chatID, userID)
-	case "add", "new":
-		b.handleAddTaskCommand(ctx, message)
-	case "complete", "done":
-		b.handleCompleteTaskCommand(ctx, message)
-	case "delete", "del":
-		b.handleDeleteTaskCommand(ctx, message)
-	case "show", "get":
-		b.handleShowTaskCommand(ctx, message)
-	case "pending":
-		b.handlePendingTasksCommand(ctx, chatID, userID)
-	case "completed":
-		b.handleCompletedTasksCommand(ctx, chatID, userID)
-	case "notify":
-		b.handleSetNotificationCommand(ctx, message)
-	case "notes":
-		b.handleListNotesCommand(ctx, chatID, userID)
-	case "note":
-		b.handleAddNoteCommand(ctx, message)
-	case "nshow":
-		b.handleShowNoteCommand(ctx, message)
-	case "ndelete":
-		b.handleDeleteNoteCommand(ctx, message)
-	case "favorites":
-		b.handleFavoriteNotesCommand(ctx, chatID, userID)
-	case "favorite":
-		b.handleToggleFavoriteCommand(ctx, message)
-	case "search":
-		b.handleSearchNotesCommand(ctx, message)
-	case "links":
-		b.handleLinkNotesCommand(ctx, chatID, userID)
-	case "files":
-		b.handleFileNotesCommand(ctx, chatID, userID)
-	case "logout":
-		b.handleLogoutCommand(ctx, chatID, userID)
-	default:
-		b.sendMessage(chatID, "❓ Неизвестная команда. Используйте /help для просмотра доступных команд.")
-	}
+// request выполняет запрос без ответа-сообщения (удаление, ответ на callback) через Sender
+func (b *Bot) request(c tgbotapi.Chattable) error {
+	return b.sender.Request(context.Background(), c, sender.PriorityInteractive)
 }
 
-// handleHelpCommand обрабатывает команду /help
-func (b *Bot) handleHelpCommand(chatID int64) {
-	helpText := `📖 *Справка по командам Todo Bot*
-
-🔐 *Авторизация:*
-/start пароль - авторизация в системе
-
-📝 *Работа с задачами:*
-/tasks, /list - показать все задачи
-/pending - показать невыполненные задачи
-/completed - показать выполненные задачи
-/add название - создать новую задачу
-/complete ID - отметить задачу как выполненную
-/delete ID - удалить задачу
-/show ID - показать подробную информацию о задаче
-
-📚 *Работа с заметками:*
-/notes - показать все заметки
-/note заголовок - создать новую заметку
-/nshow ID - показать заметку
-/ndelete ID - удалить заметку
-/favorites - показать избранные заметки
-/favorite ID - добавить/убрать из избранного
-/search запрос - поиск заметок
-/links - показать все ссылки
-/files - показать все файлы
-
-⏰ *Уведомления:*
-/notify ID время - установить напоминание
-   Примеры времени:
-   • 15:30 - сегодня в 15:30
-   • завтра 10:00 - завтра в 10:00
-   • 25.12 14:00 - 25 декабря в 14:00
-
-🔧 *Прочее:*
-/help - показать эту справку
-/logout - выйти из системы
-
-💡 *Быстрое создание:*
-Просто отправьте текст - он станет новой задачей!
-Отправьте документ/изображение - станет заметкой!
-
-📋 *Приоритеты задач:*
-🔴 высокий | 🟡 средний | 🟢 низкий`
-
-	msg := tgbotapi.NewMessage(chatID, helpText)
-	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+// sendChattable отправляет подготовленное сообщение и записывает ошибку в лог
+func (b *Bot) sendChattable(c tgbotapi.Chattable) {
+	if _, err := b.send(c); err != nil {
+		b.logger.Error("failed to send message", zap.Error(err))
+	}
 }
 
 // sendMessage отправляет сообщение пользователю
 func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := b.api.Send(msg); err != nil {
-		b.logger.Error("failed to send message", zap.Error(err))
+	b.sendChattable(tgbotapi.NewMessage(chatID, text))
+}
+
+// deleteMessage удаляет сообщение из чата
+func (b *Bot) deleteMessage(chatID int64, messageID int) {
+	if err := b.request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		b.logger.Warn("failed to delete message", zap.Error(err))
 	}
 }
 
@@ -249,7 +253,7 @@ func (b *Bot) sendMessage(chatID int64, text string) {
 func (b *Bot) sendMessageWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.send(msg); err != nil {
 		b.logger.Error("failed to send message with keyboard", zap.Error(err))
 	}
 }
@@ -0,0 +1,54 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestMessageLogFieldsRedactsText проверяет, что пароли и коды из сообщений не попадают в журнал
+func TestMessageLogFieldsRedactsText(t *testing.T) {
+	tests := []struct {
+		text    string
+		command string
+	}{
+		{text: "/start s3cret-pass", command: "start"},
+		{text: "/password s3cret-pass", command: "password"},
+		{text: "/2fa confirm 123456", command: "2fa"},
+		{text: "123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			message := &tgbotapi.Message{
+				From: &tgbotapi.User{ID: 42},
+				Chat: &tgbotapi.Chat{ID: 42},
+				Text: tt.text,
+			}
+			if tt.command != "" {
+				message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(tt.command) + 1}}
+			}
+
+			encoder := zapcore.NewMapObjectEncoder()
+			for _, field := range messageLogFields(message) {
+				field.AddTo(encoder)
+			}
+
+			for key, value := range encoder.Fields {
+				logged := fmt.Sprint(value)
+				if strings.Contains(logged, "s3cret") || strings.Contains(logged, "123456") {
+					t.Fatalf("field %s = %q leaks the message text", key, logged)
+				}
+			}
+			if got := fmt.Sprint(encoder.Fields["command"]); tt.command != "" && got != tt.command {
+				t.Fatalf("command = %q, want %q", got, tt.command)
+			}
+			if got := encoder.Fields["text_length"]; got != int64(len(tt.text)) {
+				t.Fatalf("text_length = %v, want %d", got, len(tt.text))
+			}
+		})
+	}
+}
@@ -3,44 +3,46 @@ package telegram
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
+	"todolist/internal/callbackdata"
 	"todolist/internal/domain"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
 )
 
 // handleSearchCallback обрабатывает кнопку поиска заметок
-func (b *Bot) handleSearchCallback(ctx context.Context, chatID, userID int64) {
-	b.userStates[userID] = &UserState{
-		Action:   "search_notes",
-		Step:     1,
+func (b *Bot) handleSearchCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	if err := b.startState(ctx, user.ID, &UserState{
+		Action:   actionSearchNotes,
 		NoteData: make(map[string]string),
+	}); err != nil {
+		b.sendError(chatID, err)
+		return
 	}
 
 	text := "🔍 *Поиск заметок*\n\nВведите поисковый запрос:"
-	keyboard := getBackToMenuKeyboard()
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleFavoritesCallback обрабатывает кнопку избранных заметок
-func (b *Bot) handleFavoritesCallback(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleFavoritesCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
 
 	notes, err := b.noteService.GetFavoriteNotes(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения избранных заметок: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
 	if len(notes) == 0 {
 		text := "⭐ У вас пока нет избранных заметок\n\nДобавьте заметки в избранное для быстрого доступа!"
-		keyboard := getBackToMenuKeyboard()
+		keyboard := b.getBackToMenuKeyboard()
 		b.sendMessageWithKeyboard(chatID, text, keyboard)
 		return
 	}
@@ -56,158 +58,338 @@ func (b *Bot) handleFavoritesCallback(ctx context.Context, chatID, userID int64)
 	}
 
 	text := fmt.Sprintf("⭐ *Избранные заметки* (%d)\n\nВаши любимые заметки:", len(notes))
-	keyboard := getNoteListKeyboard(noteItems)
+	keyboard := b.getNoteListKeyboard(noteItems)
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleShowNoteCallback обрабатывает показ заметки
-func (b *Bot) handleShowNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handleShowNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	noteIDStr := strings.TrimPrefix(query.Data, "show_note_")
-	noteID, err := strconv.Atoi(noteIDStr)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID заметки")
-		return
-	}
 
-	note, err := b.noteService.GetNote(ctx, noteID)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
-		return
-	}
+	noteID := data.ID
 
-	if note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
+	note, err := b.noteService.GetNote(ctx, noteID, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
 		return
 	}
 
-	text := b.noteService.FormatNoteForDisplay(note)
-	keyboard := getNoteActionsKeyboard(noteID, note.IsFavorite)
+	text := b.noteService.FormatNoteForDisplay(note, b.userLocation(user))
+	keyboard := b.getNoteActionsKeyboard(note)
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
-// handleDeleteNoteCallback обрабатывает удаление заметки
-func (b *Bot) handleDeleteNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+// handleDownloadNoteCallback отправляет файл, прикрепленный к заметке
+func (b *Bot) handleDownloadNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	noteIDStr := strings.TrimPrefix(query.Data, "delete_note_")
-	noteID, err := strconv.Atoi(noteIDStr)
+
+	note, err := b.noteService.GetAttachment(ctx, data.ID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID заметки")
+		b.sendError(chatID, err)
 		return
 	}
 
+	file := tgbotapi.FileID(note.FileID)
+
+	var msg tgbotapi.Chattable
+	switch note.Type {
+	case domain.NoteTypeImage:
+		msg = tgbotapi.NewPhoto(chatID, file)
+	case domain.NoteTypeVideo:
+		msg = tgbotapi.NewVideo(chatID, file)
+	case domain.NoteTypeAudio:
+		// Голосовые сообщения сохраняются как аудио с именем voice_*.ogg,
+		// а Telegram отправляет их только методом sendVoice
+		if strings.HasPrefix(note.FileName, "voice_") {
+			msg = tgbotapi.NewVoice(chatID, file)
+		} else {
+			msg = tgbotapi.NewAudio(chatID, file)
+		}
+	default:
+		msg = tgbotapi.NewDocument(chatID, file)
+	}
+
+	b.sendChattable(msg)
+}
+
+// handleDeleteNoteCallback обрабатывает удаление заметки
+func (b *Bot) handleDeleteNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	noteID := data.ID
+
 	text := "🗑️ *Удаление заметки*\n\nВы уверены, что хотите удалить эту заметку?"
-	keyboard := getConfirmationKeyboard("delete_note", noteID)
+	keyboard := b.getConfirmationKeyboard(callbackdata.EntityNote, callbackdata.ActionDelete, noteID)
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleAddFavoriteCallback обрабатывает добавление в избранное
-func (b *Bot) handleAddFavoriteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handleAddFavoriteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	noteIDStr := strings.TrimPrefix(query.Data, "favorite_add_")
-	noteID, err := strconv.Atoi(noteIDStr)
+
+	noteID := data.ID
+
+	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID заметки")
+		b.sendError(chatID, err)
 		return
 	}
 
-	// Проверяем принадлежность заметки пользователю
-	note, err := b.noteService.GetNote(ctx, noteID)
-	if err != nil || note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
+	text := fmt.Sprintf("⭐ *Заметка добавлена в избранное!*\n\n[%d] %s", noteID, updatedNote.Title)
+	keyboard := b.getNoteActionsKeyboard(updatedNote)
+	b.sendMessageWithKeyboard(chatID, text, keyboard)
+}
+
+// handleRemoveFavoriteCallback обрабатывает удаление из избранного
+func (b *Bot) handleRemoveFavoriteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	noteID := data.ID
+
+	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
 		return
 	}
 
-	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+	text := fmt.Sprintf("✨ *Заметка убрана из избранного*\n\n[%d] %s", noteID, updatedNote.Title)
+	keyboard := b.getNoteActionsKeyboard(updatedNote)
+	b.sendMessageWithKeyboard(chatID, text, keyboard)
+}
+
+// handleDeleteTaskCallback обрабатывает удаление задачи
+func (b *Bot) handleDeleteTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	taskID := data.ID
+
+	text := "🗑️ *Удаление задачи*\n\nВы уверены, что хотите удалить эту задачу?"
+	keyboard := b.getConfirmationKeyboard(callbackdata.EntityTask, callbackdata.ActionDelete, taskID)
+	b.sendMessageWithKeyboard(chatID, text, keyboard)
+}
+
+// handleNotifyTaskCallback обрабатывает установку напоминания для задачи
+func (b *Bot) handleNotifyTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	taskID := data.ID
+
+	// Запускаем интерактивную настройку уведомления
+	if err := b.startState(ctx, user.ID, &UserState{
+		Action:   actionSetNotification,
+		TaskID:   taskID,
+		TaskData: make(map[string]string),
+	}); err != nil {
+		b.sendError(chatID, err)
 		return
 	}
 
-	text := fmt.Sprintf("⭐ *Заметка добавлена в избранное!*\n\n[%d] %s", noteID, updatedNote.Title)
-	keyboard := getNoteActionsKeyboard(noteID, updatedNote.IsFavorite)
+	text := "⏰ *Настройка напоминания*\n\nВведите время уведомления:\n\n*Примеры:*\n• 15:30 - сегодня в 15:30\n• завтра 10:00\n• через 2 часа\n• в пятницу в 9\n• послезавтра утром\n• 25.12 14:00"
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
-// handleRemoveFavoriteCallback обрабатывает удаление из избранного
-func (b *Bot) handleRemoveFavoriteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+// snoozePresets - выражения времени для кнопок откладывания напоминания
+var snoozePresets = map[string]string{
+	"10m": "через 10 минут",
+	"1h":  "через час",
+	"tm":  "завтра утром",
+}
+
+// handleSnoozeCallback откладывает напоминание и обновляет исходное сообщение
+func (b *Bot) handleSnoozeCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	noteIDStr := strings.TrimPrefix(query.Data, "favorite_remove_")
-	noteID, err := strconv.Atoi(noteIDStr)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID заметки")
+
+	taskID := data.ID
+	preset := data.Arg(0)
+
+	if preset == "custom" {
+		// Запоминаем сообщение, чтобы обновить его после ввода времени
+		if err := b.startState(ctx, user.ID, &UserState{
+			Action:    actionSnoozeCustom,
+			TaskID:    taskID,
+			MessageID: query.Message.MessageID,
+			TaskData:  map[string]string{"text": query.Message.Text},
+		}); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		text := "💤 *Отложить напоминание*\n\nВведите новое время:\n\n*Примеры:*\n• через 30 минут\n• 18:00\n• завтра 10:00\n• в понедельник утром"
+		keyboard := b.getBackToMenuKeyboard()
+		b.sendMessageWithKeyboard(chatID, text, keyboard)
 		return
 	}
 
-	// Проверяем принадлежность заметки пользователю
-	note, err := b.noteService.GetNote(ctx, noteID)
-	if err != nil || note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
+	expression, ok := snoozePresets[preset]
+	if !ok {
+		b.sendMessage(chatID, "❌ Неверный формат команды")
 		return
 	}
 
-	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID)
+	notifyAt, err := b.parseTime(expression, b.userLocation(user))
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	text := fmt.Sprintf("✨ *Заметка убрана из избранного*\n\n[%d] %s", noteID, updatedNote.Title)
-	keyboard := getNoteActionsKeyboard(noteID, updatedNote.IsFavorite)
+	if _, err := b.taskService.AddReminder(ctx, taskID, user.ID, notifyAt); err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.editSnoozedReminder(chatID, query.Message.MessageID, query.Message.Text, taskID, notifyAt)
+}
+
+// editSnoozedReminder дописывает в сообщение напоминания новое время и убирает кнопки откладывания.
+// Время remindAt должно быть в часовом поясе пользователя.
+func (b *Bot) editSnoozedReminder(chatID int64, messageID int, original string, taskID int, remindAt time.Time) {
+	// Убираем отметку о предыдущем откладывании, если напоминание откладывают повторно
+	var lines []string
+	for _, line := range strings.Split(original, "\n") {
+		if !strings.HasPrefix(line, "💤 Отложено до") {
+			lines = append(lines, line)
+		}
+	}
+
+	text := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	text += fmt.Sprintf("\n\n💤 Отложено до %s", remindAt.Format("02.01.2006 15:04"))
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, b.getSnoozedReminderKeyboard(taskID))
+	if _, err := b.send(edit); err != nil {
+		b.logger.Error("failed to edit reminder message", zap.Error(err))
+	}
+}
+
+// handleRecurrenceMenuCallback показывает выбор правила повторения задачи
+func (b *Bot) handleRecurrenceMenuCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	taskID := data.ID
+
+	text := fmt.Sprintf("🔁 *Повторение задачи [%d]*\n\nВыберите правило:", taskID)
+	keyboard := b.getRecurrenceKeyboard(taskID)
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
-// handleDeleteTaskCallback обрабатывает удаление задачи
-func (b *Bot) handleDeleteTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+// handleRecurrenceCallback обрабатывает выбор правила повторения
+func (b *Bot) handleRecurrenceCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	taskIDStr := strings.TrimPrefix(query.Data, "delete_")
-	taskID, err := strconv.Atoi(taskIDStr)
+
+	taskID := data.ID
+	preset := data.Arg(0)
+	if preset == "" {
+		b.sendMessage(chatID, "❌ Неверный формат команды")
+		return
+	}
+
+	if preset == "custom" {
+		// Запускаем ввод собственного правила
+		if err := b.startState(ctx, user.ID, &UserState{
+			Action:   actionSetRecurrence,
+			TaskID:   taskID,
+			TaskData: make(map[string]string),
+		}); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		text := "✏️ *Свое правило повторения*\n\nВведите правило:\n\n*Примеры:*\n• every 3 days\n• monthly 15 10:00\n• FREQ=WEEKLY;BYDAY=MO,TH"
+		keyboard := b.getBackToMenuKeyboard()
+		b.sendMessageWithKeyboard(chatID, text, keyboard)
+		return
+	}
+
+	task, err := b.taskService.SetTaskRecurrence(ctx, taskID, user.ID, preset)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID задачи")
+		b.sendError(chatID, err)
 		return
 	}
 
-	text := "🗑️ *Удаление задачи*\n\nВы уверены, что хотите удалить эту задачу?"
-	keyboard := getConfirmationKeyboard("delete_task", taskID)
+	keyboard := b.getTaskActionsKeyboard(task.ID, nil, nil)
+	b.sendMessageWithKeyboard(chatID, formatRecurrenceResult(task), keyboard)
+}
+
+// handleAddSubtaskCallback обрабатывает начало добавления подзадачи
+func (b *Bot) handleAddSubtaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	taskID := data.ID
+
+	if err := b.startState(ctx, user.ID, &UserState{
+		Action:   actionAddSubtask,
+		TaskID:   taskID,
+		TaskData: make(map[string]string),
+	}); err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("➕ *Новая подзадача для задачи [%d]*\n\nВведите название подзадачи:", taskID)
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
-// handleNotifyTaskCallback обрабатывает установку напоминания для задачи
-func (b *Bot) handleNotifyTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+// handleToggleSubtaskCallback отмечает пункт чек-листа и обновляет карточку задачи
+func (b *Bot) handleToggleSubtaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	userID := query.From.ID
-	taskIDStr := strings.TrimPrefix(query.Data, "notify_")
-	taskID, err := strconv.Atoi(taskIDStr)
+
+	subtaskID := data.ID
+
+	subtask, err := b.taskService.ToggleSubtask(ctx, subtaskID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID задачи")
+		b.sendError(chatID, err)
 		return
 	}
 
-	// Запускаем интерактивную настройку уведомления
-	b.userStates[userID] = &UserState{
-		Action:   "set_notification",
-		Step:     1,
-		TaskID:   taskID,
-		TaskData: make(map[string]string),
+	parent, err := b.taskService.GetTaskWithSubtasks(ctx, *subtask.ParentID, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
 	}
 
-	text := "⏰ *Настройка напоминания*\n\nВведите время уведомления:\n\n*Примеры:*\n• 15:30 - сегодня в 15:30\n• завтра 10:00\n• 25.12 14:00"
-	keyboard := getBackToMenuKeyboard()
-	b.sendMessageWithKeyboard(chatID, text, keyboard)
+	// Обновляем исходное сообщение, чтобы чек-лист не дублировался в чате
+	b.editTaskMessage(chatID, query.Message.MessageID, parent, user)
+}
+
+// handleRemoveReminderCallback удаляет напоминание и обновляет карточку задачи
+func (b *Bot) handleRemoveReminderCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	reminderID := data.ID
+
+	task, err := b.taskService.RemoveReminder(ctx, reminderID, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.editTaskMessage(chatID, query.Message.MessageID, task, user)
+}
+
+// editTaskMessage заменяет карточку задачи в сообщении актуальной версией
+func (b *Bot) editTaskMessage(chatID int64, messageID int, task *domain.Task, user *domain.User) {
+	loc := b.userLocation(user)
+	keyboard := b.getTaskActionsKeyboard(task.ID, getSubtaskItems(task), getReminderItems(task, loc))
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, b.taskService.FormatTask(task, loc), keyboard)
+	if _, err := b.send(edit); err != nil {
+		b.logger.Error("failed to edit task message", zap.Error(err))
+	}
 }
 
 // handlePriorityCallback обрабатывает выбор приоритета
-func (b *Bot) handlePriorityCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handlePriorityCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
+
 	userID := query.From.ID
-	priority := strings.TrimPrefix(query.Data, "priority_")
+	priority := data.Arg(0)
 
-	if state, exists := b.userStates[userID]; exists && state.Action == "add_task" && state.Step == 3 {
-		state.TaskData["priority"] = priority
+	if state, err := b.getState(ctx, user.ID); err == nil && state != nil && state.Action == actionAddTask && state.Step == 3 {
 		b.handleAddTaskState(ctx, &tgbotapi.Message{
 			Chat: &tgbotapi.Chat{ID: chatID},
 			From: &tgbotapi.User{ID: userID},
@@ -217,104 +399,123 @@ func (b *Bot) handlePriorityCallback(ctx context.Context, query *tgbotapi.Callba
 }
 
 // handleCategoryCallback обрабатывает выбор категории заметки
-func (b *Bot) handleCategoryCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handleCategoryCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	userID := query.From.ID
-	category := strings.TrimPrefix(query.Data, "category_")
 
-	if state, exists := b.userStates[userID]; exists && state.Action == "add_note" && state.Step == 3 {
+	category := data.Arg(0)
+
+	if state, err := b.getState(ctx, user.ID); err == nil && state != nil && state.Action == actionAddNote && state.Step == 3 {
 		state.NoteData["category"] = category
-		state.Step = 4
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
 		text := "4️⃣ Введите теги через запятую (или отправьте \"-\" чтобы пропустить):"
-		keyboard := getBackToMenuKeyboard()
+		keyboard := b.getBackToMenuKeyboard()
 		b.sendMessageWithKeyboard(chatID, text, keyboard)
 	}
 }
 
-// handleConfirmCallback обрабатывает подтверждение действий
-func (b *Bot) handleConfirmCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+// handleConfirmCallback обрабатывает подтверждение действий. Подтверждаемое действие
+// передается первым аргументом кнопки, сущность и ID - в самих данных кнопки.
+func (b *Bot) handleConfirmCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
+
 	userID := query.From.ID
-	data := strings.TrimPrefix(query.Data, "confirm_")
+	confirmed := callbackdata.Action(data.Arg(0))
 
-	parts := strings.Split(data, "_")
-	if len(parts) < 2 {
-		b.sendMessage(chatID, "❌ Неверный формат команды")
-		return
-	}
+	switch {
+	case data.Entity == callbackdata.EntityTask && confirmed == callbackdata.ActionComplete:
+		task, err := b.taskService.CompleteTaskWithSubtasks(ctx, data.ID, user.ID)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 
-	action := parts[0]
-
-	switch action {
-	case "delete":
-		if len(parts) >= 3 && parts[1] == "task" {
-			taskID, err := strconv.Atoi(parts[2])
-			if err != nil {
-				b.sendMessage(chatID, "❌ Неверный ID задачи")
-				return
-			}
-
-			err = b.taskService.DeleteTask(ctx, taskID, user.ID)
-			if err != nil {
-				b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
-				return
-			}
-
-			text := fmt.Sprintf("🗑️ *Задача [%d] удалена!*", taskID)
-			keyboard := tgbotapi.InlineKeyboardMarkup{
-				InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
-					{
-						tgbotapi.InlineKeyboardButton{Text: "📋 К задачам", CallbackData: &[]string{"cmd_tasks"}[0]},
-						tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
-					},
+		text := fmt.Sprintf("✅ *Задача выполнена вместе с подзадачами!*\n\n📌 [%d] %s", task.ID, task.Title)
+		if task.IsRecurring() {
+			text += "\n🔁 Следующее повторение создано"
+		}
+		keyboard := tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					b.button("📋 К задачам", callbackdata.Menu(callbackdata.ActionTasks)),
+					b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 				},
-			}
-			b.sendMessageWithKeyboard(chatID, text, keyboard)
-
-		} else if len(parts) >= 3 && parts[1] == "note" {
-			noteID, err := strconv.Atoi(parts[2])
-			if err != nil {
-				b.sendMessage(chatID, "❌ Неверный ID заметки")
-				return
-			}
-
-			err = b.noteService.DeleteNote(ctx, noteID)
-			if err != nil {
-				b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
-				return
-			}
-
-			text := fmt.Sprintf("🗑️ *Заметка [%d] удалена!*", noteID)
-			keyboard := tgbotapi.InlineKeyboardMarkup{
-				InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
-					{
-						tgbotapi.InlineKeyboardButton{Text: "📝 К заметкам", CallbackData: &[]string{"cmd_notes"}[0]},
-						tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
-					},
+			},
+		}
+		b.sendMessageWithKeyboard(chatID, text, keyboard)
+
+	case data.Entity == callbackdata.EntityTask && confirmed == callbackdata.ActionDelete:
+		if err := b.taskService.DeleteTask(ctx, data.ID, user.ID); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		text := fmt.Sprintf("🗑️ *Задача [%d] удалена!*", data.ID)
+		keyboard := tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					b.button("📋 К задачам", callbackdata.Menu(callbackdata.ActionTasks)),
+					b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
+				},
+			},
+		}
+		b.sendMessageWithKeyboard(chatID, text, keyboard)
+
+	case data.Entity == callbackdata.EntityNote && confirmed == callbackdata.ActionDelete:
+		if err := b.noteService.DeleteNote(ctx, data.ID, user.ID); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		text := fmt.Sprintf("🗑️ *Заметка [%d] удалена!*", data.ID)
+		keyboard := tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					b.button("📝 К заметкам", callbackdata.Menu(callbackdata.ActionNotes)),
+					b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 				},
-			}
-			b.sendMessageWithKeyboard(chatID, text, keyboard)
+			},
 		}
+		b.sendMessageWithKeyboard(chatID, text, keyboard)
 
-	case "logout":
-		err := b.authService.Logout(ctx, userID)
+	case data.Entity == callbackdata.EntitySession && confirmed == callbackdata.ActionLogout:
+		err := b.authService.Logout(ctx, userID, chatID)
 		if err != nil {
 			b.sendMessage(chatID, "❌ Ошибка при выходе")
 			return
 		}
 
 		// Удаляем состояние пользователя
-		delete(b.userStates, userID)
+		b.clearState(ctx, user.ID)
 
 		b.sendMessage(chatID, "👋 Вы вышли из системы. Для повторной авторизации отправьте /start пароль")
+
+	case data.Entity == callbackdata.EntitySession && confirmed == callbackdata.ActionLogoutAll:
+		count, err := b.authService.LogoutEverywhere(ctx, user.ID)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		b.clearState(ctx, user.ID)
+
+		b.sendMessage(chatID, fmt.Sprintf("👋 Завершено сессий во всех чатах: %d. Для повторной авторизации отправьте /start пароль", count))
+
+	default:
+		b.sendMessage(chatID, "❌ Неверный формат команды")
 	}
 }
 
 // handleCancelCallback обрабатывает отмену действий
-func (b *Bot) handleCancelCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCancelCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
 
+	b.clearState(ctx, user.ID)
+
 	text := "❌ *Действие отменено*\n\nВозвращаемся в главное меню:"
-	keyboard := getMainMenuKeyboard()
+	keyboard := b.getMainMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
@@ -2,103 +2,60 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 
+	"todolist/internal/callbackdata"
 	"todolist/internal/domain"
+	"todolist/internal/usecase"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
 )
 
-// handleCallbackQuery обрабатывает callback запросы от inline клавиатур
+// handleCallbackQuery подтверждает получение callback и передает его обработчику кнопки
 func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
 	chatID := query.Message.Chat.ID
-	userID := query.From.ID
-	data := query.Data
 
 	// Подтверждаем получение callback
 	callback := tgbotapi.NewCallback(query.ID, "")
-	b.api.Request(callback)
+	if err := b.request(callback); err != nil {
+		b.logger.Warn("failed to answer callback", zap.Error(err))
+	}
 
-	// Получаем пользователя
-	user, err := b.getUserFromTelegram(ctx, userID)
+	data, err := b.callbacks.Decode(query.Data)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
+		b.logger.Warn("invalid callback data",
+			zap.Int64("telegram_id", query.From.ID),
+			zap.String("data", query.Data),
+			zap.Error(err))
+		b.sendMessageWithKeyboard(chatID, "❓ Кнопка устарела. Выберите действие в главном меню:", b.getMainMenuKeyboard())
 		return
 	}
 
-	// Обрабатываем команды
-	switch {
-	case data == "cmd_menu":
-		b.handleMenuCallback(ctx, chatID, userID)
-	case data == "cmd_tasks":
-		b.handleTasksCallback(ctx, chatID, userID)
-	case data == "cmd_add_task":
-		b.handleAddTaskCallback(ctx, chatID, userID)
-	case data == "cmd_notes":
-		b.handleNotesCallback(ctx, chatID, userID)
-	case data == "cmd_add_note":
-		b.handleAddNoteCallback(ctx, chatID, userID)
-	case data == "cmd_pending":
-		b.handlePendingCallback(ctx, chatID, userID)
-	case data == "cmd_completed":
-		b.handleCompletedCallback(ctx, chatID, userID)
-	case data == "cmd_search":
-		b.handleSearchCallback(ctx, chatID, userID)
-	case data == "cmd_favorites":
-		b.handleFavoritesCallback(ctx, chatID, userID)
-	case data == "cmd_help":
-		b.handleHelpCallback(ctx, chatID)
-	case data == "cmd_logout":
-		b.handleLogoutCallback(ctx, chatID, userID)
-	case strings.HasPrefix(data, "complete_"):
-		b.handleCompleteTaskCallback(ctx, query, user)
-	case strings.HasPrefix(data, "show_"):
-		b.handleShowTaskCallback(ctx, query, user)
-	case strings.HasPrefix(data, "delete_"):
-		b.handleDeleteTaskCallback(ctx, query, user)
-	case strings.HasPrefix(data, "notify_"):
-		b.handleNotifyTaskCallback(ctx, query, user)
-	case strings.HasPrefix(data, "show_note_"):
-		b.handleShowNoteCallback(ctx, query, user)
-	case strings.HasPrefix(data, "delete_note_"):
-		b.handleDeleteNoteCallback(ctx, query, user)
-	case strings.HasPrefix(data, "favorite_add_"):
-		b.handleAddFavoriteCallback(ctx, query, user)
-	case strings.HasPrefix(data, "favorite_remove_"):
-		b.handleRemoveFavoriteCallback(ctx, query, user)
-	case strings.HasPrefix(data, "priority_"):
-		b.handlePriorityCallback(ctx, query, user)
-	case strings.HasPrefix(data, "category_"):
-		b.handleCategoryCallback(ctx, query, user)
-	case strings.HasPrefix(data, "confirm_"):
-		b.handleConfirmCallback(ctx, query, user)
-	case strings.HasPrefix(data, "cancel_"):
-		b.handleCancelCallback(ctx, query)
-	default:
+	if !b.router.handleCallback(ctx, query, data) {
 		b.sendMessage(chatID, "❓ Неизвестная команда")
 	}
 }
 
-// handleMenuCallback обрабатывает возврат в главное меню
-func (b *Bot) handleMenuCallback(ctx context.Context, chatID, userID int64) {
+// handleMenuCallback обрабатывает возврат в главное меню, прерывая незаконченный диалог
+func (b *Bot) handleMenuCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	b.clearState(ctx, user.ID)
+
 	text := "🏠 *Главное меню*\n\nВыберите действие:"
-	keyboard := getMainMenuKeyboard()
+	keyboard := b.getMainMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleTasksCallback обрабатывает показ списка задач
-func (b *Bot) handleTasksCallback(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleTasksCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
 
 	tasks, err := b.taskService.GetTasks(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -107,10 +64,10 @@ func (b *Bot) handleTasksCallback(ctx context.Context, chatID, userID int64) {
 		keyboard := tgbotapi.InlineKeyboardMarkup{
 			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 				{
-					tgbotapi.InlineKeyboardButton{Text: "➕ Создать задачу", CallbackData: &[]string{"cmd_add_task"}[0]},
+					b.button("➕ Создать задачу", callbackdata.Menu(callbackdata.ActionAddTask)),
 				},
 				{
-					tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+					b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 				},
 			},
 		}
@@ -128,34 +85,34 @@ func (b *Bot) handleTasksCallback(ctx context.Context, chatID, userID int64) {
 	}
 
 	text := fmt.Sprintf("📋 *Ваши задачи* (%d)\n\nВыберите задачу для выполнения действий:", len(tasks))
-	keyboard := getTaskListKeyboard(taskItems)
+	keyboard := b.getTaskListKeyboard(taskItems)
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleAddTaskCallback обрабатывает начало создания задачи
-func (b *Bot) handleAddTaskCallback(ctx context.Context, chatID, userID int64) {
-	b.userStates[userID] = &UserState{
-		Action:   "add_task",
-		Step:     1,
+func (b *Bot) handleAddTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	if err := b.startState(ctx, user.ID, &UserState{
+		Action:   actionAddTask,
 		TaskData: make(map[string]string),
+	}); err != nil {
+		b.sendError(chatID, err)
+		return
 	}
 
 	text := "📝 *Создание новой задачи*\n\n1️⃣ Введите название задачи:"
-	keyboard := getBackToMenuKeyboard()
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleNotesCallback обрабатывает показ списка заметок
-func (b *Bot) handleNotesCallback(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleNotesCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
 
 	notes, err := b.noteService.GetUserNotes(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения заметок: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -164,10 +121,10 @@ func (b *Bot) handleNotesCallback(ctx context.Context, chatID, userID int64) {
 		keyboard := tgbotapi.InlineKeyboardMarkup{
 			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 				{
-					tgbotapi.InlineKeyboardButton{Text: "📄 Создать заметку", CallbackData: &[]string{"cmd_add_note"}[0]},
+					b.button("📄 Создать заметку", callbackdata.Menu(callbackdata.ActionAddNote)),
 				},
 				{
-					tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+					b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 				},
 			},
 		}
@@ -186,45 +143,52 @@ func (b *Bot) handleNotesCallback(ctx context.Context, chatID, userID int64) {
 	}
 
 	text := fmt.Sprintf("📝 *Ваши заметки* (%d)\n\nВыберите заметку для просмотра:", len(notes))
-	keyboard := getNoteListKeyboard(noteItems)
+	keyboard := b.getNoteListKeyboard(noteItems)
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleAddNoteCallback обрабатывает начало создания заметки
-func (b *Bot) handleAddNoteCallback(ctx context.Context, chatID, userID int64) {
-	b.userStates[userID] = &UserState{
-		Action:   "add_note",
-		Step:     1,
+func (b *Bot) handleAddNoteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	if err := b.startState(ctx, user.ID, &UserState{
+		Action:   actionAddNote,
 		NoteData: make(map[string]string),
+	}); err != nil {
+		b.sendError(chatID, err)
+		return
 	}
 
 	text := "📄 *Создание новой заметки*\n\n1️⃣ Введите заголовок заметки:"
-	keyboard := getBackToMenuKeyboard()
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleCompleteTaskCallback обрабатывает завершение задачи
-func (b *Bot) handleCompleteTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handleCompleteTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	taskIDStr := strings.TrimPrefix(query.Data, "complete_")
-	taskID, err := strconv.Atoi(taskIDStr)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID задачи")
-		return
-	}
+
+	taskID := data.ID
 
 	task, err := b.taskService.CompleteTask(ctx, taskID, user.ID)
+	if errors.Is(err, usecase.ErrOpenSubtasks) {
+		b.sendOpenSubtasksConfirmation(chatID, taskID)
+		return
+	}
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
 	text := fmt.Sprintf("✅ *Задача выполнена!*\n\n📌 [%d] %s", task.ID, task.Title)
+	if task.IsRecurring() {
+		text += "\n🔁 Следующее повторение создано"
+	}
 	keyboard := tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "📋 К задачам", CallbackData: &[]string{"cmd_tasks"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+				b.button("📋 К задачам", callbackdata.Menu(callbackdata.ActionTasks)),
+				b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 			},
 		},
 	}
@@ -232,80 +196,71 @@ func (b *Bot) handleCompleteTaskCallback(ctx context.Context, query *tgbotapi.Ca
 }
 
 // handleShowTaskCallback обрабатывает показ детальной информации о задаче
-func (b *Bot) handleShowTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, user *domain.User) {
+func (b *Bot) handleShowTaskCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
 	chatID := query.Message.Chat.ID
-	taskIDStr := strings.TrimPrefix(query.Data, "show_")
-	taskID, err := strconv.Atoi(taskIDStr)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Неверный ID задачи")
-		return
-	}
 
-	task, err := b.taskService.GetTaskByID(ctx, taskID, user.ID)
+	taskID := data.ID
+
+	task, err := b.taskService.GetTaskWithSubtasks(ctx, taskID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	text := b.taskService.FormatTask(task)
-	keyboard := getTaskActionsKeyboard(taskID)
+	loc := b.userLocation(user)
+	text := b.taskService.FormatTask(task, loc)
+	keyboard := b.getTaskActionsKeyboard(taskID, getSubtaskItems(task), getReminderItems(task, loc))
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handlePendingCallback обрабатывает показ активных задач
-func (b *Bot) handlePendingCallback(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handlePendingCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
 
 	tasks, err := b.taskService.GetTasksByStatus(ctx, user.ID, domain.TaskStatusPending)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
 	if len(tasks) == 0 {
 		text := "⏰ Нет активных задач\n\nВсе задачи выполнены! 🎉"
-		keyboard := getBackToMenuKeyboard()
+		keyboard := b.getBackToMenuKeyboard()
 		b.sendMessageWithKeyboard(chatID, text, keyboard)
 		return
 	}
 
-	text := fmt.Sprintf("⏰ *Активные задачи* (%d)\n\n%s", len(tasks), b.taskService.FormatTaskList(tasks))
-	keyboard := getBackToMenuKeyboard()
+	text := fmt.Sprintf("⏰ *Активные задачи* (%d)\n\n%s", len(tasks), b.taskService.FormatTaskList(tasks, b.userLocation(user)))
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleCompletedCallback обрабатывает показ выполненных задач
-func (b *Bot) handleCompletedCallback(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleCompletedCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
 
 	tasks, err := b.taskService.GetTasksByStatus(ctx, user.ID, domain.TaskStatusCompleted)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
 	if len(tasks) == 0 {
 		text := "✅ Нет выполненных задач\n\nПора взяться за дело! 💪"
-		keyboard := getBackToMenuKeyboard()
+		keyboard := b.getBackToMenuKeyboard()
 		b.sendMessageWithKeyboard(chatID, text, keyboard)
 		return
 	}
 
-	text := fmt.Sprintf("✅ *Выполненные задачи* (%d)\n\n%s", len(tasks), b.taskService.FormatTaskList(tasks))
-	keyboard := getBackToMenuKeyboard()
+	text := fmt.Sprintf("✅ *Выполненные задачи* (%d)\n\n%s", len(tasks), b.taskService.FormatTaskList(tasks, b.userLocation(user)))
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
 
 // handleHelpCallback обрабатывает показ справки
-func (b *Bot) handleHelpCallback(ctx context.Context, chatID int64) {
+func (b *Bot) handleHelpCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
 	helpText := `❓ *Справка по командам*
 
 🤖 *Основные функции:*
@@ -329,13 +284,43 @@ func (b *Bot) handleHelpCallback(ctx context.Context, chatID int64) {
 • Все ваши данные сохраняются автоматически
 • Бот поддерживает различные типы файлов`
 
-	keyboard := getBackToMenuKeyboard()
+	keyboard := b.getBackToMenuKeyboard()
 	b.sendMessageWithKeyboard(chatID, helpText, keyboard)
 }
 
 // handleLogoutCallback обрабатывает выход из системы
-func (b *Bot) handleLogoutCallback(ctx context.Context, chatID, userID int64) {
-	keyboard := getConfirmationKeyboard("logout", 0)
+func (b *Bot) handleLogoutCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	keyboard := b.getConfirmationKeyboard(callbackdata.EntitySession, callbackdata.ActionLogout, 0)
 	text := "🚪 *Выход из системы*\n\nВы уверены, что хотите выйти?"
 	b.sendMessageWithKeyboard(chatID, text, keyboard)
 }
+
+// handleLogoutAllCallback запрашивает подтверждение выхода во всех чатах
+func (b *Bot) handleLogoutAllCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	keyboard := b.getConfirmationKeyboard(callbackdata.EntitySession, callbackdata.ActionLogoutAll, 0)
+	text := "🚪 *Выход везде*\n\nВсе ваши сессии во всех чатах будут завершены. Продолжить?"
+	b.sendMessageWithKeyboard(chatID, text, keyboard)
+}
+
+// handleRevokeSessionCallback завершает сессию пользователя в другом чате
+func (b *Bot) handleRevokeSessionCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User) {
+	chatID := query.Message.Chat.ID
+
+	if err := b.authService.RevokeSession(ctx, user.ID, int64(data.ID)); err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ Сессия [%d] завершена", data.ID))
+}
+
+// sendOpenSubtasksConfirmation предлагает завершить задачу вместе с открытыми подзадачами
+func (b *Bot) sendOpenSubtasksConfirmation(chatID int64, taskID int) {
+	text := fmt.Sprintf("☑️ *У задачи [%d] есть невыполненные подзадачи*\n\nЗавершить задачу вместе со всеми подзадачами?", taskID)
+	keyboard := b.getConfirmationKeyboard(callbackdata.EntityTask, callbackdata.ActionComplete, taskID)
+	b.sendMessageWithKeyboard(chatID, text, keyboard)
+}
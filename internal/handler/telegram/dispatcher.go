@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// updateDispatcher распределяет обновления между фиксированным числом воркеров.
+// Обновления одного пользователя всегда попадают в очередь одного воркера и обрабатываются
+// по порядку, поэтому шаг диалога не может выполниться раньше предыдущего.
+// Когда очередь воркера заполнена, Dispatch ждет: чтение новых обновлений из Telegram
+// приостанавливается, а не порождает неограниченное число горутин.
+// После Shutdown Dispatch отклоняет обновления: HTTP-обработчик webhook, не завершившийся
+// за время остановки сервера, получит false, а не запись в закрытую очередь.
+type updateDispatcher struct {
+	queues []chan tgbotapi.Update
+	handle func(ctx context.Context, update tgbotapi.Update)
+	logger *zap.Logger
+
+	// mu защищает закрытие очередей: Dispatch пишет в очередь под RLock,
+	// Shutdown закрывает очереди под Lock. stopping закрывается раньше, чтобы разбудить
+	// Dispatch, ждущие места в очереди, и освободить RLock
+	mu       sync.RWMutex
+	closed   bool
+	stopping chan struct{}
+	stopOnce sync.Once
+
+	// ctx передается обработчикам. Он не отменяется вместе с контекстом бота,
+	// чтобы начатые обновления при остановке завершились, а не оборвались на середине
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newUpdateDispatcher запускает workers воркеров с очередью queueSize обновлений у каждого
+func newUpdateDispatcher(
+	parent context.Context,
+	workers, queueSize int,
+	handle func(ctx context.Context, update tgbotapi.Update),
+	logger *zap.Logger,
+) *updateDispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	d := &updateDispatcher{
+		queues:   make([]chan tgbotapi.Update, workers),
+		handle:   handle,
+		logger:   logger,
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, queueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+
+	return d
+}
+
+// Dispatch ставит обновление в очередь воркера пользователя.
+// Блокируется, пока в очереди нет места; возвращает false, если ctx отменен раньше
+// или диспетчер остановлен.
+func (d *updateDispatcher) Dispatch(ctx context.Context, update tgbotapi.Update) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return false
+	}
+
+	queue := d.queues[shardKey(update)%uint64(len(d.queues))]
+
+	select {
+	case queue <- update:
+		return true
+	default:
+	}
+
+	d.logger.Warn("update queue is full, waiting", zap.Int("update_id", update.UpdateID))
+
+	select {
+	case queue <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-d.stopping:
+		return false
+	}
+}
+
+// Shutdown закрывает очереди и ждет, пока воркеры обработают уже принятые обновления.
+// Если они не успевают за timeout, контекст обработчиков отменяется. Повторный вызов ничего не делает.
+func (d *updateDispatcher) Shutdown(timeout time.Duration) {
+	first := false
+	d.stopOnce.Do(func() {
+		first = true
+		close(d.stopping)
+	})
+	if !first {
+		return
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		d.logger.Warn("update handlers did not finish in time, cancelling", zap.Duration("timeout", timeout))
+		d.cancel()
+		<-done
+	}
+
+	d.cancel()
+}
+
+// work обрабатывает обновления из очереди по одному, пока очередь не закрыта
+func (d *updateDispatcher) work(queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+
+	for update := range queue {
+		d.handle(d.ctx, update)
+	}
+}
+
+// shardKey возвращает ключ, по которому обновление закрепляется за воркером:
+// Telegram ID отправителя, а для обновлений без отправителя - ID чата
+func shardKey(update tgbotapi.Update) uint64 {
+	if user := update.SentFrom(); user != nil {
+		return uint64(user.ID)
+	}
+	if chat := update.FromChat(); chat != nil {
+		return uint64(chat.ID)
+	}
+	return 0
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// TestDispatcherKeepsUserOrder проверяет, что обновления одного пользователя обрабатываются
+// в порядке поступления, даже когда воркеров несколько, а Shutdown дожидается принятых обновлений
+func TestDispatcherKeepsUserOrder(t *testing.T) {
+	const users, perUser = 5, 50
+
+	var mu sync.Mutex
+	handled := make(map[int64][]int)
+	dispatcher := newUpdateDispatcher(context.Background(), 4, 2, func(ctx context.Context, update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		userID := update.SentFrom().ID
+		handled[userID] = append(handled[userID], update.UpdateID)
+	}, zap.NewNop())
+
+	for i := 0; i < perUser; i++ {
+		for userID := int64(1); userID <= users; userID++ {
+			update := tgbotapi.Update{
+				UpdateID: i,
+				Message:  &tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: userID}},
+			}
+			if !dispatcher.Dispatch(context.Background(), update) {
+				t.Fatalf("update %d of user %d was not accepted", i, userID)
+			}
+		}
+	}
+	dispatcher.Shutdown(time.Second)
+
+	for userID := int64(1); userID <= users; userID++ {
+		ids := handled[userID]
+		if len(ids) != perUser {
+			t.Fatalf("user %d: handled %d updates, want %d", userID, len(ids), perUser)
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("user %d: update %d handled at position %d", userID, id, i)
+			}
+		}
+	}
+}
+
+// TestDispatcherShutdownRejectsUpdates проверяет, что после остановки Dispatch
+// отклоняет обновления, а не пишет в закрытую очередь
+func TestDispatcherShutdownRejectsUpdates(t *testing.T) {
+	dispatcher := newUpdateDispatcher(context.Background(), 2, 1, func(ctx context.Context, update tgbotapi.Update) {}, zap.NewNop())
+	dispatcher.Shutdown(time.Second)
+
+	if dispatcher.Dispatch(context.Background(), tgbotapi.Update{UpdateID: 1}) {
+		t.Fatal("Dispatch after Shutdown accepted the update")
+	}
+
+	// Повторная остановка не закрывает очереди второй раз
+	dispatcher.Shutdown(time.Second)
+}
+
+// TestDispatcherShutdownReleasesWaiting проверяет, что Dispatch, ждущий места в очереди
+// (например, запрос webhook, не завершившийся за время остановки сервера), получает false
+func TestDispatcherShutdownReleasesWaiting(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	dispatcher := newUpdateDispatcher(context.Background(), 1, 0, func(ctx context.Context, update tgbotapi.Update) {
+		close(started)
+		<-release
+	}, zap.NewNop())
+
+	if !dispatcher.Dispatch(context.Background(), tgbotapi.Update{UpdateID: 1}) {
+		t.Fatal("first update was not accepted")
+	}
+	<-started
+
+	result := make(chan bool)
+	go func() {
+		result <- dispatcher.Dispatch(context.Background(), tgbotapi.Update{UpdateID: 2})
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		dispatcher.Shutdown(time.Second)
+		close(stopped)
+	}()
+
+	select {
+	case accepted := <-result:
+		if accepted {
+			t.Fatal("waiting Dispatch accepted the update during Shutdown")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not release the waiting Dispatch")
+	}
+
+	close(release)
+	<-stopped
+}
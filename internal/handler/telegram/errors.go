@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"todolist/internal/domain"
+)
+
+// notFoundMessages - сообщения о ненайденных сущностях.
+// Чужие сущности показываются как несуществующие, чтобы не раскрывать их наличие.
+var notFoundMessages = map[domain.Entity]string{
+	domain.EntityUser:     "❌ Пользователь не найден",
+	domain.EntitySession:  "🔐 Сессия не найдена. Авторизуйтесь через /start",
+	domain.EntityTask:     "❌ Задача не найдена",
+	domain.EntityReminder: "❌ Напоминание не найдено",
+	domain.EntityNote:     "❌ Заметка не найдена",
+	domain.EntityInvite:   "❌ Приглашение не найдено или истекло",
+}
+
+// errorMessage переводит ошибку сервиса в сообщение для пользователя.
+// Все тексты ошибок формируются здесь: сервисы и репозитории возвращают типизированные ошибки из domain.
+func errorMessage(err error) string {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return "❌ " + capitalize(validationErr.Message)
+	}
+
+	var rateLimitErr *domain.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		minutes := int(math.Ceil(time.Until(rateLimitErr.Until).Minutes()))
+		if minutes < 1 {
+			minutes = 1
+		}
+		return fmt.Sprintf("⏳ Слишком много неудачных попыток входа. Попробуйте через %d мин", minutes)
+	}
+
+	var entityErr *domain.EntityError
+	if errors.As(err, &entityErr) {
+		switch {
+		case entityErr.Reason != "":
+			return "❌ " + capitalize(entityErr.Reason)
+		case errors.Is(entityErr, domain.ErrNotFound), errors.Is(entityErr, domain.ErrForbidden):
+			if msg, ok := notFoundMessages[entityErr.Entity]; ok {
+				return msg
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrForbidden):
+		return "❌ Не найдено"
+	case errors.Is(err, domain.ErrConflict):
+		return "❌ Такая запись уже существует или была изменена. Обновите данные и попробуйте еще раз"
+	case errors.Is(err, domain.ErrValidation):
+		return "❌ Некорректные данные"
+	case errors.Is(err, domain.ErrRateLimited):
+		return "⏳ Слишком много попыток. Попробуйте позже"
+	default:
+		return "❌ Произошла ошибка. Попробуйте позже"
+	}
+}
+
+// isExpectedError проверяет, описывает ли ошибка ожидаемую ситуацию, а не сбой
+func isExpectedError(err error) bool {
+	return errors.Is(err, domain.ErrNotFound) ||
+		errors.Is(err, domain.ErrForbidden) ||
+		errors.Is(err, domain.ErrConflict) ||
+		errors.Is(err, domain.ErrValidation) ||
+		errors.Is(err, domain.ErrRateLimited)
+}
+
+// sendError отправляет пользователю сообщение об ошибке.
+// Непредвиденные ошибки дополнительно записываются в лог.
+func (b *Bot) sendError(chatID int64, err error) {
+	if !isExpectedError(err) {
+		b.logger.Error("request failed", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+	b.sendMessage(chatID, errorMessage(err))
+}
+
+// capitalize делает первую букву сообщения заглавной
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
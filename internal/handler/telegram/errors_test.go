@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"todolist/internal/domain"
+	"todolist/internal/usecase"
+)
+
+// TestErrorMessageWrapped проверяет, что типизированные ошибки доходят до пользователя
+// через обертки сервисов и репозиториев
+func TestErrorMessageWrapped(t *testing.T) {
+	openSubtasks := fmt.Errorf("%w: %w", usecase.ErrOpenSubtasks,
+		domain.Conflict(domain.EntityTask, 42, "у задачи есть невыполненные подзадачи"))
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "validation",
+			err:  fmt.Errorf("ошибка установки срока: %w", domain.NewValidationError("due_at", "срок уже прошел")),
+			want: "❌ Срок уже прошел",
+		},
+		{
+			name: "not found",
+			err:  fmt.Errorf("ошибка получения задачи: %w", domain.NotFound(domain.EntityTask, 7)),
+			want: "❌ Задача не найдена",
+		},
+		{
+			name: "conflict reason",
+			err:  fmt.Errorf("ошибка: %w", openSubtasks),
+			want: "❌ У задачи есть невыполненные подзадачи",
+		},
+		{
+			name: "expired session",
+			err:  domain.NotFound(domain.EntitySession, 1),
+			want: "🔐 Сессия не найдена. Авторизуйтесь через /start",
+		},
+		{
+			name: "unknown",
+			err:  errors.New("connection refused"),
+			want: "❌ Произошла ошибка. Попробуйте позже",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorMessage(tt.err); got != tt.want {
+				t.Errorf("errorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Действия многошаговых диалогов
+const (
+	actionAddTask         = "add_task"
+	actionAddNote         = "add_note"
+	actionSetNotification = "set_notification"
+	actionSetRecurrence   = "set_recurrence"
+	actionAddSubtask      = "add_subtask"
+	actionSnoozeCustom    = "snooze_custom"
+	actionSetTimezone     = "set_timezone"
+	actionSearchNotes     = "search_notes"
+)
+
+// conversationFlows объявляет шаги каждого диалога. Диалог начинается с шага 1,
+// переходит только на следующий шаг и завершается clearState на любом шаге.
+var conversationFlows = map[string][]string{
+	actionAddTask:         {"title", "description", "priority"},
+	actionAddNote:         {"title", "content", "category", "tags"},
+	actionSetNotification: {"task_id", "time"},
+	actionSetRecurrence:   {"rule"},
+	actionAddSubtask:      {"title"},
+	actionSnoozeCustom:    {"time"},
+	actionSetTimezone:     {"zone"},
+	actionSearchNotes:     {"query"},
+}
+
+// errStateExpired возвращается getState, если пользователь не ответил за BOT_STATE_TIMEOUT
+var errStateExpired = errors.New("conversation state expired")
+
+// UserState хранит состояние пользователя для многошаговых операций.
+// Состояния хранятся по внутреннему ID пользователя (users.id), а не по Telegram ID.
+// Обработчик получает свою копию состояния: изменения нужно сохранить через advanceState или saveState.
+type UserState struct {
+	Action    string            `json:"-"`
+	Step      int               `json:"-"`
+	TaskID    int               `json:"task_id,omitempty"`
+	NoteID    int               `json:"note_id,omitempty"`
+	MessageID int               `json:"message_id,omitempty"`
+	TaskData  map[string]string `json:"task_data,omitempty"`
+	NoteData  map[string]string `json:"note_data,omitempty"`
+}
+
+// getState возвращает состояние пользователя или nil, если диалога нет.
+// Истекшее состояние удаляется, а вызывающий код получает errStateExpired.
+func (b *Bot) getState(ctx context.Context, userID int64) (*UserState, error) {
+	saved, err := b.states.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get conversation state: %w", err)
+	}
+
+	if saved.IsExpired(time.Now()) {
+		b.clearState(ctx, userID)
+		return nil, errStateExpired
+	}
+
+	if _, ok := conversationFlows[saved.Action]; !ok {
+		b.logger.Warn("unknown conversation action, state dropped",
+			zap.Int64("user_id", userID),
+			zap.String("action", saved.Action))
+		b.clearState(ctx, userID)
+		return nil, nil
+	}
+
+	state := &UserState{Action: saved.Action, Step: saved.Step}
+	if len(saved.Data) > 0 {
+		if err := json.Unmarshal(saved.Data, state); err != nil {
+			return nil, fmt.Errorf("failed to decode conversation state: %w", err)
+		}
+	}
+	if state.TaskData == nil {
+		state.TaskData = make(map[string]string)
+	}
+	if state.NoteData == nil {
+		state.NoteData = make(map[string]string)
+	}
+
+	return state, nil
+}
+
+// startState начинает диалог с первого шага, заменяя незаконченный
+func (b *Bot) startState(ctx context.Context, userID int64, state *UserState) error {
+	if _, ok := conversationFlows[state.Action]; !ok {
+		return fmt.Errorf("unknown conversation action %q", state.Action)
+	}
+
+	state.Step = 1
+	return b.saveState(ctx, userID, state)
+}
+
+// advanceState переводит диалог на следующий шаг и сохраняет данные, собранные на текущем
+func (b *Bot) advanceState(ctx context.Context, userID int64, state *UserState) error {
+	if state.Step >= len(conversationFlows[state.Action]) {
+		return fmt.Errorf("conversation %q has no step after %d", state.Action, state.Step)
+	}
+
+	state.Step++
+	return b.saveState(ctx, userID, state)
+}
+
+// saveState сохраняет состояние и продлевает время ожидания ответа
+func (b *Bot) saveState(ctx context.Context, userID int64, state *UserState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation state: %w", err)
+	}
+
+	now := time.Now()
+	saved := &domain.ConversationState{
+		UserID:    userID,
+		Action:    state.Action,
+		Step:      state.Step,
+		Data:      data,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(b.config.Bot.StateTimeout),
+	}
+
+	if err := b.states.Save(ctx, saved); err != nil {
+		return fmt.Errorf("failed to save conversation state: %w", err)
+	}
+
+	return nil
+}
+
+// clearState завершает диалог пользователя
+func (b *Bot) clearState(ctx context.Context, userID int64) {
+	if err := b.states.Delete(ctx, userID); err != nil {
+		b.logger.Error("failed to clear conversation state", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// handleCancelCommand обрабатывает команду /cancel: прерывает текущий диалог
+func (b *Bot) handleCancelCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	state, err := b.getState(ctx, user.ID)
+	if err != nil && !errors.Is(err, errStateExpired) {
+		b.sendError(chatID, err)
+		return
+	}
+
+	if state == nil {
+		b.sendMessage(chatID, "🤷 Нечего отменять")
+		return
+	}
+
+	b.clearState(ctx, user.ID)
+	b.sendMessageWithKeyboard(chatID, "❌ Действие отменено\n\nВыберите действие в главном меню:", b.getMainMenuKeyboard())
+}
@@ -2,8 +2,8 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -11,118 +11,98 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"todolist/internal/domain"
+	"todolist/internal/timeparse"
+	"todolist/internal/usecase"
 )
 
 // handleUserState обрабатывает состояния пользователя для многошаговых операций
-func (b *Bot) handleUserState(ctx context.Context, message *tgbotapi.Message, state *UserState) {
+func (b *Bot) handleUserState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	switch state.Action {
-	case "add_task":
+	case actionAddTask:
 		b.handleAddTaskState(ctx, message, user, state)
-	case "add_note":
+	case actionAddNote:
 		b.handleAddNoteState(ctx, message, user, state)
-	case "set_notification":
+	case actionSetNotification:
 		b.handleSetNotificationState(ctx, message, user, state)
+	case actionSetRecurrence:
+		b.handleSetRecurrenceState(ctx, message, user, state)
+	case actionAddSubtask:
+		b.handleAddSubtaskState(ctx, message, user, state)
+	case actionSnoozeCustom:
+		b.handleSnoozeCustomState(ctx, message, user, state)
+	case actionSetTimezone:
+		b.handleSetTimezoneState(ctx, message, user, state)
+	case actionSearchNotes:
+		b.handleSearchNotesState(ctx, message, user, state)
 	default:
-		delete(b.userStates, userID)
+		b.clearState(ctx, user.ID)
 		b.sendMessage(chatID, "❌ Неизвестное состояние. Попробуйте еще раз.")
 	}
 }
 
 // handleCreateTaskFromText создает задачу из произвольного текста
-func (b *Bot) handleCreateTaskFromText(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleCreateTaskFromText(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
-	title := strings.TrimSpace(message.Text)
-	if title == "" {
+	text := strings.TrimSpace(message.Text)
+	if text == "" {
 		b.sendMessage(chatID, "❌ Название задачи не может быть пустым")
 		return
 	}
 
-	task, err := b.taskService.CreateTask(ctx, user.ID, title, "", domain.TaskPriorityMedium)
+	b.quickAddTask(ctx, chatID, user, text)
+}
+
+// quickAddTask создает задачу из строки с маркерами быстрого добавления
+func (b *Bot) quickAddTask(ctx context.Context, chatID int64, user *domain.User, text string) {
+	task, err := b.taskService.QuickAddTask(ctx, user.ID, text)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания задачи: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] создана!\n📌 %s", task.ID, task.Title))
+	b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] создана!\n%s", task.ID, b.taskService.FormatTask(task, b.userLocation(user))))
 }
 
 // handleListTasksCommand обрабатывает команду /tasks
-func (b *Bot) handleListTasksCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleListTasksCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
 
 	tasks, err := b.taskService.GetTasks(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	b.sendMessage(chatID, b.taskService.FormatTaskList(tasks))
+	b.sendMessage(chatID, b.taskService.FormatTaskList(tasks, b.userLocation(user)))
 }
 
 // handleAddTaskCommand обрабатывает команду /add
-func (b *Bot) handleAddTaskCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleAddTaskCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
 		// Запускаем интерактивное создание задачи
-		b.userStates[userID] = &UserState{
-			Action:   "add_task",
-			Step:     1,
+		if err := b.startState(ctx, user.ID, &UserState{
+			Action:   actionAddTask,
 			TaskData: make(map[string]string),
+		}); err != nil {
+			b.sendError(chatID, err)
+			return
 		}
 		b.sendMessage(chatID, "📝 Создание новой задачи\n\n1️⃣ Введите название задачи:")
 		return
 	}
 
-	title := strings.Join(args[1:], " ")
-	task, err := b.taskService.CreateTask(ctx, user.ID, title, "", domain.TaskPriorityMedium)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания задачи: %s", err.Error()))
-		return
-	}
-
-	b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] создана!\n📌 %s", task.ID, task.Title))
+	b.quickAddTask(ctx, chatID, user, message.CommandArguments())
 }
 
 // handleCompleteTaskCommand обрабатывает команду /complete
-func (b *Bot) handleCompleteTaskCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleCompleteTaskCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -136,25 +116,32 @@ func (b *Bot) handleCompleteTaskCommand(ctx context.Context, message *tgbotapi.M
 		return
 	}
 
-	task, err := b.taskService.CompleteTask(ctx, taskID, user.ID)
+	// "/complete ID force" завершает задачу вместе с невыполненными подзадачами
+	var task *domain.Task
+	if len(args) > 2 && args[2] == "force" {
+		task, err = b.taskService.CompleteTaskWithSubtasks(ctx, taskID, user.ID)
+	} else {
+		task, err = b.taskService.CompleteTask(ctx, taskID, user.ID)
+	}
+	if errors.Is(err, usecase.ErrOpenSubtasks) {
+		b.sendOpenSubtasksConfirmation(chatID, taskID)
+		return
+	}
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] выполнена!\n📌 %s", task.ID, task.Title))
+	text := fmt.Sprintf("✅ Задача [%d] выполнена!\n📌 %s", task.ID, task.Title)
+	if task.IsRecurring() {
+		text += "\n🔁 Следующее повторение создано"
+	}
+	b.sendMessage(chatID, text)
 }
 
 // handleDeleteTaskCommand обрабатывает команду /delete
-func (b *Bot) handleDeleteTaskCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleDeleteTaskCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -170,7 +157,7 @@ func (b *Bot) handleDeleteTaskCommand(ctx context.Context, message *tgbotapi.Mes
 
 	err = b.taskService.DeleteTask(ctx, taskID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -178,15 +165,8 @@ func (b *Bot) handleDeleteTaskCommand(ctx context.Context, message *tgbotapi.Mes
 }
 
 // handleShowTaskCommand обрабатывает команду /show
-func (b *Bot) handleShowTaskCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleShowTaskCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -200,26 +180,49 @@ func (b *Bot) handleShowTaskCommand(ctx context.Context, message *tgbotapi.Messa
 		return
 	}
 
-	task, err := b.taskService.GetTaskByID(ctx, taskID, user.ID)
+	task, err := b.taskService.GetTaskWithSubtasks(ctx, taskID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	b.sendMessage(chatID, b.taskService.FormatTask(task))
+	loc := b.userLocation(user)
+	keyboard := b.getTaskActionsKeyboard(task.ID, getSubtaskItems(task), getReminderItems(task, loc))
+	b.sendMessageWithKeyboard(chatID, b.taskService.FormatTask(task, loc), keyboard)
 }
 
-// handlePendingTasksCommand обрабатывает команду /pending
-func (b *Bot) handlePendingTasksCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
+// handleAddSubtaskCommand обрабатывает команду /sub
+func (b *Bot) handleAddSubtaskCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.Text)
+	if len(args) < 3 {
+		b.sendMessage(chatID, "❌ Укажите ID задачи и название подзадачи: /sub 123 Купить хлеб")
+		return
+	}
+
+	parentID, err := strconv.Atoi(args[1])
+	if err != nil {
+		b.sendMessage(chatID, "❌ Неверный ID задачи")
+		return
+	}
+
+	subtask, err := b.taskService.AddSubtask(ctx, parentID, user.ID, strings.Join(args[2:], " "))
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
+		b.sendError(chatID, err)
 		return
 	}
 
+	b.sendMessage(chatID, fmt.Sprintf("✅ Подзадача [%d] добавлена к задаче [%d]\n☑️ %s", subtask.ID, parentID, subtask.Title))
+}
+
+// handlePendingTasksCommand обрабатывает команду /pending
+func (b *Bot) handlePendingTasksCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
 	tasks, err := b.taskService.GetTasksByStatus(ctx, user.ID, domain.TaskStatusPending)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -228,53 +231,112 @@ func (b *Bot) handlePendingTasksCommand(ctx context.Context, chatID, userID int6
 		return
 	}
 
-	message := "⏳ Невыполненные задачи:\n\n"
-	message += b.taskService.FormatTaskList(tasks)
-	b.sendMessage(chatID, message)
+	text := "⏳ Невыполненные задачи:\n\n"
+	text += b.taskService.FormatTaskList(tasks, b.userLocation(user))
+	b.sendMessage(chatID, text)
 }
 
 // handleCompletedTasksCommand обрабатывает команду /completed
-func (b *Bot) handleCompletedTasksCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
+func (b *Bot) handleCompletedTasksCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	tasks, err := b.taskService.GetTasksByStatus(ctx, user.ID, domain.TaskStatusCompleted)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
+		b.sendError(chatID, err)
 		return
 	}
 
-	tasks, err := b.taskService.GetTasksByStatus(ctx, user.ID, domain.TaskStatusCompleted)
+	if len(tasks) == 0 {
+		b.sendMessage(chatID, "📝 У вас нет выполненных задач.")
+		return
+	}
+
+	text := "✅ Выполненные задачи:\n\n"
+	text += b.taskService.FormatTaskList(tasks, b.userLocation(user))
+	b.sendMessage(chatID, text)
+}
+
+// handleOverdueTasksCommand обрабатывает команду /overdue
+func (b *Bot) handleOverdueTasksCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	tasks, err := b.taskService.GetOverdueTasks(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения задач: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
 	if len(tasks) == 0 {
-		b.sendMessage(chatID, "📝 У вас нет выполненных задач.")
+		b.sendMessage(chatID, "🎉 У вас нет просроченных задач!")
 		return
 	}
 
-	message := "✅ Выполненные задачи:\n\n"
-	message += b.taskService.FormatTaskList(tasks)
-	b.sendMessage(chatID, message)
+	text := "🔥 Просроченные задачи:\n\n"
+	text += b.taskService.FormatTaskList(tasks, b.userLocation(user))
+	b.sendMessage(chatID, text)
 }
 
-// handleSetNotificationCommand обрабатывает команду /notify
-func (b *Bot) handleSetNotificationCommand(ctx context.Context, message *tgbotapi.Message) {
+// handleSetDueDateCommand обрабатывает команду /due
+func (b *Bot) handleSetDueDateCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
 
-	user, err := b.getUserFromTelegram(ctx, userID)
+	args := strings.Fields(message.Text)
+	if len(args) < 3 {
+		b.sendMessage(chatID, "❌ Укажите ID задачи и срок: /due 123 завтра 18:00")
+		return
+	}
+
+	taskID, err := strconv.Atoi(args[1])
+	if err != nil {
+		b.sendMessage(chatID, "❌ Неверный ID задачи")
+		return
+	}
+
+	if args[2] == "off" || args[2] == "-" {
+		task, err := b.taskService.ClearTaskDueDate(ctx, taskID, user.ID)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		b.sendMessage(chatID, fmt.Sprintf("📅 Срок задачи [%d] убран\n📌 %s", task.ID, task.Title))
+		return
+	}
+
+	loc := b.userLocation(user)
+	dueAt, err := b.parseTime(strings.Join(args[2:], " "), loc)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
+		b.sendMessage(chatID, fmt.Sprintf("❌ Неверный формат времени: %s", err.Error()))
 		return
 	}
 
+	task, err := b.taskService.SetTaskDueDate(ctx, taskID, user.ID, dueAt)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("📅 Срок установлен!\n📌 Задача [%d]: %s\n🗓️ Срок: %s",
+		task.ID, task.Title, task.DueAt.In(loc).Format("02.01.2006 15:04"))
+	for _, reminder := range task.PendingReminders() {
+		text += fmt.Sprintf("\n⏰ Напоминание: %s", reminder.RemindAt.In(loc).Format("02.01.2006 15:04"))
+	}
+	b.sendMessage(chatID, text)
+}
+
+// handleSetNotificationCommand обрабатывает команду /notify
+func (b *Bot) handleSetNotificationCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
 	args := strings.Fields(message.Text)
 	if len(args) < 3 {
 		// Запускаем интерактивную настройку уведомления
-		b.userStates[userID] = &UserState{
-			Action:   "set_notification",
-			Step:     1,
+		if err := b.startState(ctx, user.ID, &UserState{
+			Action:   actionSetNotification,
 			TaskData: make(map[string]string),
+		}); err != nil {
+			b.sendError(chatID, err)
+			return
 		}
 		b.sendMessage(chatID, "⏰ Настройка уведомления\n\n1️⃣ Введите ID задачи:")
 		return
@@ -287,72 +349,101 @@ func (b *Bot) handleSetNotificationCommand(ctx context.Context, message *tgbotap
 	}
 
 	timeStr := strings.Join(args[2:], " ")
-	notifyTime, err := b.parseTime(timeStr)
+	notifyTime, err := b.parseTime(timeStr, b.userLocation(user))
 	if err != nil {
 		b.sendMessage(chatID, fmt.Sprintf("❌ Неверный формат времени: %s", err.Error()))
 		return
 	}
 
-	task, err := b.taskService.SetTaskNotification(ctx, taskID, user.ID, notifyTime)
+	task, err := b.taskService.AddReminder(ctx, taskID, user.ID, notifyTime)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	b.sendMessage(chatID, fmt.Sprintf("⏰ Уведомление установлено!\n📌 Задача [%d]: %s\n🕐 Время: %s",
-		task.ID, task.Title, notifyTime.Format("02.01.2006 15:04")))
+	b.sendMessage(chatID, formatReminderAdded(task, notifyTime))
 }
 
-// parseTime парсит время из различных форматов
-func (b *Bot) parseTime(timeStr string) (time.Time, error) {
-	now := time.Now()
-	timeStr = strings.ToLower(strings.TrimSpace(timeStr))
-
-	// Регулярные выражения для разных форматов времени
-	patterns := []struct {
-		regex  *regexp.Regexp
-		parser func([]string) (time.Time, error)
-	}{
-		// Сегодня HH:MM
-		{
-			regexp.MustCompile(`^(\d{1,2}):(\d{2})$`),
-			func(matches []string) (time.Time, error) {
-				hour, _ := strconv.Atoi(matches[1])
-				minute, _ := strconv.Atoi(matches[2])
-				return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location()), nil
-			},
-		},
-		// Завтра HH:MM
-		{
-			regexp.MustCompile(`^завтра (\d{1,2}):(\d{2})$`),
-			func(matches []string) (time.Time, error) {
-				hour, _ := strconv.Atoi(matches[1])
-				minute, _ := strconv.Atoi(matches[2])
-				tomorrow := now.AddDate(0, 0, 1)
-				return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, minute, 0, 0, now.Location()), nil
-			},
-		},
-		// DD.MM HH:MM
-		{
-			regexp.MustCompile(`^(\d{1,2})\.(\d{1,2}) (\d{1,2}):(\d{2})$`),
-			func(matches []string) (time.Time, error) {
-				day, _ := strconv.Atoi(matches[1])
-				month, _ := strconv.Atoi(matches[2])
-				hour, _ := strconv.Atoi(matches[3])
-				minute, _ := strconv.Atoi(matches[4])
-				year := now.Year()
-				return time.Date(year, time.Month(month), day, hour, minute, 0, 0, now.Location()), nil
-			},
-		},
-	}
-
-	for _, pattern := range patterns {
-		if matches := pattern.regex.FindStringSubmatch(timeStr); matches != nil {
-			return pattern.parser(matches)
+// handleSetRecurrenceCommand обрабатывает команду /repeat
+func (b *Bot) handleSetRecurrenceCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.Text)
+	if len(args) < 2 {
+		b.sendMessage(chatID, "❌ Укажите ID задачи: /repeat 123 weekdays 09:30")
+		return
+	}
+
+	taskID, err := strconv.Atoi(args[1])
+	if err != nil {
+		b.sendMessage(chatID, "❌ Неверный ID задачи")
+		return
+	}
+
+	if len(args) < 3 {
+		// Показываем выбор правила повторения
+		keyboard := b.getRecurrenceKeyboard(taskID)
+		b.sendMessageWithKeyboard(chatID, fmt.Sprintf("🔁 Повторение задачи [%d]\n\nВыберите правило:", taskID), keyboard)
+		return
+	}
+
+	task, err := b.taskService.SetTaskRecurrence(ctx, taskID, user.ID, strings.Join(args[2:], " "))
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendMessage(chatID, formatRecurrenceResult(task))
+}
+
+// formatReminderAdded формирует ответ о добавлении напоминания со списком остальных напоминаний задачи
+func formatReminderAdded(task *domain.Task, remindAt time.Time) string {
+	text := fmt.Sprintf("⏰ Напоминание добавлено!\n📌 Задача [%d]: %s\n🕐 Время: %s",
+		task.ID, task.Title, remindAt.Format("02.01.2006 15:04"))
+
+	if pending := task.PendingReminders(); len(pending) > 1 {
+		text += fmt.Sprintf("\n\nВсего напоминаний: %d, посмотреть и удалить лишние: /show %d", len(pending), task.ID)
+	}
+	return text
+}
+
+// formatRecurrenceResult формирует ответ об изменении правила повторения
+func formatRecurrenceResult(task *domain.Task) string {
+	if !task.IsRecurring() {
+		return fmt.Sprintf("🔁 Повторение задачи [%d] отключено\n📌 %s", task.ID, task.Title)
+	}
+
+	description := task.Recurrence
+	if recurrence, err := domain.ParseRecurrence(task.Recurrence); err == nil {
+		description = recurrence.Describe()
+	}
+
+	return fmt.Sprintf("🔁 Повторение установлено!\n📌 Задача [%d]: %s\n📆 %s", task.ID, task.Title, description)
+}
+
+// parseTime парсит время на естественном языке в часовом поясе пользователя
+func (b *Bot) parseTime(timeStr string, loc *time.Location) (time.Time, error) {
+	parsed, err := timeparse.Parse(timeStr, time.Now().In(loc))
+	if err != nil {
+		switch {
+		case errors.Is(err, timeparse.ErrEmpty):
+			return time.Time{}, fmt.Errorf("время не указано")
+		case errors.Is(err, timeparse.ErrInvalidDate):
+			return time.Time{}, fmt.Errorf("такой даты не существует")
+		case errors.Is(err, timeparse.ErrInvalidTime):
+			return time.Time{}, fmt.Errorf("некорректное время")
+		case errors.Is(err, timeparse.ErrConflict):
+			return time.Time{}, fmt.Errorf("указано несколько дат или времен")
+		case errors.Is(err, timeparse.ErrPast):
+			return time.Time{}, fmt.Errorf("это время уже прошло")
+		case errors.Is(err, timeparse.ErrOutOfRange):
+			return time.Time{}, fmt.Errorf("слишком далекая дата")
+		default:
+			return time.Time{}, fmt.Errorf("не удалось распознать время \"%s\"", timeStr)
 		}
 	}
 
-	return time.Time{}, fmt.Errorf("неподдерживаемый формат времени")
+	return parsed, nil
 }
 
 // handleAddTaskState обрабатывает состояние создания задачи
@@ -362,7 +453,10 @@ func (b *Bot) handleAddTaskState(ctx context.Context, message *tgbotapi.Message,
 	switch state.Step {
 	case 1: // Название задачи
 		state.TaskData["title"] = message.Text
-		state.Step = 2
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 		b.sendMessage(chatID, "2️⃣ Введите описание задачи (или отправьте \"-\" чтобы пропустить):")
 
 	case 2: // Описание задачи
@@ -371,9 +465,12 @@ func (b *Bot) handleAddTaskState(ctx context.Context, message *tgbotapi.Message,
 			description = ""
 		}
 		state.TaskData["description"] = description
-		state.Step = 3
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 
-		keyboard := getPriorityKeyboard()
+		keyboard := b.getPriorityKeyboard()
 		b.sendMessageWithKeyboard(chatID, "3️⃣ Выберите приоритет задачи:", keyboard)
 
 	default:
@@ -390,14 +487,14 @@ func (b *Bot) handleAddTaskState(ctx context.Context, message *tgbotapi.Message,
 			state.TaskData["description"],
 			priority)
 
-		delete(b.userStates, user.TelegramID)
+		b.clearState(ctx, user.ID)
 
 		if err != nil {
-			b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания задачи: %s", err.Error()))
+			b.sendError(chatID, err)
 			return
 		}
 
-		b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] создана!\n%s", task.ID, b.taskService.FormatTask(task)))
+		b.sendMessage(chatID, fmt.Sprintf("✅ Задача [%d] создана!\n%s", task.ID, b.taskService.FormatTask(task, b.userLocation(user))))
 	}
 }
 
@@ -413,29 +510,90 @@ func (b *Bot) handleSetNotificationState(ctx context.Context, message *tgbotapi.
 			return
 		}
 		state.TaskID = taskID
-		state.Step = 2
-		b.sendMessage(chatID, "2️⃣ Введите время уведомления:\n\nПримеры:\n• 15:30 - сегодня в 15:30\n• завтра 10:00\n• 25.12 14:00")
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+		b.sendMessage(chatID, "2️⃣ Введите время уведомления:\n\nПримеры:\n• 15:30 - сегодня в 15:30\n• завтра 10:00\n• через 2 часа\n• в пятницу в 9\n• послезавтра утром\n• 25.12 14:00")
 
 	case 2: // Время уведомления
-		notifyTime, err := b.parseTime(message.Text)
+		notifyTime, err := b.parseTime(message.Text, b.userLocation(user))
 		if err != nil {
 			b.sendMessage(chatID, fmt.Sprintf("❌ Неверный формат времени: %s\nПопробуйте еще раз:", err.Error()))
 			return
 		}
 
-		task, err := b.taskService.SetTaskNotification(ctx, state.TaskID, user.ID, notifyTime)
-		delete(b.userStates, user.TelegramID)
+		task, err := b.taskService.AddReminder(ctx, state.TaskID, user.ID, notifyTime)
+		b.clearState(ctx, user.ID)
 
 		if err != nil {
-			b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+			b.sendError(chatID, err)
 			return
 		}
 
-		b.sendMessage(chatID, fmt.Sprintf("⏰ Уведомление установлено!\n📌 Задача [%d]: %s\n🕐 Время: %s",
-			task.ID, task.Title, notifyTime.Format("02.01.2006 15:04")))
+		b.sendMessage(chatID, formatReminderAdded(task, notifyTime))
 	}
 }
 
+// handleSnoozeCustomState обрабатывает ввод времени, на которое откладывается напоминание
+func (b *Bot) handleSnoozeCustomState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
+	chatID := message.Chat.ID
+
+	notifyAt, err := b.parseTime(message.Text, b.userLocation(user))
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Неверный формат времени: %s\nПопробуйте еще раз:", err.Error()))
+		return
+	}
+
+	task, err := b.taskService.AddReminder(ctx, state.TaskID, user.ID, notifyAt)
+	if err != nil {
+		b.sendMessage(chatID, errorMessage(err)+"\nПопробуйте еще раз:")
+		return
+	}
+	b.clearState(ctx, user.ID)
+
+	b.editSnoozedReminder(chatID, state.MessageID, state.TaskData["text"], task.ID, notifyAt)
+	b.sendMessage(chatID, fmt.Sprintf("💤 Напоминание отложено!\n📌 Задача [%d]: %s\n🕐 Время: %s",
+		task.ID, task.Title, notifyAt.Format("02.01.2006 15:04")))
+}
+
+// handleSetRecurrenceState обрабатывает ввод собственного правила повторения
+func (b *Bot) handleSetRecurrenceState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
+	chatID := message.Chat.ID
+
+	task, err := b.taskService.SetTaskRecurrence(ctx, state.TaskID, user.ID, message.Text)
+	if err != nil {
+		b.sendMessage(chatID, errorMessage(err)+"\nПопробуйте еще раз:")
+		return
+	}
+
+	b.clearState(ctx, user.ID)
+	b.sendMessage(chatID, formatRecurrenceResult(task))
+}
+
+// handleAddSubtaskState обрабатывает ввод названия подзадачи
+func (b *Bot) handleAddSubtaskState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
+	chatID := message.Chat.ID
+
+	subtask, err := b.taskService.AddSubtask(ctx, state.TaskID, user.ID, message.Text)
+	b.clearState(ctx, user.ID)
+
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	parent, err := b.taskService.GetTaskWithSubtasks(ctx, state.TaskID, user.ID)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("✅ Подзадача [%d] добавлена!", subtask.ID))
+		return
+	}
+
+	loc := b.userLocation(user)
+	keyboard := b.getTaskActionsKeyboard(parent.ID, getSubtaskItems(parent), getReminderItems(parent, loc))
+	b.sendMessageWithKeyboard(chatID, b.taskService.FormatTask(parent, loc), keyboard)
+}
+
 // handleAddNoteState обрабатывает состояние создания заметки
 func (b *Bot) handleAddNoteState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
 	chatID := message.Chat.ID
@@ -443,7 +601,10 @@ func (b *Bot) handleAddNoteState(ctx context.Context, message *tgbotapi.Message,
 	switch state.Step {
 	case 1: // Заголовок заметки
 		state.NoteData["title"] = message.Text
-		state.Step = 2
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 		b.sendMessage(chatID, "2️⃣ Введите содержимое заметки (или отправьте \"-\" чтобы пропустить):")
 
 	case 2: // Содержимое заметки
@@ -452,13 +613,19 @@ func (b *Bot) handleAddNoteState(ctx context.Context, message *tgbotapi.Message,
 			content = ""
 		}
 		state.NoteData["content"] = content
-		state.Step = 3
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 
-		keyboard := getCategoryKeyboard()
+		keyboard := b.getCategoryKeyboard()
 		b.sendMessageWithKeyboard(chatID, "3️⃣ Выберите категорию заметки:", keyboard)
 
-	case 3: // Теги
-		state.Step = 4
+	case 3: // Категория не выбрана кнопкой, переходим к тегам
+		if err := b.advanceState(ctx, user.ID, state); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
 		b.sendMessage(chatID, "4️⃣ Введите теги через запятую (или отправьте \"-\" чтобы пропустить):")
 
 	case 4: // Завершение создания заметки
@@ -478,16 +645,16 @@ func (b *Bot) handleAddNoteState(ctx context.Context, message *tgbotapi.Message,
 			category,
 			tags)
 
-		delete(b.userStates, user.TelegramID)
+		b.clearState(ctx, user.ID)
 
 		if err != nil {
-			b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания заметки: %s", err.Error()))
+			b.sendError(chatID, err)
 			return
 		}
 
-		response := fmt.Sprintf("✅ Заметка [%d] создана!\n\n%s", note.ID, b.noteService.FormatNoteForDisplay(note))
+		response := fmt.Sprintf("✅ Заметка [%d] создана!\n\n%s", note.ID, b.noteService.FormatNoteForDisplay(note, b.userLocation(user)))
 		msg := tgbotapi.NewMessage(chatID, response)
 		msg.ParseMode = "Markdown"
-		b.api.Send(msg)
+		b.sendChattable(msg)
 	}
 }
@@ -2,119 +2,205 @@ package telegram
 
 import (
 	"fmt"
-	"strconv"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"todolist/internal/callbackdata"
+	"todolist/internal/domain"
 )
 
+// button создает inline-кнопку с закодированными данными. Если данные не кодируются
+// (например, аргумент не помещается в 64 байта), кнопка открывает главное меню
+func (b *Bot) button(text string, data callbackdata.Data) tgbotapi.InlineKeyboardButton {
+	encoded, err := b.callbacks.EncodeOrMenu(data)
+	if err != nil {
+		b.logger.Error("failed to encode callback data", zap.String("button", data.Key()), zap.Error(err))
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, encoded)
+}
+
 // getMainMenuKeyboard возвращает главное меню бота
-func getMainMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) getMainMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "📋 Мои задачи", CallbackData: &[]string{"cmd_tasks"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "➕ Добавить задачу", CallbackData: &[]string{"cmd_add_task"}[0]},
+				b.button("📋 Мои задачи", callbackdata.Menu(callbackdata.ActionTasks)),
+				b.button("➕ Добавить задачу", callbackdata.Menu(callbackdata.ActionAddTask)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "📝 Мои заметки", CallbackData: &[]string{"cmd_notes"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "📄 Добавить заметку", CallbackData: &[]string{"cmd_add_note"}[0]},
+				b.button("📝 Мои заметки", callbackdata.Menu(callbackdata.ActionNotes)),
+				b.button("📄 Добавить заметку", callbackdata.Menu(callbackdata.ActionAddNote)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "⏰ Активные задачи", CallbackData: &[]string{"cmd_pending"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "✅ Выполненные", CallbackData: &[]string{"cmd_completed"}[0]},
+				b.button("⏰ Активные задачи", callbackdata.Menu(callbackdata.ActionPending)),
+				b.button("✅ Выполненные", callbackdata.Menu(callbackdata.ActionCompleted)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🔍 Поиск заметок", CallbackData: &[]string{"cmd_search"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "⭐ Избранные", CallbackData: &[]string{"cmd_favorites"}[0]},
+				b.button("🔍 Поиск заметок", callbackdata.Menu(callbackdata.ActionSearch)),
+				b.button("⭐ Избранные", callbackdata.Menu(callbackdata.ActionFavorites)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "❓ Справка", CallbackData: &[]string{"cmd_help"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🚪 Выйти", CallbackData: &[]string{"cmd_logout"}[0]},
+				b.button("❓ Справка", callbackdata.Menu(callbackdata.ActionHelp)),
+				b.button("🚪 Выйти", callbackdata.Menu(callbackdata.ActionLogout)),
 			},
 		},
 	}
 }
 
-// getTaskActionsKeyboard возвращает клавиатуру для действий с задачей
-func getTaskActionsKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
-	taskIDStr := strconv.Itoa(taskID)
+// getTaskActionsKeyboard возвращает клавиатуру для действий с задачей.
+// Подзадачи выводятся отдельными кнопками, нажатие на которые отмечает пункт выполненным,
+// а неотправленные напоминания - кнопками для их удаления.
+func (b *Bot) getTaskActionsKeyboard(taskID int, subtasks []TaskListItem, reminders []ReminderListItem) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, subtask := range subtasks {
+		mark := "⬜"
+		if subtask.IsCompleted {
+			mark = "✅"
+		}
+
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{
+			b.button(fmt.Sprintf("%s %s", mark, truncateString(subtask.Title, 30)),
+				callbackdata.New(callbackdata.EntitySubtask, callbackdata.ActionToggle, subtask.ID)),
+		})
+	}
+
+	for _, reminder := range reminders {
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{
+			b.button(fmt.Sprintf("🔕 Удалить напоминание %s", reminder.Label),
+				callbackdata.New(callbackdata.EntityReminder, callbackdata.ActionRemove, reminder.ID)),
+		})
+	}
+
 	return tgbotapi.InlineKeyboardMarkup{
-		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+		InlineKeyboard: append(rows, [][]tgbotapi.InlineKeyboardButton{
+			{
+				b.button("✅ Выполнить", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionComplete, taskID)),
+				b.button("👀 Подробнее", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionShow, taskID)),
+			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "✅ Выполнить", CallbackData: &[]string{"complete_" + taskIDStr}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "👀 Подробнее", CallbackData: &[]string{"show_" + taskIDStr}[0]},
+				b.button("⏰ Напоминание", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionNotify, taskID)),
+				b.button("🔁 Повтор", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionRecurrence, taskID)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "⏰ Напоминание", CallbackData: &[]string{"notify_" + taskIDStr}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🗑️ Удалить", CallbackData: &[]string{"delete_" + taskIDStr}[0]},
+				b.button("➕ Подзадача", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionAddSubtask, taskID)),
+				b.button("🗑️ Удалить", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionDelete, taskID)),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🔙 Назад к задачам", CallbackData: &[]string{"cmd_tasks"}[0]},
+				b.button("🔙 Назад к задачам", callbackdata.Menu(callbackdata.ActionTasks)),
+			},
+		}...),
+	}
+}
+
+// getSnoozedReminderKeyboard возвращает клавиатуру напоминания после того, как его отложили
+func (b *Bot) getSnoozedReminderKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{
+				b.button("✅ Выполнить", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionComplete, taskID)),
+				b.button("📋 Подробнее", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionShow, taskID)),
 			},
 		},
 	}
 }
 
 // getPriorityKeyboard возвращает клавиатуру для выбора приоритета задачи
-func getPriorityKeyboard() tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) getPriorityKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🔴 Высокий", CallbackData: &[]string{"priority_high"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🟡 Средний", CallbackData: &[]string{"priority_medium"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🟢 Низкий", CallbackData: &[]string{"priority_low"}[0]},
+				b.button("🔴 Высокий", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionPriority, 0, "high")),
+				b.button("🟡 Средний", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionPriority, 0, "medium")),
+				b.button("🟢 Низкий", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionPriority, 0, "low")),
 			},
 		},
 	}
 }
 
-// getCategoryKeyboard возвращает клавиатуру для выбора категории заметки
-func getCategoryKeyboard() tgbotapi.InlineKeyboardMarkup {
+// getRecurrenceKeyboard возвращает клавиатуру для выбора правила повторения задачи
+func (b *Bot) getRecurrenceKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	rule := func(preset string) callbackdata.Data {
+		return callbackdata.New(callbackdata.EntityTask, callbackdata.ActionRepeat, taskID, preset)
+	}
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🗂️ Общее", CallbackData: &[]string{"category_general"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "💼 Работа", CallbackData: &[]string{"category_work"}[0]},
+				b.button("📅 Каждый день", rule("daily")),
+				b.button("💼 По будням", rule("weekdays")),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "📚 Учеба", CallbackData: &[]string{"category_study"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "👤 Личное", CallbackData: &[]string{"category_personal"}[0]},
+				b.button("🗓️ Каждую неделю", rule("weekly")),
+				b.button("📆 Каждый месяц", rule("monthly")),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🔗 Ресурсы", CallbackData: &[]string{"category_resources"}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "💡 Идеи", CallbackData: &[]string{"category_ideas"}[0]},
+				b.button("✏️ Свое правило", rule("custom")),
+				b.button("🚫 Без повтора", rule("off")),
 			},
 		},
 	}
 }
 
-// getNoteActionsKeyboard возвращает клавиатуру для действий с заметкой
-func getNoteActionsKeyboard(noteID int, isFavorite bool) tgbotapi.InlineKeyboardMarkup {
-	noteIDStr := strconv.Itoa(noteID)
-	favoriteText := "⭐ В избранное"
-	favoriteAction := "favorite_add_" + noteIDStr
-
-	if isFavorite {
-		favoriteText = "✨ Убрать из избранного"
-		favoriteAction = "favorite_remove_" + noteIDStr
-	}
+// getLocationRequestKeyboard возвращает клавиатуру с запросом геопозиции для определения часового пояса
+func getLocationRequestKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	keyboard := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButtonLocation("📍 Отправить геопозицию")),
+	)
+	keyboard.OneTimeKeyboard = true
+	keyboard.ResizeKeyboard = true
+	return keyboard
+}
 
+// getCategoryKeyboard возвращает клавиатуру для выбора категории заметки
+func (b *Bot) getCategoryKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: favoriteText, CallbackData: &favoriteAction},
-				tgbotapi.InlineKeyboardButton{Text: "📝 Редактировать", CallbackData: &[]string{"edit_note_" + noteIDStr}[0]},
+				b.button("🗂️ Общее", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "general")),
+				b.button("💼 Работа", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "work")),
 			},
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🗑️ Удалить", CallbackData: &[]string{"delete_note_" + noteIDStr}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "🔙 К заметкам", CallbackData: &[]string{"cmd_notes"}[0]},
+				b.button("📚 Учеба", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "study")),
+				b.button("👤 Личное", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "personal")),
+			},
+			{
+				b.button("🔗 Ресурсы", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "resources")),
+				b.button("💡 Идеи", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionCategory, 0, "ideas")),
 			},
 		},
 	}
 }
 
+// getNoteActionsKeyboard возвращает клавиатуру для действий с заметкой
+func (b *Bot) getNoteActionsKeyboard(note *domain.Note) tgbotapi.InlineKeyboardMarkup {
+	favoriteText := "⭐ В избранное"
+	favoriteAction := callbackdata.ActionFavoriteAdd
+
+	if note.IsFavorite {
+		favoriteText = "✨ Убрать из избранного"
+		favoriteAction = callbackdata.ActionFavoriteRemove
+	}
+
+	firstRow := tgbotapi.NewInlineKeyboardRow(
+		b.button(favoriteText, callbackdata.New(callbackdata.EntityNote, favoriteAction, note.ID)),
+	)
+	if note.IsFile() {
+		firstRow = append(firstRow,
+			b.button("📎 Скачать файл", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionDownload, note.ID)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		firstRow,
+		tgbotapi.NewInlineKeyboardRow(
+			b.button("🗑️ Удалить", callbackdata.New(callbackdata.EntityNote, callbackdata.ActionDelete, note.ID)),
+			b.button("🔙 К заметкам", callbackdata.Menu(callbackdata.ActionNotes)),
+		),
+	)
+}
+
 // getTaskListKeyboard возвращает клавиатуру для списка задач с кнопками действий
-func getTaskListKeyboard(tasks []TaskListItem) tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) getTaskListKeyboard(tasks []TaskListItem) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Добавляем кнопки для каждой задачи (максимум 5)
@@ -123,34 +209,28 @@ func getTaskListKeyboard(tasks []TaskListItem) tgbotapi.InlineKeyboardMarkup {
 			break
 		}
 
-		taskIDStr := strconv.Itoa(task.ID)
-		completeBtn := tgbotapi.InlineKeyboardButton{
-			Text:         "✅",
-			CallbackData: &[]string{"complete_" + taskIDStr}[0],
-		}
-		showBtn := tgbotapi.InlineKeyboardButton{
-			Text:         fmt.Sprintf("👀 [%d] %s", task.ID, truncateString(task.Title, 20)),
-			CallbackData: &[]string{"show_" + taskIDStr}[0],
-		}
+		completeBtn := b.button("✅", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionComplete, task.ID))
+		showBtn := b.button(fmt.Sprintf("👀 [%d] %s", task.ID, truncateString(task.Title, 20)),
+			callbackdata.New(callbackdata.EntityTask, callbackdata.ActionShow, task.ID))
 
 		rows = append(rows, []tgbotapi.InlineKeyboardButton{completeBtn, showBtn})
 	}
 
 	// Добавляем кнопки управления
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.InlineKeyboardButton{Text: "➕ Добавить задачу", CallbackData: &[]string{"cmd_add_task"}[0]},
-		tgbotapi.InlineKeyboardButton{Text: "🔄 Обновить", CallbackData: &[]string{"cmd_tasks"}[0]},
+		b.button("➕ Добавить задачу", callbackdata.Menu(callbackdata.ActionAddTask)),
+		b.button("🔄 Обновить", callbackdata.Menu(callbackdata.ActionTasks)),
 	})
 
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+		b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 	})
 
 	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
 }
 
 // getNoteListKeyboard возвращает клавиатуру для списка заметок
-func getNoteListKeyboard(notes []NoteListItem) tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) getNoteListKeyboard(notes []NoteListItem) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Добавляем кнопки для каждой заметки (максимум 5)
@@ -159,52 +239,48 @@ func getNoteListKeyboard(notes []NoteListItem) tgbotapi.InlineKeyboardMarkup {
 			break
 		}
 
-		noteIDStr := strconv.Itoa(note.ID)
 		favoriteIcon := ""
 		if note.IsFavorite {
 			favoriteIcon = "⭐"
 		}
 
-		showBtn := tgbotapi.InlineKeyboardButton{
-			Text:         fmt.Sprintf("%s📝 [%d] %s", favoriteIcon, note.ID, truncateString(note.Title, 18)),
-			CallbackData: &[]string{"show_note_" + noteIDStr}[0],
-		}
+		showBtn := b.button(fmt.Sprintf("%s📝 [%d] %s", favoriteIcon, note.ID, truncateString(note.Title, 18)),
+			callbackdata.New(callbackdata.EntityNote, callbackdata.ActionShow, note.ID))
 
 		rows = append(rows, []tgbotapi.InlineKeyboardButton{showBtn})
 	}
 
 	// Добавляем кнопки управления
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.InlineKeyboardButton{Text: "📄 Добавить заметку", CallbackData: &[]string{"cmd_add_note"}[0]},
-		tgbotapi.InlineKeyboardButton{Text: "🔄 Обновить", CallbackData: &[]string{"cmd_notes"}[0]},
+		b.button("📄 Добавить заметку", callbackdata.Menu(callbackdata.ActionAddNote)),
+		b.button("🔄 Обновить", callbackdata.Menu(callbackdata.ActionNotes)),
 	})
 
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+		b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 	})
 
 	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
 }
 
-// getConfirmationKeyboard возвращает клавиатуру подтверждения действия
-func getConfirmationKeyboard(action string, itemID int) tgbotapi.InlineKeyboardMarkup {
-	itemIDStr := strconv.Itoa(itemID)
+// getConfirmationKeyboard возвращает клавиатуру подтверждения действия action над сущностью entity
+func (b *Bot) getConfirmationKeyboard(entity callbackdata.Entity, action callbackdata.Action, itemID int) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "✅ Да", CallbackData: &[]string{"confirm_" + action + "_" + itemIDStr}[0]},
-				tgbotapi.InlineKeyboardButton{Text: "❌ Отмена", CallbackData: &[]string{"cancel_" + action}[0]},
+				b.button("✅ Да", callbackdata.Confirm(entity, itemID, action)),
+				b.button("❌ Отмена", callbackdata.Menu(callbackdata.ActionCancel)),
 			},
 		},
 	}
 }
 
 // getBackToMenuKeyboard возвращает кнопку возврата в главное меню
-func getBackToMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
+func (b *Bot) getBackToMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{Text: "🏠 Главное меню", CallbackData: &[]string{"cmd_menu"}[0]},
+				b.button("🏠 Главное меню", callbackdata.Menu(callbackdata.ActionMain)),
 			},
 		},
 	}
@@ -212,8 +288,41 @@ func getBackToMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 
 // TaskListItem представляет элемент списка задач для клавиатуры
 type TaskListItem struct {
+	ID          int
+	Title       string
+	IsCompleted bool
+}
+
+// getSubtaskItems конвертирует подзадачи в элементы для клавиатуры
+func getSubtaskItems(task *domain.Task) []TaskListItem {
+	items := make([]TaskListItem, 0, len(task.Subtasks))
+	for _, subtask := range task.Subtasks {
+		items = append(items, TaskListItem{
+			ID:          subtask.ID,
+			Title:       subtask.Title,
+			IsCompleted: subtask.IsCompleted(),
+		})
+	}
+	return items
+}
+
+// ReminderListItem представляет напоминание задачи для клавиатуры
+type ReminderListItem struct {
 	ID    int
-	Title string
+	Label string
+}
+
+// getReminderItems конвертирует неотправленные напоминания задачи в элементы для клавиатуры
+func getReminderItems(task *domain.Task, loc *time.Location) []ReminderListItem {
+	pending := task.PendingReminders()
+	items := make([]ReminderListItem, 0, len(pending))
+	for _, reminder := range pending {
+		items = append(items, ReminderListItem{
+			ID:    reminder.ID,
+			Label: reminder.RemindAt.In(loc).Format("02.01 15:04"),
+		})
+	}
+	return items
 }
 
 // NoteListItem представляет элемент списка заметок для клавиатуры
@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"context"
+	"expvar"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// routeMetrics - число вызовов каждой команды и callback, публикуется через expvar в /debug/vars
+var routeMetrics = expvar.NewMap("telegram_routes")
+
+// routerMetrics - отказы, ограничения частоты, паники и суммарное время обработки
+var routerMetrics = expvar.NewMap("telegram_router")
+
+// recoverMiddleware перехватывает панику обработчика, чтобы она не остановила воркер,
+// и сообщает пользователю об ошибке
+func (b *Bot) recoverMiddleware(next routeHandler) routeHandler {
+	return func(ctx context.Context, req *routeRequest) {
+		defer func() {
+			if r := recover(); r != nil {
+				routerMetrics.Add("panics", 1)
+				b.logger.Error("panic in route handler",
+					zap.String("route", req.route.title()),
+					zap.Int64("telegram_id", req.from.ID),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()))
+				b.sendMessage(req.chatID, "❌ Произошла ошибка. Попробуйте позже")
+			}
+		}()
+
+		next(ctx, req)
+	}
+}
+
+// observeMiddleware записывает в лог и метрики каждый вызов маршрута и время его обработки
+func (b *Bot) observeMiddleware(next routeHandler) routeHandler {
+	return func(ctx context.Context, req *routeRequest) {
+		started := time.Now()
+		next(ctx, req)
+		elapsed := time.Since(started)
+
+		routeMetrics.Add(req.route.title(), 1)
+		routerMetrics.Add("handled", 1)
+		routerMetrics.Add("duration_ms", elapsed.Milliseconds())
+
+		b.logger.Info("route handled",
+			zap.String("route", req.route.title()),
+			zap.Int64("telegram_id", req.from.ID),
+			zap.Duration("duration", elapsed))
+	}
+}
+
+// rateLimitMiddleware ограничивает частоту команд и нажатий кнопок одного пользователя.
+// О превышении пользователь узнает один раз, пока лимит не восстановится.
+func (b *Bot) rateLimitMiddleware(limiter *userRateLimiter) middleware {
+	return func(next routeHandler) routeHandler {
+		return func(ctx context.Context, req *routeRequest) {
+			allowed, warn := limiter.allow(req.from.ID, time.Now())
+			if !allowed {
+				routerMetrics.Add("rate_limited", 1)
+				if warn {
+					b.sendMessage(req.chatID, "⏳ Слишком много запросов. Подождите немного и повторите")
+				}
+				return
+			}
+
+			next(ctx, req)
+		}
+	}
+}
+
+// authMiddleware загружает пользователя с действующей сессией. Без сессии доступны только публичные команды.
+func (b *Bot) authMiddleware(next routeHandler) routeHandler {
+	return func(ctx context.Context, req *routeRequest) {
+		if req.route.public {
+			next(ctx, req)
+			return
+		}
+
+		user, err := b.authService.IsAuthenticated(ctx, req.from.ID, req.chatID)
+		if err != nil {
+			routerMetrics.Add("unauthorized", 1)
+			b.sendMessage(req.chatID, "🔐 Для использования бота необходимо авторизоваться. Используйте команду /start")
+			return
+		}
+
+		req.user = user
+		next(ctx, req)
+	}
+}
+
+// adminMiddleware пропускает к командам администраторов только пользователей с ролью администратора.
+// Административные команды не проверяют роль сами.
+func (b *Bot) adminMiddleware(next routeHandler) routeHandler {
+	return func(ctx context.Context, req *routeRequest) {
+		if req.route.admin && (req.user == nil || !req.user.IsAdmin()) {
+			routerMetrics.Add("forbidden", 1)
+			b.sendMessage(req.chatID, "⛔ Команда доступна только администраторам")
+			return
+		}
+
+		next(ctx, req)
+	}
+}
+
+// userRateLimiter ограничивает частоту запросов каждого пользователя по алгоритму GCRA:
+// допускается серия из burst запросов, затем один запрос в interval
+type userRateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu    sync.Mutex
+	users map[int64]*userRate
+}
+
+// userRate - состояние лимита пользователя
+type userRate struct {
+	// next - время, когда лимит полностью восстановится
+	next time.Time
+	// warned - пользователь уже получил предупреждение о превышении
+	warned bool
+}
+
+// userRatePruneThreshold - после этого числа записей устаревшие записи удаляются
+const userRatePruneThreshold = 1024
+
+// newUserRateLimiter создает ограничение perMinute запросов в минуту с серией до burst запросов.
+// При perMinute <= 0 ограничение отключено.
+func newUserRateLimiter(perMinute, burst int) *userRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	limiter := &userRateLimiter{
+		burst: burst,
+		users: make(map[int64]*userRate),
+	}
+	if perMinute > 0 {
+		limiter.interval = time.Minute / time.Duration(perMinute)
+	}
+	return limiter
+}
+
+// allow проверяет, можно ли выполнить запрос пользователя. warn - запрос отклонен впервые с момента превышения.
+func (l *userRateLimiter) allow(telegramID int64, now time.Time) (allowed bool, warn bool) {
+	if l.interval == 0 {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate, ok := l.users[telegramID]
+	if !ok {
+		if len(l.users) >= userRatePruneThreshold {
+			l.prune(now)
+		}
+		rate = &userRate{}
+		l.users[telegramID] = rate
+	}
+
+	next := rate.next
+	if next.Before(now) {
+		next = now
+	}
+
+	// Запрос допустим, если после него лимит восстановится не позже чем через burst интервалов
+	if next.Add(l.interval).Sub(now) > time.Duration(l.burst)*l.interval {
+		warn = !rate.warned
+		rate.warned = true
+		return false, warn
+	}
+
+	rate.next = next.Add(l.interval)
+	rate.warned = false
+	return true, false
+}
+
+// prune удаляет пользователей, чей лимит полностью восстановился. Вызывается под l.mu.
+func (l *userRateLimiter) prune(now time.Time) {
+	for telegramID, rate := range l.users {
+		if rate.next.Before(now) {
+			delete(l.users, telegramID)
+		}
+	}
+}
@@ -12,16 +12,12 @@ import (
 )
 
 // handleListNotesCommand обрабатывает команду /notes
-func (b *Bot) handleListNotesCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleListNotesCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
 
 	notes, err := b.noteService.GetUserNotes(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения заметок: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -30,6 +26,8 @@ func (b *Bot) handleListNotesCommand(ctx context.Context, chatID, userID int64)
 		return
 	}
 
+	loc := b.userLocation(user)
+
 	var response strings.Builder
 	response.WriteString("📚 *Ваши заметки:*\n\n")
 
@@ -43,34 +41,29 @@ func (b *Bot) handleListNotesCommand(ctx context.Context, chatID, userID int64)
 		if note.IsFavorite {
 			response.WriteString("⭐")
 		}
-		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.Format("02.01.2006")))
+		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.In(loc).Format("02.01.2006")))
 	}
 
 	response.WriteString("💡 Используйте /nshow ID для просмотра заметки")
 
 	msg := tgbotapi.NewMessage(chatID, response.String())
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleAddNoteCommand обрабатывает команду /note
-func (b *Bot) handleAddNoteCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleAddNoteCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
 		// Запускаем интерактивное создание заметки
-		b.userStates[userID] = &UserState{
-			Action:   "add_note",
-			Step:     1,
+		if err := b.startState(ctx, user.ID, &UserState{
+			Action:   actionAddNote,
 			NoteData: make(map[string]string),
+		}); err != nil {
+			b.sendError(chatID, err)
+			return
 		}
 		b.sendMessage(chatID, "📝 Создание новой заметки\n\n1️⃣ Введите заголовок заметки:")
 		return
@@ -80,7 +73,7 @@ func (b *Bot) handleAddNoteCommand(ctx context.Context, message *tgbotapi.Messag
 
 	note, err := b.noteService.CreateNote(ctx, user.ID, title, "", "general", "")
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания заметки: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -88,15 +81,8 @@ func (b *Bot) handleAddNoteCommand(ctx context.Context, message *tgbotapi.Messag
 }
 
 // handleShowNoteCommand обрабатывает команду /nshow
-func (b *Bot) handleShowNoteCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleShowNoteCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -110,35 +96,22 @@ func (b *Bot) handleShowNoteCommand(ctx context.Context, message *tgbotapi.Messa
 		return
 	}
 
-	note, err := b.noteService.GetNote(ctx, noteID)
+	note, err := b.noteService.GetNote(ctx, noteID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
-	// Проверяем принадлежность заметки пользователю
-	if note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
-		return
-	}
-
-	response := b.noteService.FormatNoteForDisplay(note)
+	response := b.noteService.FormatNoteForDisplay(note, b.userLocation(user))
 
 	msg := tgbotapi.NewMessage(chatID, response)
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleDeleteNoteCommand обрабатывает команду /ndelete
-func (b *Bot) handleDeleteNoteCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleDeleteNoteCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -152,21 +125,9 @@ func (b *Bot) handleDeleteNoteCommand(ctx context.Context, message *tgbotapi.Mes
 		return
 	}
 
-	// Проверяем принадлежность заметки пользователю
-	note, err := b.noteService.GetNote(ctx, noteID)
+	err = b.noteService.DeleteNote(ctx, noteID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
-		return
-	}
-
-	if note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
-		return
-	}
-
-	err = b.noteService.DeleteNote(ctx, noteID)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка удаления: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -174,16 +135,12 @@ func (b *Bot) handleDeleteNoteCommand(ctx context.Context, message *tgbotapi.Mes
 }
 
 // handleFavoriteNotesCommand обрабатывает команду /favorites
-func (b *Bot) handleFavoriteNotesCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleFavoriteNotesCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
 
 	notes, err := b.noteService.GetFavoriteNotes(ctx, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения избранных заметок: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -192,29 +149,24 @@ func (b *Bot) handleFavoriteNotesCommand(ctx context.Context, chatID, userID int
 		return
 	}
 
+	loc := b.userLocation(user)
+
 	var response strings.Builder
 	response.WriteString("⭐ *Избранные заметки:*\n\n")
 
 	for _, note := range notes {
 		response.WriteString(fmt.Sprintf("%s [%d] %s\n", note.GetDisplayType(), note.ID, note.Title))
-		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.Format("02.01.2006")))
+		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.In(loc).Format("02.01.2006")))
 	}
 
 	msg := tgbotapi.NewMessage(chatID, response.String())
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleToggleFavoriteCommand обрабатывает команду /favorite
-func (b *Bot) handleToggleFavoriteCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleToggleFavoriteCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -228,21 +180,9 @@ func (b *Bot) handleToggleFavoriteCommand(ctx context.Context, message *tgbotapi
 		return
 	}
 
-	// Проверяем принадлежность заметки пользователю
-	note, err := b.noteService.GetNote(ctx, noteID)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
-		return
-	}
-
-	if note.UserID != user.ID {
-		b.sendMessage(chatID, "❌ Заметка не найдена")
-		return
-	}
-
-	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID)
+	updatedNote, err := b.noteService.ToggleFavorite(ctx, noteID, user.ID)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -254,15 +194,8 @@ func (b *Bot) handleToggleFavoriteCommand(ctx context.Context, message *tgbotapi
 }
 
 // handleSearchNotesCommand обрабатывает команду /search
-func (b *Bot) handleSearchNotesCommand(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleSearchNotesCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	args := strings.Fields(message.Text)
 	if len(args) < 2 {
@@ -270,11 +203,26 @@ func (b *Bot) handleSearchNotesCommand(ctx context.Context, message *tgbotapi.Me
 		return
 	}
 
-	query := strings.Join(args[1:], " ")
+	b.sendNoteSearchResults(ctx, chatID, user, strings.Join(args[1:], " "))
+}
+
+// handleSearchNotesState обрабатывает ввод поискового запроса после кнопки поиска
+func (b *Bot) handleSearchNotesState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
+	query := strings.TrimSpace(message.Text)
+	if query == "" {
+		b.sendMessage(message.Chat.ID, "❌ Поисковый запрос не может быть пустым")
+		return
+	}
+
+	b.clearState(ctx, user.ID)
+	b.sendNoteSearchResults(ctx, message.Chat.ID, user, query)
+}
 
+// sendNoteSearchResults ищет заметки пользователя и отправляет результаты
+func (b *Bot) sendNoteSearchResults(ctx context.Context, chatID int64, user *domain.User, query string) {
 	notes, err := b.noteService.SearchNotes(ctx, user.ID, query)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка поиска: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -283,6 +231,8 @@ func (b *Bot) handleSearchNotesCommand(ctx context.Context, message *tgbotapi.Me
 		return
 	}
 
+	loc := b.userLocation(user)
+
 	var response strings.Builder
 	response.WriteString(fmt.Sprintf("🔍 *Результаты поиска по \"%s\":*\n\n", query))
 
@@ -296,25 +246,21 @@ func (b *Bot) handleSearchNotesCommand(ctx context.Context, message *tgbotapi.Me
 		if note.IsFavorite {
 			response.WriteString("⭐")
 		}
-		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.Format("02.01.2006")))
+		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.In(loc).Format("02.01.2006")))
 	}
 
 	msg := tgbotapi.NewMessage(chatID, response.String())
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleLinkNotesCommand обрабатывает команду /links
-func (b *Bot) handleLinkNotesCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleLinkNotesCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
 
 	notes, err := b.noteService.GetNotesByType(ctx, user.ID, domain.NoteTypeLink)
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения ссылок: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
@@ -323,6 +269,8 @@ func (b *Bot) handleLinkNotesCommand(ctx context.Context, chatID, userID int64)
 		return
 	}
 
+	loc := b.userLocation(user)
+
 	var response strings.Builder
 	response.WriteString("🔗 *Сохраненные ссылки:*\n\n")
 
@@ -339,21 +287,17 @@ func (b *Bot) handleLinkNotesCommand(ctx context.Context, chatID, userID int64)
 		if note.IsFavorite {
 			response.WriteString("⭐")
 		}
-		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.Format("02.01.2006")))
+		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.In(loc).Format("02.01.2006")))
 	}
 
 	msg := tgbotapi.NewMessage(chatID, response.String())
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleFileNotesCommand обрабатывает команду /files
-func (b *Bot) handleFileNotesCommand(ctx context.Context, chatID, userID int64) {
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
+func (b *Bot) handleFileNotesCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
 
 	// Получаем заметки с файлами (документы, изображения, видео, аудио)
 	var allFiles []*domain.Note
@@ -361,7 +305,7 @@ func (b *Bot) handleFileNotesCommand(ctx context.Context, chatID, userID int64)
 	for _, noteType := range []domain.NoteType{domain.NoteTypeDocument, domain.NoteTypeImage, domain.NoteTypeVideo, domain.NoteTypeAudio} {
 		notes, err := b.noteService.GetNotesByType(ctx, user.ID, noteType)
 		if err != nil {
-			b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка получения файлов: %s", err.Error()))
+			b.sendError(chatID, err)
 			return
 		}
 		allFiles = append(allFiles, notes...)
@@ -372,6 +316,8 @@ func (b *Bot) handleFileNotesCommand(ctx context.Context, chatID, userID int64)
 		return
 	}
 
+	loc := b.userLocation(user)
+
 	var response strings.Builder
 	response.WriteString("📎 *Сохраненные файлы:*\n\n")
 
@@ -392,24 +338,17 @@ func (b *Bot) handleFileNotesCommand(ctx context.Context, chatID, userID int64)
 		if note.IsFavorite {
 			response.WriteString("⭐")
 		}
-		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.Format("02.01.2006")))
+		response.WriteString(fmt.Sprintf("📅 %s\n\n", note.CreatedAt.In(loc).Format("02.01.2006")))
 	}
 
 	msg := tgbotapi.NewMessage(chatID, response.String())
 	msg.ParseMode = "Markdown"
-	b.api.Send(msg)
+	b.sendChattable(msg)
 }
 
 // handleCreateNoteFromFile создает заметку из файла
-func (b *Bot) handleCreateNoteFromFile(ctx context.Context, message *tgbotapi.Message) {
+func (b *Bot) handleCreateNoteFromFile(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
 	chatID := message.Chat.ID
-	userID := message.From.ID
-
-	user, err := b.getUserFromTelegram(ctx, userID)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка авторизации")
-		return
-	}
 
 	var fileID, fileName string
 	var fileSize int64
@@ -473,7 +412,7 @@ func (b *Bot) handleCreateNoteFromFile(ctx context.Context, message *tgbotapi.Me
 
 	note, err := b.noteService.CreateNoteFromFile(ctx, user.ID, title, fileID, fileName, fileSize, noteType, "general", "")
 	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("❌ Ошибка создания заметки: %s", err.Error()))
+		b.sendError(chatID, err)
 		return
 	}
 
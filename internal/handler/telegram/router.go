@@ -0,0 +1,221 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"todolist/internal/callbackdata"
+	"todolist/internal/domain"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandHandler обрабатывает команду. user - пользователь с действующей сессией,
+// nil только для публичных команд.
+type commandHandler func(ctx context.Context, message *tgbotapi.Message, user *domain.User)
+
+// callbackHandler обрабатывает нажатие inline-кнопки авторизованным пользователем.
+// data - разобранные данные кнопки: ID и аргументы уже проверены кодеком.
+type callbackHandler func(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data, user *domain.User)
+
+// routeRequest - данные обновления, общие для команд и callback.
+// Middleware заполняют user и могут прервать обработку, не вызывая следующий обработчик.
+type routeRequest struct {
+	route   *route
+	chatID  int64
+	from    *tgbotapi.User
+	message *tgbotapi.Message
+	query   *tgbotapi.CallbackQuery
+	data    callbackdata.Data
+	user    *domain.User
+}
+
+// routeHandler - обработчик в цепочке middleware
+type routeHandler func(ctx context.Context, req *routeRequest)
+
+// middleware оборачивает обработчик: проверяет запрос до вызова next или наблюдает за его выполнением
+type middleware func(next routeHandler) routeHandler
+
+// usage - вариант вызова команды для справки: аргументы и описание
+type usage struct {
+	args        string
+	description string
+}
+
+// route описывает команду или callback и правила доступа к ним
+type route struct {
+	// name - команда без "/" или пара сущность:действие кнопки
+	name string
+	// aliases - другие имена команды
+	aliases []string
+	// section - раздел справки; пустой - команда не показывается в /help
+	section string
+	// usage - варианты вызова для справки
+	usage []usage
+	// details - дополнительные строки справки, например примеры аргументов
+	details string
+	// public - команда доступна без авторизации
+	public bool
+	// admin - команда доступна только администраторам
+	admin bool
+
+	// kind - "command" или "callback", используется в логах и метриках
+	kind   string
+	handle routeHandler
+}
+
+// title возвращает имя маршрута для логов и метрик
+func (r *route) title() string {
+	switch r.kind {
+	case "command":
+		return "/" + r.name
+	default:
+		return r.name
+	}
+}
+
+// router сопоставляет команды и кнопки с обработчиками и пропускает их через цепочку middleware.
+// Маршруты регистрируются один раз при создании бота, поэтому повторная регистрация - ошибка программы.
+type router struct {
+	middlewares []middleware
+
+	commands     map[string]*route
+	commandOrder []*route
+	// callbacks - обработчики кнопок по ключу сущность:действие
+	callbacks map[string]*route
+}
+
+// newRouter создает маршрутизатор. Middleware применяются в порядке перечисления: первый - внешний.
+func newRouter(middlewares ...middleware) *router {
+	return &router{
+		middlewares: middlewares,
+		commands:    make(map[string]*route),
+		callbacks:   make(map[string]*route),
+	}
+}
+
+// command регистрирует команду с метаданными rt
+func (r *router) command(rt route, handler commandHandler) {
+	rt.kind = "command"
+	r.register(&rt, func(ctx context.Context, req *routeRequest) {
+		handler(ctx, req.message, req.user)
+	})
+
+	for _, name := range append([]string{rt.name}, rt.aliases...) {
+		if _, ok := r.commands[name]; ok {
+			panic(fmt.Sprintf("command /%s registered twice", name))
+		}
+		r.commands[name] = &rt
+	}
+	r.commandOrder = append(r.commandOrder, &rt)
+}
+
+// callback регистрирует обработчик кнопок с действием action над сущностью entity
+func (r *router) callback(entity callbackdata.Entity, action callbackdata.Action, handler callbackHandler) {
+	key := callbackdata.Key(entity, action)
+	if _, ok := r.callbacks[key]; ok {
+		panic(fmt.Sprintf("callback %s registered twice", key))
+	}
+
+	rt := &route{name: key, kind: "callback"}
+	r.register(rt, wrapCallback(handler))
+	r.callbacks[key] = rt
+}
+
+// register строит цепочку middleware вокруг обработчика маршрута
+func (r *router) register(rt *route, handler routeHandler) {
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	rt.handle = handler
+}
+
+// handleCommand выполняет команду из сообщения. Возвращает false, если команда не зарегистрирована.
+func (r *router) handleCommand(ctx context.Context, message *tgbotapi.Message) bool {
+	rt, ok := r.commands[message.Command()]
+	if !ok {
+		return false
+	}
+
+	rt.handle(ctx, &routeRequest{
+		route:   rt,
+		chatID:  message.Chat.ID,
+		from:    message.From,
+		message: message,
+	})
+	return true
+}
+
+// handleCallback выполняет обработчик нажатия кнопки с разобранными данными data.
+// Возвращает false, если для сущности и действия нет обработчика.
+func (r *router) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery, data callbackdata.Data) bool {
+	rt, ok := r.callbacks[data.Key()]
+	if !ok {
+		return false
+	}
+
+	rt.handle(ctx, &routeRequest{
+		route:  rt,
+		chatID: query.Message.Chat.ID,
+		from:   query.From,
+		query:  query,
+		data:   data,
+	})
+	return true
+}
+
+// help формирует справку по зарегистрированным командам: разделы в порядке sections,
+// команды внутри раздела - в порядке регистрации. Команды администраторов видны только администраторам.
+func (r *router) help(sections []string, admin bool) string {
+	var text strings.Builder
+
+	for _, section := range sections {
+		var lines strings.Builder
+		for _, rt := range r.commandOrder {
+			if rt.section != section || (rt.admin && !admin) {
+				continue
+			}
+			writeUsage(&lines, rt)
+		}
+
+		if lines.Len() == 0 {
+			continue
+		}
+
+		text.WriteString(section)
+		text.WriteString("\n")
+		text.WriteString(lines.String())
+		text.WriteString("\n")
+	}
+
+	return text.String()
+}
+
+// writeUsage добавляет в справку строки команды. Другие имена команды указываются в первой строке.
+func writeUsage(text *strings.Builder, rt *route) {
+	for i, u := range rt.usage {
+		text.WriteString("/" + rt.name)
+		if i == 0 {
+			for _, alias := range rt.aliases {
+				text.WriteString(", /" + alias)
+			}
+		}
+		if u.args != "" {
+			text.WriteString(" " + u.args)
+		}
+		text.WriteString(" - " + u.description + "\n")
+	}
+
+	if rt.details != "" {
+		text.WriteString(rt.details)
+		text.WriteString("\n")
+	}
+}
+
+// wrapCallback приводит обработчик callback к обработчику цепочки
+func wrapCallback(handler callbackHandler) routeHandler {
+	return func(ctx context.Context, req *routeRequest) {
+		handler(ctx, req.query, req.data, req.user)
+	}
+}
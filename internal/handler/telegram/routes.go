@@ -0,0 +1,247 @@
+package telegram
+
+import (
+	"context"
+
+	"todolist/internal/callbackdata"
+	"todolist/internal/domain"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Разделы справки в порядке вывода
+const (
+	sectionAuth     = "🔐 *Авторизация:*"
+	sectionAdmin    = "👑 *Администрирование:*"
+	sectionTasks    = "📝 *Работа с задачами:*"
+	sectionNotes    = "📚 *Работа с заметками:*"
+	sectionDue      = "⏰ *Сроки и уведомления:*"
+	sectionRepeat   = "🔁 *Повторение задач:*"
+	sectionTimezone = "🌍 *Часовой пояс:*"
+	sectionOther    = "🔧 *Прочее:*"
+)
+
+var helpSections = []string{
+	sectionAuth, sectionAdmin, sectionTasks, sectionNotes,
+	sectionDue, sectionRepeat, sectionTimezone, sectionOther,
+}
+
+// helpFooter - часть справки, не относящаяся к командам
+const helpFooter = `💡 *Быстрое создание:*
+Просто отправьте текст - он станет новой задачей!
+В тексте можно указать маркеры:
+   • !high, !medium, !low - приоритет
+   • #тег - теги задачи
+   • @завтра 10:00 - срок выполнения
+   • ~30m, ~1h30m - оценка времени
+   Пример: Позвонить в банк !high #finance @завтра 10:00 ~30m
+Отправьте документ/изображение - станет заметкой!
+
+📋 *Приоритеты задач:*
+🔴 высокий | 🟡 средний | 🟢 низкий`
+
+// newBotRouter создает маршрутизатор со всеми командами и кнопками бота.
+// Новая команда регистрируется здесь и автоматически появляется в /help.
+func (b *Bot) newBotRouter() *router {
+	r := newRouter(
+		b.recoverMiddleware,
+		b.observeMiddleware,
+		b.rateLimitMiddleware(newUserRateLimiter(b.config.Bot.UserRateLimit, b.config.Bot.UserRateBurst)),
+		b.authMiddleware,
+		b.adminMiddleware,
+	)
+
+	// Авторизация
+	r.command(route{name: "start", section: sectionAuth, public: true, usage: []usage{
+		{"пароль", "авторизация в системе"},
+		{"код", "регистрация по приглашению"},
+	}}, b.handleStartCommand)
+	r.command(route{name: "password", section: sectionAuth, usage: []usage{
+		{"пароль", "установить личный пароль"},
+	}}, b.handlePasswordCommand)
+	r.command(route{name: "2fa", section: sectionAuth, usage: []usage{
+		{"", "двухфакторная аутентификация: /2fa on, /2fa confirm КОД, /2fa off КОД, /2fa recovery КОД"},
+	}}, b.handleTwoFactorCommand)
+	r.command(route{name: "sessions", section: sectionAuth, usage: []usage{
+		{"", "ваши сессии во всех чатах, завершение сессии и выход везде"},
+	}}, b.handleSessionsCommand)
+
+	// Администрирование
+	r.command(route{name: "invite", section: sectionAdmin, admin: true, usage: []usage{
+		{"", "выдать приглашение"},
+	}}, b.handleInviteCommand)
+	r.command(route{name: "users", section: sectionAdmin, admin: true, usage: []usage{
+		{"", "список пользователей"},
+	}}, b.handleUsersCommand)
+	r.command(route{name: "ban", section: sectionAdmin, admin: true, usage: []usage{
+		{"ID", "заблокировать пользователя"},
+	}}, b.handleBanCommand(false))
+	r.command(route{name: "unban", section: sectionAdmin, admin: true, usage: []usage{
+		{"ID", "разблокировать пользователя"},
+	}}, b.handleBanCommand(true))
+	r.command(route{name: "allsessions", section: sectionAdmin, admin: true, usage: []usage{
+		{"", "сессии всех пользователей"},
+	}}, b.handleAllSessionsCommand)
+	r.command(route{name: "revoke", section: sectionAdmin, admin: true, usage: []usage{
+		{"ID", "завершить сессии пользователя"},
+	}}, b.handleRevokeSessionsCommand)
+	r.command(route{name: "broadcast", section: sectionAdmin, admin: true, usage: []usage{
+		{"текст", "рассылка всем пользователям"},
+	}}, b.handleBroadcastCommand)
+	r.command(route{name: "stats", section: sectionAdmin, admin: true, usage: []usage{
+		{"", "общая статистика"},
+	}}, b.handleStatsCommand)
+
+	// Задачи
+	r.command(route{name: "tasks", aliases: []string{"list"}, section: sectionTasks, usage: []usage{
+		{"", "показать все задачи"},
+	}}, b.handleListTasksCommand)
+	r.command(route{name: "pending", section: sectionTasks, usage: []usage{
+		{"", "показать невыполненные задачи"},
+	}}, b.handlePendingTasksCommand)
+	r.command(route{name: "completed", section: sectionTasks, usage: []usage{
+		{"", "показать выполненные задачи"},
+	}}, b.handleCompletedTasksCommand)
+	r.command(route{name: "overdue", section: sectionTasks, usage: []usage{
+		{"", "показать просроченные задачи"},
+	}}, b.handleOverdueTasksCommand)
+	r.command(route{name: "add", aliases: []string{"new"}, section: sectionTasks, usage: []usage{
+		{"название", "создать новую задачу"},
+	}}, b.handleAddTaskCommand)
+	r.command(route{name: "complete", aliases: []string{"done"}, section: sectionTasks, usage: []usage{
+		{"ID", "отметить задачу как выполненную"},
+		{"ID force", "выполнить задачу вместе с подзадачами"},
+	}}, b.handleCompleteTaskCommand)
+	r.command(route{name: "delete", aliases: []string{"del"}, section: sectionTasks, usage: []usage{
+		{"ID", "удалить задачу"},
+	}}, b.handleDeleteTaskCommand)
+	r.command(route{name: "show", aliases: []string{"get"}, section: sectionTasks, usage: []usage{
+		{"ID", "показать подробную информацию о задаче"},
+	}}, b.handleShowTaskCommand)
+	r.command(route{name: "sub", aliases: []string{"subtask"}, section: sectionTasks, usage: []usage{
+		{"ID название", "добавить подзадачу (пункт чек-листа)"},
+	}}, b.handleAddSubtaskCommand)
+
+	// Заметки
+	r.command(route{name: "notes", section: sectionNotes, usage: []usage{
+		{"", "показать все заметки"},
+	}}, b.handleListNotesCommand)
+	r.command(route{name: "note", section: sectionNotes, usage: []usage{
+		{"заголовок", "создать новую заметку"},
+	}}, b.handleAddNoteCommand)
+	r.command(route{name: "nshow", section: sectionNotes, usage: []usage{
+		{"ID", "показать заметку"},
+	}}, b.handleShowNoteCommand)
+	r.command(route{name: "ndelete", section: sectionNotes, usage: []usage{
+		{"ID", "удалить заметку"},
+	}}, b.handleDeleteNoteCommand)
+	r.command(route{name: "favorites", section: sectionNotes, usage: []usage{
+		{"", "показать избранные заметки"},
+	}}, b.handleFavoriteNotesCommand)
+	r.command(route{name: "favorite", section: sectionNotes, usage: []usage{
+		{"ID", "добавить/убрать из избранного"},
+	}}, b.handleToggleFavoriteCommand)
+	r.command(route{name: "search", section: sectionNotes, usage: []usage{
+		{"запрос", "поиск заметок"},
+	}}, b.handleSearchNotesCommand)
+	r.command(route{name: "links", section: sectionNotes, usage: []usage{
+		{"", "показать все ссылки"},
+	}}, b.handleLinkNotesCommand)
+	r.command(route{name: "files", section: sectionNotes, usage: []usage{
+		{"", "показать все файлы"},
+	}}, b.handleFileNotesCommand)
+
+	// Сроки, напоминания и повторение
+	r.command(route{name: "due", section: sectionDue, usage: []usage{
+		{"ID время", "установить срок выполнения (напоминание придет заранее)"},
+		{"ID off", "убрать срок выполнения"},
+	}}, b.handleSetDueDateCommand)
+	r.command(route{name: "notify", section: sectionDue, usage: []usage{
+		{"ID время", "добавить напоминание"},
+	}, details: `   Примеры времени:
+   • 15:30 - сегодня в 15:30
+   • завтра 10:00, послезавтра утром
+   • через 2 часа, через 3 дня, in 30 min
+   • в пятницу в 9, next monday
+   • 25.12 14:00, 25 декабря, конец месяца`}, b.handleSetNotificationCommand)
+	r.command(route{name: "repeat", section: sectionRepeat, usage: []usage{
+		{"ID правило", "повторять задачу после выполнения"},
+	}, details: `   Примеры правил:
+   • daily, weekdays, weekly - каждый день, по будням, каждую неделю
+   • every 3 days - каждые 3 дня
+   • monthly 15 - каждый месяц 15-го числа
+   • FREQ=WEEKLY;BYDAY=MO,TH - правило RRULE
+   • weekdays 09:30 - время следующего напоминания
+   • off - отключить повторение`}, b.handleSetRecurrenceCommand)
+	r.command(route{name: "timezone", aliases: []string{"tz"}, section: sectionTimezone, usage: []usage{
+		{"", "показать часовой пояс или определить его по геопозиции"},
+		{"Europe/Berlin", "установить часовой пояс"},
+	}, details: "   Все время вводится и показывается в вашем часовом поясе"}, b.handleTimezoneCommand)
+
+	// Прочее
+	r.command(route{name: "help", section: sectionOther, usage: []usage{
+		{"", "показать эту справку"},
+	}}, b.handleHelpCommand)
+	r.command(route{name: "cancel", section: sectionOther, usage: []usage{
+		{"", "прервать текущее действие (создание задачи, заметки и т.п.)"},
+	}}, b.handleCancelCommand)
+	r.command(route{name: "logout", section: sectionOther, usage: []usage{
+		{"", "выйти из системы в текущем чате"},
+	}}, b.handleLogoutCommand)
+
+	// Кнопки главного меню
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionMain, b.handleMenuCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionTasks, b.handleTasksCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionAddTask, b.handleAddTaskCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionNotes, b.handleNotesCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionAddNote, b.handleAddNoteCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionPending, b.handlePendingCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionCompleted, b.handleCompletedCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionSearch, b.handleSearchCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionFavorites, b.handleFavoritesCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionHelp, b.handleHelpCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionLogout, b.handleLogoutCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionLogoutAll, b.handleLogoutAllCallback)
+	r.callback(callbackdata.EntityMenu, callbackdata.ActionCancel, b.handleCancelCallback)
+
+	// Кнопки задач
+	r.callback(callbackdata.EntityTask, callbackdata.ActionComplete, b.handleCompleteTaskCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionShow, b.handleShowTaskCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionDelete, b.handleDeleteTaskCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionNotify, b.handleNotifyTaskCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionSnooze, b.handleSnoozeCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionAddSubtask, b.handleAddSubtaskCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionRecurrence, b.handleRecurrenceMenuCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionRepeat, b.handleRecurrenceCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionPriority, b.handlePriorityCallback)
+	r.callback(callbackdata.EntityTask, callbackdata.ActionConfirm, b.handleConfirmCallback)
+	r.callback(callbackdata.EntitySubtask, callbackdata.ActionToggle, b.handleToggleSubtaskCallback)
+	r.callback(callbackdata.EntityReminder, callbackdata.ActionRemove, b.handleRemoveReminderCallback)
+
+	// Кнопки заметок
+	r.callback(callbackdata.EntityNote, callbackdata.ActionShow, b.handleShowNoteCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionDelete, b.handleDeleteNoteCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionDownload, b.handleDownloadNoteCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionFavoriteAdd, b.handleAddFavoriteCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionFavoriteRemove, b.handleRemoveFavoriteCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionCategory, b.handleCategoryCallback)
+	r.callback(callbackdata.EntityNote, callbackdata.ActionConfirm, b.handleConfirmCallback)
+
+	// Сессии и подтверждение выхода
+	r.callback(callbackdata.EntitySession, callbackdata.ActionRemove, b.handleRevokeSessionCallback)
+	r.callback(callbackdata.EntitySession, callbackdata.ActionConfirm, b.handleConfirmCallback)
+
+	return r
+}
+
+// handleHelpCommand обрабатывает команду /help. Справка строится по зарегистрированным командам,
+// команды администраторов видны только администраторам.
+func (b *Bot) handleHelpCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	helpText := "📖 *Справка по командам Todo Bot*\n\n" +
+		b.router.help(helpSections, user.IsAdmin()) +
+		helpFooter
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, helpText)
+	msg.ParseMode = "Markdown"
+	b.sendChattable(msg)
+}
@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"todolist/internal/domain"
+)
+
+// handleTimezoneCommand обрабатывает команду /timezone
+func (b *Bot) handleTimezoneCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	zone := strings.TrimSpace(message.CommandArguments())
+	if zone == "" {
+		if err := b.startState(ctx, user.ID, &UserState{Action: actionSetTimezone}); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		text := fmt.Sprintf("🌍 Текущий часовой пояс: %s\n\n", describeLocation(b.userLocation(user)))
+		text += "Отправьте название часового пояса (например, Europe/Berlin или Asia/Yekaterinburg) " +
+			"или поделитесь геопозицией, чтобы определить его автоматически.\n\n" +
+			"Отправьте \"off\", чтобы вернуть часовой пояс по умолчанию."
+
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = getLocationRequestKeyboard()
+		if _, err := b.send(msg); err != nil {
+			b.logger.Error("failed to send timezone prompt", zap.Error(err))
+		}
+		return
+	}
+
+	b.setTimezone(ctx, chatID, user, zone)
+}
+
+// handleSetTimezoneState обрабатывает ввод часового пояса после команды /timezone
+func (b *Bot) handleSetTimezoneState(ctx context.Context, message *tgbotapi.Message, user *domain.User, state *UserState) {
+	b.clearState(ctx, user.ID)
+	b.setTimezone(ctx, message.Chat.ID, user, message.Text)
+}
+
+// handleLocationMessage определяет часовой пояс по присланной геопозиции
+func (b *Bot) handleLocationMessage(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	b.clearState(ctx, user.ID)
+
+	user, err := b.userService.SetTimezoneFromLocation(ctx, user.ID, message.Location.Latitude, message.Location.Longitude)
+	if err != nil {
+		b.sendTimezoneResult(chatID, errorMessage(err))
+		return
+	}
+
+	b.sendTimezoneResult(chatID, fmt.Sprintf(
+		"✅ Часовой пояс определен по геопозиции: %s\n\n"+
+			"💡 Смещение рассчитано по долготе без учета летнего времени. "+
+			"Для точной настройки используйте /timezone Europe/Berlin",
+		describeLocation(b.userLocation(user))))
+}
+
+// setTimezone сохраняет часовой пояс пользователя и сообщает результат
+func (b *Bot) setTimezone(ctx context.Context, chatID int64, user *domain.User, zone string) {
+	user, err := b.userService.SetTimezone(ctx, user.ID, zone)
+	if err != nil {
+		b.sendTimezoneResult(chatID, errorMessage(err)+"\n\nПримеры: Europe/Moscow, Europe/Berlin, Asia/Almaty, UTC")
+		return
+	}
+
+	b.sendTimezoneResult(chatID, fmt.Sprintf("✅ Часовой пояс установлен: %s", describeLocation(b.userLocation(user))))
+}
+
+// sendTimezoneResult отправляет ответ и убирает клавиатуру запроса геопозиции
+func (b *Bot) sendTimezoneResult(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+	if _, err := b.send(msg); err != nil {
+		b.logger.Error("failed to send timezone result", zap.Error(err))
+	}
+}
+
+// userLocation возвращает часовой пояс пользователя или часовой пояс по умолчанию
+func (b *Bot) userLocation(user *domain.User) *time.Location {
+	return user.Location(b.config.Bot.Location())
+}
+
+// describeLocation форматирует часовой пояс вместе с текущим временем в нем
+func describeLocation(loc *time.Location) string {
+	now := time.Now().In(loc)
+	return fmt.Sprintf("%s (UTC%s, сейчас %s)", loc.String(), now.Format("-07:00"), now.Format("15:04"))
+}
@@ -0,0 +1,163 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"todolist/internal/domain"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+// qrCodeSize - размер PNG с QR-кодом в пикселях
+const qrCodeSize = 512
+
+// handleTwoFactorCommand обрабатывает команду /2fa:
+// без аргументов показывает статус, "on" отправляет QR-код, "confirm КОД" включает проверку,
+// "off КОД" отключает ее, "recovery КОД" выпускает новые коды восстановления
+func (b *Bot) handleTwoFactorCommand(ctx context.Context, message *tgbotapi.Message, user *domain.User) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		b.sendTwoFactorStatus(ctx, chatID, user)
+		return
+	}
+
+	// Код подтверждения или восстановления не должен оставаться в истории чата
+	code := ""
+	if len(args) > 1 {
+		code = strings.Join(args[1:], "")
+		defer b.deleteMessage(chatID, message.MessageID)
+	}
+
+	switch args[0] {
+	case "on":
+		b.handleTwoFactorEnroll(ctx, chatID, user)
+
+	case "confirm":
+		if code == "" {
+			b.sendMessage(chatID, "❌ Укажите код из приложения: /2fa confirm 123456")
+			return
+		}
+
+		codes, err := b.twoFactorService.Confirm(ctx, user, code)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		b.sendMessage(chatID, "✅ Двухфакторная аутентификация включена. Теперь после пароля бот будет запрашивать код из приложения.\n\n"+
+			formatRecoveryCodes(codes))
+
+	case "off":
+		if code == "" {
+			b.sendMessage(chatID, "❌ Укажите код из приложения или код восстановления: /2fa off 123456")
+			return
+		}
+
+		if err := b.twoFactorService.Disable(ctx, user, code); err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		b.sendMessage(chatID, "🔓 Двухфакторная аутентификация отключена, коды восстановления удалены")
+
+	case "recovery":
+		if code == "" {
+			b.sendMessage(chatID, "❌ Укажите код из приложения: /2fa recovery 123456")
+			return
+		}
+
+		codes, err := b.twoFactorService.RegenerateRecoveryCodes(ctx, user, code)
+		if err != nil {
+			b.sendError(chatID, err)
+			return
+		}
+
+		b.sendMessage(chatID, "🔄 Старые коды восстановления больше не действуют.\n\n"+formatRecoveryCodes(codes))
+
+	default:
+		b.sendMessage(chatID, "❌ Используйте: /2fa, /2fa on, /2fa confirm КОД, /2fa off КОД, /2fa recovery КОД")
+	}
+}
+
+// sendTwoFactorStatus показывает, включена ли двухфакторная аутентификация
+func (b *Bot) sendTwoFactorStatus(ctx context.Context, chatID int64, user *domain.User) {
+	if !user.TOTPEnabled {
+		text := "🔐 Двухфакторная аутентификация выключена.\n\n" +
+			"После включения для входа понадобится не только пароль, но и код из приложения-аутентификатора " +
+			"(Google Authenticator, Aegis, 1Password и др.).\n\n/2fa on - подключить"
+		b.sendMessage(chatID, text)
+		return
+	}
+
+	count, err := b.twoFactorService.CountRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("🔐 Двухфакторная аутентификация включена.\nНеиспользованных кодов восстановления: %d\n\n"+
+		"/2fa recovery КОД - выпустить новые коды восстановления\n/2fa off КОД - отключить", count)
+	b.sendMessage(chatID, text)
+}
+
+// handleTwoFactorEnroll создает секрет и отправляет QR-код для приложения-аутентификатора.
+// QR-код генерируется локально, секрет не передается сторонним сервисам.
+func (b *Bot) handleTwoFactorEnroll(ctx context.Context, chatID int64, user *domain.User) {
+	enrollment, err := b.twoFactorService.Enroll(ctx, user)
+	if err != nil {
+		b.sendError(chatID, err)
+		return
+	}
+
+	png, err := qrcode.Encode(enrollment.URI, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		b.sendError(chatID, fmt.Errorf("failed to generate qr code: %w", err))
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "2fa.png", Bytes: png})
+	photo.Caption = fmt.Sprintf("📱 Отсканируйте QR-код в приложении-аутентификаторе "+
+		"или введите секрет вручную:\n%s\n\n"+
+		"Затем отправьте код из приложения: /2fa confirm 123456\n\n"+
+		"⚠️ Удалите это сообщение после подключения.", enrollment.Secret)
+
+	if _, err := b.send(photo); err != nil {
+		b.sendError(chatID, fmt.Errorf("failed to send qr code: %w", err))
+	}
+}
+
+// handleSecondFactorCode принимает код двухфакторной аутентификации от пользователя без действующей сессии
+func (b *Bot) handleSecondFactorCode(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	// Код восстановления одноразовый, но и его, и код из приложения из истории чата удаляем
+	defer b.deleteMessage(chatID, message.MessageID)
+
+	user, err := b.authService.VerifySecondFactor(ctx, message.From.ID, chatID, message.Text)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			b.sendMessage(chatID, "🔐 Для использования бота необходимо авторизоваться. Используйте команду /start")
+			return
+		}
+		b.sendError(chatID, err)
+		return
+	}
+
+	b.sendWelcome(chatID, user)
+}
+
+// formatRecoveryCodes форматирует коды восстановления с подсказкой, как их хранить
+func formatRecoveryCodes(codes []string) string {
+	text := "🆘 Коды восстановления - каждый можно использовать один раз вместо кода из приложения, " +
+		"если телефон потерян. Сохраните их в надежном месте и удалите это сообщение:\n\n"
+	for _, code := range codes {
+		text += code + "\n"
+	}
+	return text
+}
@@ -0,0 +1,192 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// webhookSecretHeader - заголовок, в котором Telegram передает секрет, указанный при регистрации webhook
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// maxWebhookBodySize - ограничение размера тела запроса: обновления Telegram намного меньше
+	maxWebhookBodySize = 1 << 20
+	// webhookReadHeaderTimeout - защита от медленных клиентов, держащих соединение
+	webhookReadHeaderTimeout = 10 * time.Second
+	// webhookDispatchTimeout - сколько запрос ждет места в очереди воркера, прежде чем
+	// ответить 503: Telegram повторит обновление, а не будет ждать ответа до своего таймаута
+	webhookDispatchTimeout = 5 * time.Second
+)
+
+// webhookAllowedUpdates - типы обновлений, которые обрабатывает бот
+var webhookAllowedUpdates = []string{"message", "callback_query"}
+
+// webhookHandler принимает обновления Telegram по HTTP и передает их в dispatch.
+// Не зависит от Bot, поэтому его можно проверить, отправив записанный JSON обновления на локальный сервер.
+type webhookHandler struct {
+	secret          string
+	dispatch        func(ctx context.Context, update tgbotapi.Update) bool
+	dispatchTimeout time.Duration
+	logger          *zap.Logger
+}
+
+// newWebhookHandler создает обработчик webhook. Запросы без верного секрета в заголовке
+// X-Telegram-Bot-Api-Secret-Token отклоняются.
+func newWebhookHandler(secret string, dispatch func(ctx context.Context, update tgbotapi.Update) bool, logger *zap.Logger) http.Handler {
+	return &webhookHandler{
+		secret:          secret,
+		dispatch:        dispatch,
+		dispatchTimeout: webhookDispatchTimeout,
+		logger:          logger,
+	}
+}
+
+// ServeHTTP обрабатывает запрос Telegram с одним обновлением.
+// Ответ 503 означает, что обновление не принято, и Telegram повторит его позже.
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		h.logger.Warn("webhook request with invalid secret", zap.String("remote_addr", r.RemoteAddr))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&update); err != nil {
+		h.logger.Warn("invalid webhook update", zap.Error(err))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "update too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.dispatchTimeout)
+	defer cancel()
+
+	if !h.dispatch(ctx, update) {
+		http.Error(w, "update not accepted", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// runWebhook принимает обновления через HTTP-сервер до отмены ctx.
+// При остановке сервер дожидается запросов, которые уже передают обновления воркерам;
+// если они не успевают за BOT_SHUTDOWN_TIMEOUT, соединения закрываются, а запросы,
+// которые еще ждут места в очереди, получают 503 после остановки диспетчера.
+func (b *Bot) runWebhook(ctx context.Context, dispatcher *updateDispatcher) error {
+	cfg := b.config.Webhook
+
+	if cfg.URL != "" {
+		if err := b.registerWebhook(); err != nil {
+			return err
+		}
+		b.logger.Info("webhook registered", zap.String("url", cfg.URL))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, newWebhookHandler(cfg.Secret, dispatcher.Dispatch, b.logger))
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLSCert != "" {
+			serveErr <- server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	b.logger.Info("webhook server started",
+		zap.String("listen", cfg.Listen),
+		zap.String("path", cfg.Path),
+		zap.Bool("tls", cfg.TLSCert != ""))
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("webhook server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	b.logger.Info("bot stopping...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), b.config.Bot.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		b.logger.Error("failed to shutdown webhook server", zap.Error(err))
+		if err := server.Close(); err != nil {
+			b.logger.Error("failed to close webhook server", zap.Error(err))
+		}
+	}
+
+	return ctx.Err()
+}
+
+// validWebhookSecret проверяет секрет по правилам Telegram: 1-256 символов A-Z, a-z, 0-9, _ и -
+func validWebhookSecret(secret string) bool {
+	if secret == "" || len(secret) > 256 {
+		return false
+	}
+	for _, r := range secret {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// registerWebhook регистрирует webhook в Telegram вместе с секретом.
+// Библиотека не поддерживает secret_token, поэтому запрос собирается вручную.
+func (b *Bot) registerWebhook() error {
+	cfg := b.config.Webhook
+
+	params := tgbotapi.Params{
+		"url":          cfg.URL,
+		"secret_token": cfg.Secret,
+	}
+	if err := params.AddInterface("allowed_updates", webhookAllowedUpdates); err != nil {
+		return fmt.Errorf("failed to build webhook params: %w", err)
+	}
+
+	var err error
+	if cfg.UploadCert {
+		// Самоподписанный сертификат нужно передать в Telegram, иначе он не доверяет серверу
+		_, err = b.api.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(cfg.TLSCert),
+		}})
+	} else {
+		_, err = b.api.MakeRequest("setWebhook", params)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const testWebhookSecret = "test_secret-42"
+
+const testUpdate = `{"update_id":7,"message":{"message_id":1,"from":{"id":42},"chat":{"id":42,"type":"private"},"text":"купить молоко"}}`
+
+// postUpdate отправляет тело на webhook-сервер с заданным секретом и возвращает код ответа
+func postUpdate(t *testing.T, url, secret, body string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if secret != "" {
+		req.Header.Set(webhookSecretHeader, secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post update: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var dispatched []tgbotapi.Update
+	handler := newWebhookHandler(testWebhookSecret, func(ctx context.Context, update tgbotapi.Update) bool {
+		dispatched = append(dispatched, update)
+		return true
+	}, zap.NewNop())
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   int
+	}{
+		{name: "без секрета", secret: "", body: testUpdate, want: http.StatusUnauthorized},
+		{name: "неверный секрет", secret: "wrong", body: testUpdate, want: http.StatusUnauthorized},
+		{name: "слишком большое тело", secret: testWebhookSecret, body: `{"update_id":1,"x":"` + strings.Repeat("a", maxWebhookBodySize) + `"}`, want: http.StatusRequestEntityTooLarge},
+		{name: "не JSON", secret: testWebhookSecret, body: "hello", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postUpdate(t, server.URL, tt.secret, tt.body); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if len(dispatched) != 0 {
+		t.Fatalf("rejected requests dispatched %d updates", len(dispatched))
+	}
+
+	if got := postUpdate(t, server.URL, testWebhookSecret, testUpdate); got != http.StatusOK {
+		t.Fatalf("status = %d, want %d", got, http.StatusOK)
+	}
+	if len(dispatched) != 1 || dispatched[0].UpdateID != 7 || dispatched[0].Message.Text != "купить молоко" {
+		t.Fatalf("dispatched = %+v, want update 7", dispatched)
+	}
+}
+
+// TestWebhookHandlerQueueFull проверяет, что при заполненной очереди воркера запрос
+// получает 503, чтобы Telegram повторил обновление позже
+func TestWebhookHandlerQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	dispatcher := newUpdateDispatcher(context.Background(), 1, 0, func(ctx context.Context, update tgbotapi.Update) {
+		<-release
+	}, zap.NewNop())
+	defer dispatcher.Shutdown(time.Second)
+	defer close(release)
+
+	handler := &webhookHandler{
+		secret:          testWebhookSecret,
+		dispatch:        dispatcher.Dispatch,
+		dispatchTimeout: 50 * time.Millisecond,
+		logger:          zap.NewNop(),
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// Первое обновление занимает единственного воркера
+	if got := postUpdate(t, server.URL, testWebhookSecret, testUpdate); got != http.StatusOK {
+		t.Fatalf("first update status = %d, want %d", got, http.StatusOK)
+	}
+
+	if got := postUpdate(t, server.URL, testWebhookSecret, testUpdate); got != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
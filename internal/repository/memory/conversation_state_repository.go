@@ -0,0 +1,74 @@
+// Package memory содержит реализации репозиториев в памяти процесса.
+// Данные теряются при перезапуске и не разделяются между экземплярами бота.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"todolist/internal/domain"
+)
+
+// ConversationStateRepositoryImpl реализует интерфейс ConversationStateRepository в памяти
+type ConversationStateRepositoryImpl struct {
+	mu     sync.Mutex
+	states map[int64]domain.ConversationState
+}
+
+// NewConversationStateRepository создает новый экземпляр ConversationStateRepositoryImpl
+func NewConversationStateRepository() domain.ConversationStateRepository {
+	return &ConversationStateRepositoryImpl{
+		states: make(map[int64]domain.ConversationState),
+	}
+}
+
+// Get возвращает копию состояния диалога, чтобы изменения вызывающего кода
+// не были видны другим обработчикам до Save
+func (r *ConversationStateRepositoryImpl) Get(ctx context.Context, userID int64) (*domain.ConversationState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[userID]
+	if !ok {
+		return nil, domain.NotFound(domain.EntityConversation, userID)
+	}
+
+	state.Data = append([]byte(nil), state.Data...)
+	return &state, nil
+}
+
+// Save сохраняет копию состояния диалога, заменяя предыдущее
+func (r *ConversationStateRepositoryImpl) Save(ctx context.Context, state *domain.ConversationState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *state
+	saved.Data = append([]byte(nil), state.Data...)
+	r.states[state.UserID] = saved
+	return nil
+}
+
+// Delete удаляет состояние диалога пользователя
+func (r *ConversationStateRepositoryImpl) Delete(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.states, userID)
+	return nil
+}
+
+// DeleteExpiredBefore удаляет диалоги, истекшие раньше before
+func (r *ConversationStateRepositoryImpl) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for userID, state := range r.states {
+		if state.ExpiresAt.Before(before) {
+			delete(r.states, userID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ConversationStateRepositoryImpl реализует интерфейс ConversationStateRepository
+type ConversationStateRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewConversationStateRepository создает новый экземпляр ConversationStateRepositoryImpl
+func NewConversationStateRepository(db *Database) domain.ConversationStateRepository {
+	return &ConversationStateRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Get получает состояние диалога пользователя
+func (r *ConversationStateRepositoryImpl) Get(ctx context.Context, userID int64) (*domain.ConversationState, error) {
+	query, args, err := r.sq.
+		Select("user_id", "action", "step", "data", "updated_at", "expires_at").
+		From("conversation_states").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	state := &domain.ConversationState{}
+	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(
+		&state.UserID,
+		&state.Action,
+		&state.Step,
+		&state.Data,
+		&state.UpdatedAt,
+		&state.ExpiresAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NotFound(domain.EntityConversation, userID)
+		}
+		return nil, fmt.Errorf("failed to get conversation state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Save сохраняет состояние диалога, заменяя предыдущее
+func (r *ConversationStateRepositoryImpl) Save(ctx context.Context, state *domain.ConversationState) error {
+	data := string(state.Data)
+	if data == "" {
+		data = "{}"
+	}
+
+	query, args, err := r.sq.
+		Insert("conversation_states").
+		Columns("user_id", "action", "step", "data", "updated_at", "expires_at").
+		Values(state.UserID, state.Action, state.Step, data, utc(state.UpdatedAt), utc(state.ExpiresAt)).
+		Suffix(`ON CONFLICT (user_id)
+			DO UPDATE SET
+				action = EXCLUDED.action,
+				step = EXCLUDED.step,
+				data = EXCLUDED.data,
+				updated_at = EXCLUDED.updated_at,
+				expires_at = EXCLUDED.expires_at`).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	_, err = r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to save conversation state: %w", dbError(err))
+	}
+
+	return nil
+}
+
+// Delete удаляет состояние диалога пользователя
+func (r *ConversationStateRepositoryImpl) Delete(ctx context.Context, userID int64) error {
+	query, args, err := r.sq.
+		Delete("conversation_states").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	_, err = r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation state: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredBefore удаляет диалоги, истекшие раньше before
+func (r *ConversationStateRepositoryImpl) DeleteExpiredBefore(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := r.sq.
+		Delete("conversation_states").
+		Where(squirrel.Lt{"expires_at": utc(before)}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired conversation states: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestConversationStateDeleteExpiredBefore проверяет, что очистка удаляет только
+// диалоги, истекшие раньше заданного момента
+func TestConversationStateDeleteExpiredBefore(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	repo := postgres.NewConversationStateRepository(db)
+
+	now := time.Now()
+	abandoned := createUser(t, db, 3001)
+	recent := createUser(t, db, 3002)
+	active := createUser(t, db, 3003)
+
+	states := map[int64]time.Time{
+		abandoned: now.Add(-48 * time.Hour),
+		recent:    now.Add(-time.Hour),
+		active:    now.Add(time.Hour),
+	}
+	for userID, expiresAt := range states {
+		state := &domain.ConversationState{
+			UserID: userID, Action: "add_task", Step: 1, UpdatedAt: now, ExpiresAt: expiresAt,
+		}
+		if err := repo.Save(ctx, state); err != nil {
+			t.Fatalf("save state: %v", err)
+		}
+	}
+
+	deleted, err := repo.DeleteExpiredBefore(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("delete expired: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted %d states, want 1", deleted)
+	}
+
+	if _, err := repo.Get(ctx, abandoned); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("abandoned state: got %v, want ErrNotFound", err)
+	}
+	for _, userID := range []int64{recent, active} {
+		if _, err := repo.Get(ctx, userID); err != nil {
+			t.Errorf("state of user %d should be kept: %v", userID, err)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"todolist/config"
+	"todolist/internal/domain"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Database представляет подключение к базе данных
@@ -16,7 +19,9 @@ type Database struct {
 
 // NewDatabase создает новое подключение к базе данных
 func NewDatabase(cfg *config.DatabaseConfig) (*Database, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+	// Все временные метки хранятся в UTC, часовой пояс пользователя
+	// применяется только при разборе ввода и отображении
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode)
 
 	db, err := sql.Open("postgres", dsn)
@@ -36,51 +41,49 @@ func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
-// CreateTables создает необходимые таблицы в базе данных
-func (d *Database) CreateTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			telegram_id BIGINT UNIQUE NOT NULL,
-			username VARCHAR(255),
-			first_name VARCHAR(255),
-			last_name VARCHAR(255),
-			is_active BOOLEAN DEFAULT true,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			last_login_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS sessions (
-			user_id BIGINT NOT NULL,
-			telegram_id BIGINT NOT NULL PRIMARY KEY,
-			is_active BOOLEAN DEFAULT true,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			expires_at TIMESTAMP NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-		`CREATE TABLE IF NOT EXISTS tasks (
-			id SERIAL PRIMARY KEY,
-			title VARCHAR(255) NOT NULL,
-			description TEXT,
-			status VARCHAR(50) DEFAULT 'pending',
-			priority VARCHAR(50) DEFAULT 'medium',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			completed_at TIMESTAMP,
-			notify_at TIMESTAMP,
-			user_id BIGINT NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_notify_at ON tasks(notify_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_sessions_telegram_id ON sessions(telegram_id)`,
+// utc приводит время к UTC перед записью в базу
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// utcPtr приводит необязательное время к UTC, сохраняя nil
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// dbError переводит ошибки ограничений PostgreSQL в доменные ошибки:
+// нарушение уникальности - ErrConflict, внешнего ключа - ErrNotFound, CHECK и NOT NULL - ErrValidation
+func dbError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return fmt.Errorf("%w: %w", domain.ErrConflict, err)
+	case "foreign_key_violation":
+		return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+	case "check_violation", "not_null_violation":
+		return fmt.Errorf("%w: %w", domain.ErrValidation, err)
+	}
+
+	return err
+}
+
+// requireAffected возвращает ErrNotFound, если запрос не изменил ни одной строки
+func requireAffected(result sql.Result, entity domain.Entity, id int64) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
 
-	for _, query := range queries {
-		if _, err := d.DB.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute query: %s, error: %w", query, err)
-		}
+	if affected == 0 {
+		return domain.NotFound(entity, id)
 	}
 
 	return nil
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// InviteRepositoryImpl реализует интерфейс InviteRepository
+type InviteRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewInviteRepository создает новый экземпляр InviteRepositoryImpl
+func NewInviteRepository(db *Database) domain.InviteRepository {
+	return &InviteRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Create создает новое приглашение
+func (r *InviteRepositoryImpl) Create(ctx context.Context, invite *domain.Invite) error {
+	query, args, err := r.sq.
+		Insert("invites").
+		Columns("code_hash", "created_by", "expires_at").
+		Values(invite.CodeHash, invite.CreatedBy, utc(invite.ExpiresAt)).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %w", dbError(err))
+	}
+
+	return nil
+}
+
+// Redeem погашает приглашение и создает по нему пользователя в одной транзакции.
+// Приглашение гасится условным UPDATE, поэтому один код нельзя использовать дважды даже
+// при одновременных попытках, а если создать пользователя не удалось, код остается действующим.
+func (r *InviteRepositoryImpl) Redeem(ctx context.Context, codeHash string, now time.Time, user *domain.User) (*domain.Invite, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.
+		Update("invites").
+		Set("used_at", utc(now)).
+		Where(squirrel.Eq{"code_hash": codeHash, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": utc(now)}).
+		Suffix("RETURNING id, code_hash, COALESCE(created_by, 0), expires_at, used_at, created_at").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	invite := &domain.Invite{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&invite.ID,
+		&invite.CodeHash,
+		&invite.CreatedBy,
+		&invite.ExpiresAt,
+		&invite.UsedAt,
+		&invite.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NotFound(domain.EntityInvite, 0)
+		}
+		return nil, fmt.Errorf("failed to redeem invite: %w", err)
+	}
+
+	if err := insertUser(ctx, tx, r.sq, user); err != nil {
+		return nil, err
+	}
+
+	query, args, err = r.sq.
+		Update("invites").
+		Set("used_by", user.ID).
+		Where(squirrel.Eq{"id": invite.ID}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update invite: %w", dbError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invite: %w", err)
+	}
+
+	invite.UsedBy = &user.ID
+	return invite, nil
+}
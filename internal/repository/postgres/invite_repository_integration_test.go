@@ -0,0 +1,58 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestInviteRepositoryRedeem проверяет, что код гасится вместе с созданием пользователя:
+// при ошибке создания код остается действующим, а использовать его дважды нельзя
+func TestInviteRepositoryRedeem(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	repo := postgres.NewInviteRepository(db)
+	users := postgres.NewUserRepository(db)
+
+	admin := createUser(t, db, 5001)
+	now := time.Now()
+	invite := &domain.Invite{CodeHash: "invite-hash", CreatedBy: admin, ExpiresAt: now.Add(time.Hour)}
+	if err := repo.Create(ctx, invite); err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+
+	newUser := func(telegramID int64) *domain.User {
+		return &domain.User{TelegramID: telegramID, IsActive: true, Role: domain.UserRoleUser}
+	}
+
+	// Telegram ID уже занят: пользователь не создается, и приглашение не должно сгореть
+	if _, err := repo.Redeem(ctx, invite.CodeHash, now, newUser(5001)); err == nil || errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("redeem for an existing user: %v, want a create error", err)
+	}
+
+	user := newUser(5002)
+	redeemed, err := repo.Redeem(ctx, invite.CodeHash, now, user)
+	if err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if user.ID == 0 || redeemed.ID != invite.ID || redeemed.UsedBy == nil || *redeemed.UsedBy != user.ID {
+		t.Fatalf("redeemed = %+v for user %d", redeemed, user.ID)
+	}
+	if _, err := users.GetByTelegramID(ctx, 5002); err != nil {
+		t.Fatalf("registered user: %v", err)
+	}
+
+	if _, err := repo.Redeem(ctx, invite.CodeHash, now, newUser(5003)); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("second redeem: %v, want ErrNotFound", err)
+	}
+	if _, err := users.GetByTelegramID(ctx, 5003); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("user created by a used invite: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"todolist/internal/domain"
+)
+
+// unlockTimeout ограничивает время снятия блокировки после отмены контекста задачи
+const unlockTimeout = 5 * time.Second
+
+// AdvisoryJobLocker реализует интерфейс JobLocker на advisory-блокировках Postgres
+type AdvisoryJobLocker struct {
+	db *Database
+}
+
+// NewJobLocker создает новый экземпляр AdvisoryJobLocker
+func NewJobLocker(db *Database) domain.JobLocker {
+	return &AdvisoryJobLocker{db: db}
+}
+
+// TryLock захватывает сессионную advisory-блокировку с ключом, вычисленным из имени задачи.
+// Блокировка привязана к соединению, поэтому соединение удерживается до вызова unlock;
+// если процесс упадет, Postgres снимет блокировку при закрытии соединения.
+func (l *AdvisoryJobLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	key := advisoryKey(name)
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire lock %q: %w", name, err)
+	}
+
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Контекст задачи может быть уже отменен при остановке приложения
+		unlockCtx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// Соединение с неснятой блокировкой нельзя возвращать в пул - закрываем его
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+// advisoryKey вычисляет ключ advisory-блокировки по имени задачи
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("todolist:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// LoginAttemptRepositoryImpl реализует интерфейс LoginAttemptRepository
+type LoginAttemptRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewLoginAttemptRepository создает новый экземпляр LoginAttemptRepositoryImpl
+func NewLoginAttemptRepository(db *Database) domain.LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Create записывает попытку входа
+func (r *LoginAttemptRepositoryImpl) Create(ctx context.Context, attempt *domain.LoginAttempt) error {
+	query, args, err := r.sq.
+		Insert("login_attempts").
+		Columns("telegram_id", "success", "created_at").
+		Values(attempt.TelegramID, attempt.Success, utc(attempt.CreatedAt)).
+		Suffix("RETURNING id").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.DB.QueryRowContext(ctx, query, args...).Scan(&attempt.ID); err != nil {
+		return fmt.Errorf("failed to create login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetFailures возвращает неудачные попытки пользователя после since.
+// Успешный вход обнуляет счетчик, поэтому учитываются только попытки после него.
+func (r *LoginAttemptRepositoryImpl) GetFailures(ctx context.Context, telegramID int64, since time.Time) (*domain.LoginFailures, error) {
+	query := `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_attempts
+		WHERE telegram_id = $1
+		  AND NOT success
+		  AND created_at > $2
+		  AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE telegram_id = $1 AND success),
+			'-infinity'::timestamptz)`
+
+	var (
+		failures    domain.LoginFailures
+		lastFailure sql.NullTime
+	)
+	err := r.db.DB.QueryRowContext(ctx, query, telegramID, utc(since)).Scan(&failures.Count, &lastFailure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+
+	if lastFailure.Valid {
+		failures.LastFailure = lastFailure.Time
+	}
+
+	return &failures, nil
+}
+
+// CountFailuresSince возвращает количество неудачных попыток всех пользователей после since
+func (r *LoginAttemptRepositoryImpl) CountFailuresSince(ctx context.Context, since time.Time) (int, error) {
+	query, args, err := r.sq.
+		Select("COUNT(*)").
+		From("login_attempts").
+		Where(squirrel.Eq{"success": false}).
+		Where(squirrel.Gt{"created_at": utc(since)}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var count int
+	if err := r.db.DB.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count login failures: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteOlderThan удаляет записи о попытках входа старше before
+func (r *LoginAttemptRepositoryImpl) DeleteOlderThan(ctx context.Context, before time.Time) error {
+	query, args, err := r.sq.
+		Delete("login_attempts").
+		Where(squirrel.Lt{"created_at": utc(before)}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+
+	return nil
+}
@@ -36,14 +36,14 @@ func (r *NoteRepositoryImpl) Create(ctx context.Context, note *domain.Note) erro
 		&note.ID, &note.CreatedAt, &note.UpdatedAt)
 
 	if err != nil {
-		return fmt.Errorf("failed to create note: %w", err)
+		return fmt.Errorf("failed to create note: %w", dbError(err))
 	}
 
 	return nil
 }
 
-// GetByID получает заметку по ID
-func (r *NoteRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Note, error) {
+// GetByID получает заметку пользователя по ID
+func (r *NoteRepositoryImpl) GetByID(ctx context.Context, id int, userID int64) (*domain.Note, error) {
 	query := `
 		SELECT id, title, content, type, category, url, file_id, file_name, file_size, 
 		       tags, is_favorite, created_at, updated_at, user_id
@@ -58,9 +58,13 @@ func (r *NoteRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Note,
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("note not found")
+			return nil, domain.NotFound(domain.EntityNote, int64(id))
 		}
-		return nil, fmt.Errorf("failed to get note: %w", err)
+		return nil, fmt.Errorf("failed to get note: %w", dbError(err))
+	}
+
+	if note.UserID != userID {
+		return nil, domain.Forbidden(domain.EntityNote, int64(id))
 	}
 
 	return note, nil
@@ -153,8 +157,8 @@ func (r *NoteRepositoryImpl) Search(ctx context.Context, userID int64, query str
 	return r.scanNotes(rows)
 }
 
-// Update обновляет заметку
-func (r *NoteRepositoryImpl) Update(ctx context.Context, note *domain.Note) error {
+// Update обновляет заметку пользователя
+func (r *NoteRepositoryImpl) Update(ctx context.Context, note *domain.Note, userID int64) error {
 	note.UpdatedAt = time.Now()
 
 	query := `
@@ -162,30 +166,55 @@ func (r *NoteRepositoryImpl) Update(ctx context.Context, note *domain.Note) erro
 			title = $1, content = $2, type = $3, category = $4, url = $5,
 			file_id = $6, file_name = $7, file_size = $8, tags = $9,
 			is_favorite = $10, updated_at = $11
-		WHERE id = $12`
+		WHERE id = $12 AND user_id = $13`
 
-	_, err := r.db.DB.ExecContext(ctx, query,
+	result, err := r.db.DB.ExecContext(ctx, query,
 		note.Title, note.Content, note.Type, note.Category, note.URL,
 		note.FileID, note.FileName, note.FileSize, note.Tags,
-		note.IsFavorite, note.UpdatedAt, note.ID)
+		note.IsFavorite, utc(note.UpdatedAt), note.ID, userID)
 
 	if err != nil {
-		return fmt.Errorf("failed to update note: %w", err)
+		return fmt.Errorf("failed to update note: %w", dbError(err))
 	}
 
-	return nil
+	return r.checkAffected(ctx, result, note.ID)
 }
 
-// Delete удаляет заметку
-func (r *NoteRepositoryImpl) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM notes WHERE id = $1`
+// Delete удаляет заметку пользователя
+func (r *NoteRepositoryImpl) Delete(ctx context.Context, id int, userID int64) error {
+	query := `DELETE FROM notes WHERE id = $1 AND user_id = $2`
 
-	_, err := r.db.DB.ExecContext(ctx, query, id)
+	result, err := r.db.DB.ExecContext(ctx, query, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete note: %w", err)
+		return fmt.Errorf("failed to delete note: %w", dbError(err))
 	}
 
-	return nil
+	return r.checkAffected(ctx, result, id)
+}
+
+// checkAffected проверяет, что запрос изменил заметку, и иначе объясняет почему:
+// заметки нет (ErrNotFound) или она принадлежит другому пользователю (ErrForbidden)
+func (r *NoteRepositoryImpl) checkAffected(ctx context.Context, result sql.Result, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.db.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check note: %w", err)
+	}
+
+	if !exists {
+		return domain.NotFound(domain.EntityNote, int64(id))
+	}
+
+	return domain.Forbidden(domain.EntityNote, int64(id))
 }
 
 // scanNotes сканирует строки и возвращает массив заметок
@@ -0,0 +1,93 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestNoteRepositoryCrossUserAccess проверяет, что обращение к чужой заметке возвращает
+// ErrForbidden, а не ErrNotFound или успех, и не меняет заметку
+func TestNoteRepositoryCrossUserAccess(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	repo := postgres.NewNoteRepository(db)
+
+	owner := createUser(t, db, 2001)
+	stranger := createUser(t, db, 2002)
+
+	note := &domain.Note{
+		Title: "Договор", Type: domain.NoteTypeDocument, Category: domain.NoteCategoryGeneral,
+		FileID: "file-id", FileName: "contract.pdf", UserID: owner,
+	}
+	if err := repo.Create(ctx, note); err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+
+	attempts := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := repo.GetByID(ctx, note.ID, stranger)
+			return err
+		}},
+		{"update", func() error {
+			changed := *note
+			changed.Title = "взлом"
+			return repo.Update(ctx, &changed, stranger)
+		}},
+		{"favorite", func() error {
+			changed := *note
+			changed.IsFavorite = true
+			return repo.Update(ctx, &changed, stranger)
+		}},
+		{"delete", func() error {
+			return repo.Delete(ctx, note.ID, stranger)
+		}},
+	}
+
+	for _, attempt := range attempts {
+		t.Run(attempt.name, func(t *testing.T) {
+			err := attempt.call()
+			if !errors.Is(err, domain.ErrForbidden) || errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("error = %v, want ErrForbidden", err)
+			}
+
+			stored, err := repo.GetByID(ctx, note.ID, owner)
+			if err != nil {
+				t.Fatalf("owner lost access: %v", err)
+			}
+			if stored.Title != "Договор" || stored.IsFavorite {
+				t.Fatalf("note changed by another user: %+v", stored)
+			}
+		})
+	}
+
+	if _, err := repo.GetByID(ctx, note.ID+1000, stranger); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing note error = %v, want ErrNotFound", err)
+	}
+
+	if notes, err := repo.GetByUserID(ctx, stranger); err != nil || len(notes) != 0 {
+		t.Errorf("stranger notes = %d, %v; want none", len(notes), err)
+	}
+}
+
+// createUser создает пользователя и возвращает его ID
+func createUser(t *testing.T, db *postgres.Database, telegramID int64) int64 {
+	t.Helper()
+
+	var id int64
+	if err := db.DB.QueryRow(
+		"INSERT INTO users (telegram_id) VALUES ($1) RETURNING id", telegramID,
+	).Scan(&id); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestOutboxClaimNeverDeliversTwice запускает параллельные обработчики очереди из двух
+// "экземпляров" (отдельных пулов соединений) и проверяет, что ни одно уведомление
+// не было забрано дважды и ни одно не потерялось
+func TestOutboxClaimNeverDeliversTwice(t *testing.T) {
+	const (
+		reminders = 300
+		workers   = 4
+		batchSize = 7
+	)
+
+	ctx := context.Background()
+	first := pgtest.Open(t)
+	second := pgtest.Connect(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	seedReminders(t, first, reminders, now.Add(-time.Minute))
+
+	repos := []domain.NotificationOutboxRepository{
+		postgres.NewNotificationOutboxRepository(first),
+		postgres.NewNotificationOutboxRepository(second),
+	}
+
+	// Напоминание попадает в очередь один раз, даже если оба экземпляра ставят их одновременно
+	var enqueued [2]int64
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := repo.EnqueueDueReminders(ctx, now)
+			if err != nil {
+				t.Errorf("enqueue: %v", err)
+			}
+			enqueued[i] = count
+		}()
+	}
+	wg.Wait()
+
+	if total := enqueued[0] + enqueued[1]; total != reminders {
+		t.Fatalf("enqueued %d notifications, want %d", total, reminders)
+	}
+
+	var mu sync.Mutex
+	claimed := make(map[int64]int)
+
+	for w := 0; w < workers; w++ {
+		repo := repos[w%len(repos)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				messages, err := repo.Claim(ctx, now, batchSize)
+				if err != nil {
+					t.Errorf("claim: %v", err)
+					return
+				}
+				if len(messages) == 0 {
+					return
+				}
+
+				mu.Lock()
+				for _, message := range messages {
+					claimed[message.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != reminders {
+		t.Errorf("claimed %d distinct notifications, want %d", len(claimed), reminders)
+	}
+	for id, count := range claimed {
+		if count != 1 {
+			t.Errorf("notification %d claimed %d times", id, count)
+		}
+	}
+}
+
+// seedReminders создает пользователя, задачу и count наступивших напоминаний
+func seedReminders(t *testing.T, db *postgres.Database, count int, remindAt time.Time) {
+	t.Helper()
+
+	userID := createUser(t, db, 1001)
+
+	var taskID int64
+	if err := db.DB.QueryRow(
+		"INSERT INTO tasks (title, user_id) VALUES ('outbox', $1) RETURNING id", userID,
+	).Scan(&taskID); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// Напоминания отличаются на секунду из-за ограничения UNIQUE (task_id, remind_at)
+	if _, err := db.DB.Exec(`
+		INSERT INTO task_reminders (task_id, remind_at)
+		SELECT $1, $2::timestamptz - make_interval(secs => n)
+		FROM generate_series(1, $3) AS n`,
+		taskID, remindAt, count,
+	); err != nil {
+		t.Fatalf("failed to create reminders: %v", err)
+	}
+}
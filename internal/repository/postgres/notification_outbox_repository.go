@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// NotificationOutboxRepositoryImpl реализует интерфейс NotificationOutboxRepository
+type NotificationOutboxRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewNotificationOutboxRepository создает новый экземпляр NotificationOutboxRepositoryImpl
+func NewNotificationOutboxRepository(db *Database) domain.NotificationOutboxRepository {
+	return &NotificationOutboxRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// EnqueueDueReminders ставит наступившие напоминания активных задач в очередь отправки.
+// Пометка напоминания и создание записи в очереди выполняются одним запросом,
+// поэтому напоминание не может попасть в очередь дважды, даже при параллельном запуске.
+func (r *NotificationOutboxRepositoryImpl) EnqueueDueReminders(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		WITH due AS (
+			UPDATE task_reminders rem
+			SET sent_at = $1
+			FROM tasks t
+			WHERE t.id = rem.task_id
+				AND t.status = 'pending'
+				AND rem.sent_at IS NULL
+				AND rem.remind_at <= $1
+			RETURNING rem.id, rem.task_id
+		)
+		INSERT INTO notification_outbox (reminder_id, task_id, next_attempt_at)
+		SELECT id, task_id, $1 FROM due`
+
+	result, err := r.db.DB.ExecContext(ctx, query, utc(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue reminders: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get enqueued count: %w", err)
+	}
+
+	return count, nil
+}
+
+// Claim забирает до limit уведомлений, время попытки которых наступило, и помечает их
+// отправляемыми. Записи, заблокированные другим обработчиком, пропускаются.
+func (r *NotificationOutboxRepositoryImpl) Claim(ctx context.Context, now time.Time, limit int) ([]*domain.OutboxMessage, error) {
+	query := `
+		UPDATE notification_outbox
+		SET status = 'sending', attempts = attempts + 1, locked_at = $1
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, reminder_id, task_id, status, attempts, next_attempt_at,
+			last_error, locked_at, sent_at, created_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, utc(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanMessages(rows)
+}
+
+// Update сохраняет результат попытки отправки уведомления
+func (r *NotificationOutboxRepositoryImpl) Update(ctx context.Context, message *domain.OutboxMessage) error {
+	query, args, err := r.sq.
+		Update("notification_outbox").
+		Set("status", message.Status).
+		Set("attempts", message.Attempts).
+		Set("next_attempt_at", utc(message.NextAttemptAt)).
+		Set("last_error", message.LastError).
+		Set("locked_at", utcPtr(message.LockedAt)).
+		Set("sent_at", utcPtr(message.SentAt)).
+		Where(squirrel.Eq{"id": message.ID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err = r.db.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	return nil
+}
+
+// FailStale завершает уведомления, зависшие в отправке дольше допустимого (например,
+// после падения процесса). Неизвестно, дошло ли такое сообщение, поэтому оно не
+// отправляется повторно, чтобы пользователь не получил напоминание дважды.
+func (r *NotificationOutboxRepositoryImpl) FailStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	query, args, err := r.sq.
+		Update("notification_outbox").
+		Set("status", domain.OutboxStatusDead).
+		Set("last_error", "delivery interrupted, result unknown").
+		Set("locked_at", nil).
+		Where(squirrel.Eq{"status": domain.OutboxStatusSending}).
+		Where(squirrel.Lt{"locked_at": utc(lockedBefore)}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale notifications: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stale count: %w", err)
+	}
+
+	return count, nil
+}
+
+// scanMessages сканирует строки и возвращает массив уведомлений
+func (r *NotificationOutboxRepositoryImpl) scanMessages(rows *sql.Rows) ([]*domain.OutboxMessage, error) {
+	var messages []*domain.OutboxMessage
+
+	for rows.Next() {
+		message := &domain.OutboxMessage{}
+
+		err := rows.Scan(
+			&message.ID,
+			&message.ReminderID,
+			&message.TaskID,
+			&message.Status,
+			&message.Attempts,
+			&message.NextAttemptAt,
+			&message.LastError,
+			&message.LockedAt,
+			&message.SentAt,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,82 @@
+//go:build integration
+
+// Package pgtest подключает интеграционные тесты к тестовой базе PostgreSQL.
+//
+// База задается переменными TEST_DB_HOST, TEST_DB_PORT, TEST_DB_USER, TEST_DB_PASSWORD
+// и обязательной TEST_DB_NAME. Тесты применяют миграции и очищают таблицы, поэтому
+// рабочую базу указывать нельзя. Запуск:
+//
+//	TEST_DB_NAME=todolist_test go test -tags integration ./...
+package pgtest
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"todolist/config"
+	"todolist/internal/repository/postgres"
+	"todolist/migrations"
+)
+
+// Open подключается к тестовой базе, применяет миграции и очищает данные.
+// Если TEST_DB_NAME не задана, тест пропускается.
+func Open(t *testing.T) *postgres.Database {
+	t.Helper()
+
+	db := Connect(t)
+
+	migrator, err := postgres.NewMigrator(db, migrations.FS, nil)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	// Остальные таблицы очищаются каскадно по внешним ключам
+	if _, err := db.DB.Exec("TRUNCATE users RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("failed to truncate tables: %v", err)
+	}
+
+	return db
+}
+
+// Connect открывает еще одно подключение к тестовой базе, например чтобы
+// изобразить второй экземпляр бота. Миграции не применяются.
+func Connect(t *testing.T) *postgres.Database {
+	t.Helper()
+
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	port, err := strconv.Atoi(getEnv("TEST_DB_PORT", "5432"))
+	if err != nil {
+		t.Fatalf("invalid TEST_DB_PORT: %v", err)
+	}
+
+	db, err := postgres.NewDatabase(&config.DatabaseConfig{
+		Host:     getEnv("TEST_DB_HOST", "localhost"),
+		Port:     port,
+		User:     getEnv("TEST_DB_USER", "todobot"),
+		Password: getEnv("TEST_DB_PASSWORD", "password"),
+		Database: name,
+		SSLMode:  getEnv("TEST_DB_SSLMODE", "disable"),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// RecoveryCodeRepositoryImpl реализует интерфейс RecoveryCodeRepository
+type RecoveryCodeRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewRecoveryCodeRepository создает новый экземпляр RecoveryCodeRepositoryImpl
+func NewRecoveryCodeRepository(db *Database) domain.RecoveryCodeRepository {
+	return &RecoveryCodeRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Replace удаляет старые коды и сохраняет новые в одной транзакции,
+// чтобы пользователь не остался без кодов при ошибке
+func (r *RecoveryCodeRepositoryImpl) Replace(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.sq.
+		Delete("recovery_codes").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if len(codeHashes) > 0 {
+		insert := r.sq.Insert("recovery_codes").Columns("user_id", "code_hash")
+		for _, hash := range codeHashes {
+			insert = insert.Values(userID, hash)
+		}
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", dbError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// Use погашает код одним UPDATE, поэтому один код нельзя использовать дважды
+// даже при одновременных попытках
+func (r *RecoveryCodeRepositoryImpl) Use(ctx context.Context, userID int64, codeHash string, now time.Time) error {
+	query, args, err := r.sq.
+		Update("recovery_codes").
+		Set("used_at", utc(now)).
+		Where(squirrel.Eq{"user_id": userID, "code_hash": codeHash, "used_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return requireAffected(result, domain.EntityRecoveryCode, 0)
+}
+
+// CountUnused возвращает количество неиспользованных кодов пользователя
+func (r *RecoveryCodeRepositoryImpl) CountUnused(ctx context.Context, userID int64) (int, error) {
+	query, args, err := r.sq.
+		Select("COUNT(*)").
+		From("recovery_codes").
+		Where(squirrel.Eq{"user_id": userID, "used_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var count int
+	if err := r.db.DB.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"todolist/internal/domain"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ReminderRepositoryImpl реализует интерфейс ReminderRepository
+type ReminderRepositoryImpl struct {
+	db *Database
+	sq squirrel.StatementBuilderType
+}
+
+// NewReminderRepository создает новый экземпляр ReminderRepositoryImpl
+func NewReminderRepository(db *Database) domain.ReminderRepository {
+	return &ReminderRepositoryImpl{
+		db: db,
+		sq: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Create создает напоминание. Повторное напоминание на то же время
+// не дублируется, а снова становится неотправленным.
+func (r *ReminderRepositoryImpl) Create(ctx context.Context, reminder *domain.Reminder) error {
+	query, args, err := r.sq.
+		Insert("task_reminders").
+		Columns("task_id", "remind_at").
+		Values(reminder.TaskID, utc(reminder.RemindAt)).
+		Suffix("ON CONFLICT (task_id, remind_at) DO UPDATE SET sent_at = NULL RETURNING id, created_at").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(&reminder.ID, &reminder.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reminder: %w", dbError(err))
+	}
+
+	reminder.SentAt = nil
+	return nil
+}
+
+// GetByID получает напоминание по ID
+func (r *ReminderRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Reminder, error) {
+	query, args, err := r.sq.
+		Select("id", "task_id", "remind_at", "sent_at", "created_at").
+		From("task_reminders").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	reminder := &domain.Reminder{}
+	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(
+		&reminder.ID,
+		&reminder.TaskID,
+		&reminder.RemindAt,
+		&reminder.SentAt,
+		&reminder.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NotFound(domain.EntityReminder, int64(id))
+		}
+		return nil, fmt.Errorf("failed to get reminder: %w", err)
+	}
+
+	return reminder, nil
+}
+
+// GetByTaskID получает все напоминания задачи в порядке времени
+func (r *ReminderRepositoryImpl) GetByTaskID(ctx context.Context, taskID int) ([]*domain.Reminder, error) {
+	query, args, err := r.sq.
+		Select("id", "task_id", "remind_at", "sent_at", "created_at").
+		From("task_reminders").
+		Where(squirrel.Eq{"task_id": taskID}).
+		OrderBy("remind_at ASC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanReminders(rows)
+}
+
+// Delete удаляет напоминание
+func (r *ReminderRepositoryImpl) Delete(ctx context.Context, id int) error {
+	query, args, err := r.sq.
+		Delete("task_reminders").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+
+	return requireAffected(result, domain.EntityReminder, int64(id))
+}
+
+// DeletePending удаляет все неотправленные напоминания задачи
+func (r *ReminderRepositoryImpl) DeletePending(ctx context.Context, taskID int) error {
+	query, args, err := r.sq.
+		Delete("task_reminders").
+		Where(squirrel.Eq{"task_id": taskID}).
+		Where(squirrel.Eq{"sent_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err = r.db.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete pending reminders: %w", err)
+	}
+
+	return nil
+}
+
+// scanReminders сканирует строки и возвращает массив напоминаний
+func (r *ReminderRepositoryImpl) scanReminders(rows *sql.Rows) ([]*domain.Reminder, error) {
+	var reminders []*domain.Reminder
+
+	for rows.Next() {
+		reminder := &domain.Reminder{}
+
+		err := rows.Scan(
+			&reminder.ID,
+			&reminder.TaskID,
+			&reminder.RemindAt,
+			&reminder.SentAt,
+			&reminder.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return reminders, nil
+}
@@ -26,45 +26,46 @@ func NewSessionRepository(db *Database) domain.SessionRepository {
 	}
 }
 
-// Create создает новую сессию
+// Create создает сессию или заменяет сессию того же пользователя в том же чате
 func (r *SessionRepositoryImpl) Create(ctx context.Context, session *domain.Session) error {
 	query, args, err := r.sq.
 		Insert("sessions").
-		Columns("user_id", "telegram_id", "is_active", "expires_at").
-		Values(session.UserID, session.TelegramID, session.IsActive, session.ExpiresAt).
-		Suffix(`ON CONFLICT (telegram_id) 
-			DO UPDATE SET 
+		Columns("user_id", "telegram_id", "chat_id", "is_active", "created_at", "last_activity_at", "expires_at").
+		Values(
+			session.UserID,
+			session.TelegramID,
+			session.ChatID,
+			session.IsActive,
+			utc(session.CreatedAt),
+			utc(session.LastActivityAt),
+			utc(session.ExpiresAt),
+		).
+		Suffix(`ON CONFLICT (telegram_id, chat_id)
+			DO UPDATE SET
 				user_id = EXCLUDED.user_id,
 				is_active = EXCLUDED.is_active,
-				created_at = CURRENT_TIMESTAMP,
+				created_at = EXCLUDED.created_at,
+				last_activity_at = EXCLUDED.last_activity_at,
 				expires_at = EXCLUDED.expires_at
-			RETURNING created_at`).
+			RETURNING id`).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(&session.CreatedAt)
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+	if err := r.db.DB.QueryRowContext(ctx, query, args...).Scan(&session.ID); err != nil {
+		return fmt.Errorf("failed to create session: %w", dbError(err))
 	}
 
 	return nil
 }
 
-// GetByTelegramID получает сессию по Telegram ID
-func (r *SessionRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int64) (*domain.Session, error) {
-	query, args, err := r.sq.
-		Select(
-			"user_id",
-			"telegram_id",
-			"is_active",
-			"created_at",
-			"expires_at",
-		).
-		From("sessions").
-		Where(squirrel.Eq{"telegram_id": telegramID}).ToSql()
+// Get получает сессию пользователя в чате
+func (r *SessionRepositoryImpl) Get(ctx context.Context, telegramID, chatID int64) (*domain.Session, error) {
+	query, args, err := r.selectSessions().
+		Where(squirrel.Eq{"telegram_id": telegramID, "chat_id": chatID}).
+		ToSql()
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
@@ -72,16 +73,19 @@ func (r *SessionRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID
 
 	session := &domain.Session{}
 	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(
+		&session.ID,
 		&session.UserID,
 		&session.TelegramID,
+		&session.ChatID,
 		&session.IsActive,
 		&session.CreatedAt,
+		&session.LastActivityAt,
 		&session.ExpiresAt,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("session not found")
+			return nil, domain.NotFound(domain.EntitySession, 0)
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -89,13 +93,90 @@ func (r *SessionRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID
 	return session, nil
 }
 
+// ListActive возвращает действующие сессии, новые - первыми
+func (r *SessionRepositoryImpl) ListActive(ctx context.Context) ([]*domain.Session, error) {
+	return r.listActive(ctx, nil)
+}
+
+// ListByUserID возвращает действующие сессии пользователя, новые - первыми
+func (r *SessionRepositoryImpl) ListByUserID(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	return r.listActive(ctx, squirrel.Eq{"user_id": userID})
+}
+
+// listActive возвращает действующие сессии, подходящие под дополнительное условие
+func (r *SessionRepositoryImpl) listActive(ctx context.Context, where squirrel.Sqlizer) ([]*domain.Session, error) {
+	builder := r.selectSessions().
+		Where(squirrel.Eq{"is_active": true}).
+		Where(squirrel.Gt{"expires_at": utc(time.Now())}).
+		OrderBy("created_at DESC")
+
+	if where != nil {
+		builder = builder.Where(where)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return r.scanSessions(rows)
+}
+
+// selectSessions возвращает запрос со всеми колонками сессии в порядке scanSessions
+func (r *SessionRepositoryImpl) selectSessions() squirrel.SelectBuilder {
+	return r.sq.
+		Select(
+			"id",
+			"user_id",
+			"telegram_id",
+			"chat_id",
+			"is_active",
+			"created_at",
+			"last_activity_at",
+			"expires_at",
+		).
+		From("sessions")
+}
+
+// scanSessions считывает сессии из результата запроса и закрывает его
+func (r *SessionRepositoryImpl) scanSessions(rows *sql.Rows) ([]*domain.Session, error) {
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session := &domain.Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.TelegramID,
+			&session.ChatID,
+			&session.IsActive,
+			&session.CreatedAt,
+			&session.LastActivityAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
 // Update обновляет сессию
 func (r *SessionRepositoryImpl) Update(ctx context.Context, session *domain.Session) error {
 	query, args, err := r.sq.
 		Update("sessions").
 		Set("is_active", session.IsActive).
-		Set("expires_at", session.ExpiresAt).
-		Where(squirrel.Eq{"telegram_id": session.TelegramID}).
+		Set("last_activity_at", utc(session.LastActivityAt)).
+		Set("expires_at", utc(session.ExpiresAt)).
+		Where(squirrel.Eq{"id": session.ID}).
 		ToSql()
 
 	if err != nil {
@@ -104,17 +185,17 @@ func (r *SessionRepositoryImpl) Update(ctx context.Context, session *domain.Sess
 
 	_, err = r.db.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update session: %w", err)
+		return fmt.Errorf("failed to update session: %w", dbError(err))
 	}
 
 	return nil
 }
 
-// Delete удаляет сессию
-func (r *SessionRepositoryImpl) Delete(ctx context.Context, telegramID int64) error {
+// Delete удаляет сессию пользователя в чате
+func (r *SessionRepositoryImpl) Delete(ctx context.Context, telegramID, chatID int64) error {
 	query, args, err := r.sq.
 		Delete("sessions").
-		Where(squirrel.Eq{"telegram_id": telegramID}).
+		Where(squirrel.Eq{"telegram_id": telegramID, "chat_id": chatID}).
 		ToSql()
 
 	if err != nil {
@@ -129,11 +210,58 @@ func (r *SessionRepositoryImpl) Delete(ctx context.Context, telegramID int64) er
 	return nil
 }
 
-// CleanupExpired удаляет истекшие сессии
+// DeleteByID удаляет сессию пользователя по ID
+func (r *SessionRepositoryImpl) DeleteByID(ctx context.Context, id, userID int64) error {
+	query, args, err := r.sq.
+		Delete("sessions").
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if deleted == 0 {
+		return domain.NotFound(domain.EntitySession, id)
+	}
+
+	return nil
+}
+
+// DeleteByUserID удаляет все сессии пользователя и возвращает их количество
+func (r *SessionRepositoryImpl) DeleteByUserID(ctx context.Context, userID int64) (int64, error) {
+	query, args, err := r.sq.
+		Delete("sessions").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// CleanupExpired удаляет истекшие сессии. Неактивные сессии, ожидающие код
+// двухфакторной аутентификации, удаляются по истечении своего короткого срока.
 func (r *SessionRepositoryImpl) CleanupExpired(ctx context.Context) error {
 	query, args, err := r.sq.
 		Delete("sessions").
-		Where(squirrel.Lt{"expires_at": time.Now()}).
+		Where(squirrel.Lt{"expires_at": utc(time.Now())}).
 		ToSql()
 
 	if err != nil {
@@ -0,0 +1,79 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestSessionRepositoryPerChat проверяет, что вход в другом чате не заменяет сессию,
+// а чужую сессию нельзя завершить по ID
+func TestSessionRepositoryPerChat(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	repo := postgres.NewSessionRepository(db)
+
+	const telegramID, groupID = 3001, -1003001
+	owner := createUser(t, db, telegramID)
+	stranger := createUser(t, db, 3002)
+
+	now := time.Now()
+	newSession := func(chatID int64) *domain.Session {
+		return &domain.Session{
+			UserID: owner, TelegramID: telegramID, ChatID: chatID, IsActive: true,
+			CreatedAt: now, LastActivityAt: now, ExpiresAt: now.Add(time.Hour),
+		}
+	}
+
+	private, group := newSession(telegramID), newSession(groupID)
+	for _, session := range []*domain.Session{private, group} {
+		if err := repo.Create(ctx, session); err != nil {
+			t.Fatalf("create session in chat %d: %v", session.ChatID, err)
+		}
+	}
+	if private.ID == 0 || private.ID == group.ID {
+		t.Fatalf("session ids = %d, %d; want distinct", private.ID, group.ID)
+	}
+
+	// Повторный вход в том же чате заменяет сессию этого чата
+	again := newSession(groupID)
+	if err := repo.Create(ctx, again); err != nil {
+		t.Fatalf("recreate group session: %v", err)
+	}
+	if again.ID != group.ID {
+		t.Fatalf("relogin id = %d, want %d", again.ID, group.ID)
+	}
+
+	sessions, err := repo.ListByUserID(ctx, owner)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("sessions = %d, %v; want 2", len(sessions), err)
+	}
+
+	if err := repo.DeleteByID(ctx, group.ID, stranger); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("revoke by another user: %v, want ErrNotFound", err)
+	}
+	if err := repo.DeleteByID(ctx, group.ID, owner); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := repo.Get(ctx, telegramID, groupID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("revoked session: %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Get(ctx, telegramID, telegramID); err != nil {
+		t.Fatalf("private session lost: %v", err)
+	}
+
+	if err := repo.Create(ctx, newSession(groupID)); err != nil {
+		t.Fatalf("create group session: %v", err)
+	}
+	count, err := repo.DeleteByUserID(ctx, owner)
+	if err != nil || count != 2 {
+		t.Fatalf("logout everywhere = %d, %v; want 2", count, err)
+	}
+}
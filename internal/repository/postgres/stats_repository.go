@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"todolist/internal/domain"
+)
+
+// StatsRepositoryImpl реализует интерфейс StatsRepository
+type StatsRepositoryImpl struct {
+	db *Database
+}
+
+// NewStatsRepository создает новый экземпляр StatsRepositoryImpl
+func NewStatsRepository(db *Database) domain.StatsRepository {
+	return &StatsRepositoryImpl{db: db}
+}
+
+// Get считает пользователей, сессии, задачи и заметки одним запросом
+func (r *StatsRepositoryImpl) Get(ctx context.Context) (*domain.Stats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE is_active),
+			(SELECT COUNT(*) FROM users WHERE role = 'admin'),
+			(SELECT COUNT(*) FROM sessions WHERE is_active AND expires_at > $1),
+			(SELECT COUNT(*) FROM tasks WHERE status = 'pending'),
+			(SELECT COUNT(*) FROM tasks WHERE status = 'completed'),
+			(SELECT COUNT(*) FROM notes)`
+
+	stats := &domain.Stats{}
+	err := r.db.DB.QueryRowContext(ctx, query, utc(time.Now())).Scan(
+		&stats.Users,
+		&stats.ActiveUsers,
+		&stats.Admins,
+		&stats.ActiveSessions,
+		&stats.PendingTasks,
+		&stats.CompletedTasks,
+		&stats.Notes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	return stats, nil
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/Masterminds/squirrel"
 )
 
+// nextReminderColumn вычисляет время ближайшего неотправленного напоминания задачи
+const nextReminderColumn = `(SELECT MIN(r.remind_at) FROM task_reminders r
+	WHERE r.task_id = tasks.id AND r.sent_at IS NULL) AS next_reminder_at`
+
 // TaskRepositoryImpl реализует интерфейс TaskRepository
 type TaskRepositoryImpl struct {
 	db *Database
@@ -29,8 +33,12 @@ func NewTaskRepository(db *Database) domain.TaskRepository {
 // Create создает новую задачу
 func (r *TaskRepositoryImpl) Create(ctx context.Context, task *domain.Task) error {
 	query := r.sq.Insert("tasks").
-		Columns("title", "description", "status", "priority", "user_id", "notify_at").
-		Values(task.Title, task.Description, task.Status, task.Priority, task.UserID, task.NotifyAt).
+		Columns(
+			"title", "description", "status", "priority", "user_id", "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id").
+		Values(
+			task.Title, task.Description, task.Status, task.Priority, task.UserID, utcPtr(task.DueAt),
+			task.Recurrence, task.Tags, task.EstimateMinutes, task.ParentID).
 		Suffix("RETURNING id, created_at, updated_at")
 
 	sql, args, err := query.ToSql()
@@ -41,7 +49,7 @@ func (r *TaskRepositoryImpl) Create(ctx context.Context, task *domain.Task) erro
 	err = r.db.DB.QueryRowContext(ctx, sql, args...).Scan(
 		&task.ID, &task.CreatedAt, &task.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create task: %w", err)
+		return fmt.Errorf("failed to create task: %w", dbError(err))
 	}
 
 	return nil
@@ -52,7 +60,8 @@ func (r *TaskRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Task,
 	query, args, err := r.sq.
 		Select(
 			"id", "title", "description", "status", "priority",
-			"created_at", "updated_at", "completed_at", "notify_at", "user_id").
+			"created_at", "updated_at", "completed_at", nextReminderColumn, "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id", "user_id").
 		From("tasks").
 		Where(squirrel.Eq{"id": id}).
 		Where(squirrel.NotEq{"status": "deleted"}).
@@ -72,13 +81,18 @@ func (r *TaskRepositoryImpl) GetByID(ctx context.Context, id int) (*domain.Task,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 		&task.CompletedAt,
-		&task.NotifyAt,
+		&task.NextReminderAt,
+		&task.DueAt,
+		&task.Recurrence,
+		&task.Tags,
+		&task.EstimateMinutes,
+		&task.ParentID,
 		&task.UserID,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("task not found")
+			return nil, domain.NotFound(domain.EntityTask, int64(id))
 		}
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
@@ -91,10 +105,12 @@ func (r *TaskRepositoryImpl) GetByUserID(ctx context.Context, userID int64, stat
 	query, args, err := r.sq.
 		Select(
 			"id", "title", "description", "status", "priority",
-			"created_at", "updated_at", "completed_at", "notify_at", "user_id").
+			"created_at", "updated_at", "completed_at", nextReminderColumn, "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id", "user_id").
 		From("tasks").
 		Where(squirrel.Eq{"user_id": userID}).
 		Where(squirrel.Eq{"status": status}).
+		Where(squirrel.Eq{"parent_id": nil}).
 		OrderBy("created_at DESC").
 		ToSql()
 
@@ -116,15 +132,18 @@ func (r *TaskRepositoryImpl) GetAll(ctx context.Context, userID int64) ([]*domai
 	query, args, err := r.sq.
 		Select(
 			"id", "title", "description", "status", "priority",
-			"created_at", "updated_at", "completed_at", "notify_at", "user_id").
+			"created_at", "updated_at", "completed_at", nextReminderColumn, "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id", "user_id").
 		From("tasks").
 		Where(squirrel.Eq{"user_id": userID}).
 		Where(squirrel.NotEq{"status": "deleted"}).
+		Where(squirrel.Eq{"parent_id": nil}).
 		OrderBy(`
 			CASE status 
 				WHEN 'pending' THEN 1 
 				WHEN 'completed' THEN 2 
 			END,
+			due_at ASC NULLS LAST,
 			CASE priority 
 				WHEN 'high' THEN 1 
 				WHEN 'medium' THEN 2 
@@ -146,6 +165,60 @@ func (r *TaskRepositoryImpl) GetAll(ctx context.Context, userID int64) ([]*domai
 	return r.scanTasks(rows)
 }
 
+// GetOverdue получает невыполненные задачи пользователя с истекшим сроком
+func (r *TaskRepositoryImpl) GetOverdue(ctx context.Context, userID int64, now time.Time) ([]*domain.Task, error) {
+	query, args, err := r.sq.
+		Select(
+			"id", "title", "description", "status", "priority",
+			"created_at", "updated_at", "completed_at", nextReminderColumn, "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id", "user_id").
+		From("tasks").
+		Where(squirrel.Eq{"user_id": userID}).
+		Where(squirrel.Eq{"status": "pending"}).
+		Where(squirrel.NotEq{"due_at": nil}).
+		Where(squirrel.Lt{"due_at": utc(now)}).
+		OrderBy("due_at ASC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue tasks: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanTasks(rows)
+}
+
+// GetSubtasks получает подзадачи задачи в порядке добавления
+func (r *TaskRepositoryImpl) GetSubtasks(ctx context.Context, parentID int) ([]*domain.Task, error) {
+	query, args, err := r.sq.
+		Select(
+			"id", "title", "description", "status", "priority",
+			"created_at", "updated_at", "completed_at", nextReminderColumn, "due_at",
+			"recurrence", "tags", "estimate_minutes", "parent_id", "user_id").
+		From("tasks").
+		Where(squirrel.Eq{"parent_id": parentID}).
+		Where(squirrel.NotEq{"status": "deleted"}).
+		OrderBy("created_at ASC", "id ASC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanTasks(rows)
+}
+
 // Update обновляет задачу
 func (r *TaskRepositoryImpl) Update(ctx context.Context, task *domain.Task) error {
 	task.UpdatedAt = time.Now()
@@ -156,9 +229,12 @@ func (r *TaskRepositoryImpl) Update(ctx context.Context, task *domain.Task) erro
 		Set("description", task.Description).
 		Set("status", task.Status).
 		Set("priority", task.Priority).
-		Set("updated_at", task.UpdatedAt).
-		Set("completed_at", task.CompletedAt).
-		Set("notify_at", task.NotifyAt).
+		Set("updated_at", utc(task.UpdatedAt)).
+		Set("completed_at", utcPtr(task.CompletedAt)).
+		Set("due_at", utcPtr(task.DueAt)).
+		Set("recurrence", task.Recurrence).
+		Set("tags", task.Tags).
+		Set("estimate_minutes", task.EstimateMinutes).
 		Where(squirrel.Eq{"id": task.ID}).
 		ToSql()
 
@@ -166,57 +242,33 @@ func (r *TaskRepositoryImpl) Update(ctx context.Context, task *domain.Task) erro
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	if _, err = r.db.DB.ExecContext(ctx, query, args...); err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", dbError(err))
 	}
 
-	return nil
+	return requireAffected(result, domain.EntityTask, int64(task.ID))
 }
 
-// Delete удаляет задачу (помечает как удаленную)
+// Delete удаляет задачу вместе с подзадачами (помечает как удаленные)
 func (r *TaskRepositoryImpl) Delete(ctx context.Context, id int) error {
 	query, args, err := r.sq.
 		Update("tasks").
 		Set("status", "deleted").
 		Set("updated_at", "CURRENT_TIMESTAMP").
-		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.Or{squirrel.Eq{"id": id}, squirrel.Eq{"parent_id": id}}).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	if _, err = r.db.DB.ExecContext(ctx, query, args...); err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
-	}
-
-	return nil
-}
-
-// GetTasksForNotification получает задачи для отправки уведомлений
-func (r *TaskRepositoryImpl) GetTasksForNotification(ctx context.Context, beforeTime time.Time) ([]*domain.Task, error) {
-	query, args, err := r.sq.
-		Select(
-			"id", "title", "description", "status", "priority",
-			"created_at", "updated_at", "completed_at", "notify_at", "user_id").
-		From("tasks").
-		Where(squirrel.NotEq{"notify_at": nil}).
-		Where(squirrel.LtOrEq{"notify_at": beforeTime}).
-		Where(squirrel.Eq{"status": "pending"}).
-		OrderBy("notify_at ASC").
-		ToSql()
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %w", err)
-	}
-
-	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get notification tasks: %w", err)
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
-	defer rows.Close()
 
-	return r.scanTasks(rows)
+	return requireAffected(result, domain.EntityTask, int64(id))
 }
 
 // scanTasks сканирует строки и возвращает массив задач
@@ -235,7 +287,12 @@ func (r *TaskRepositoryImpl) scanTasks(rows *sql.Rows) ([]*domain.Task, error) {
 			&task.CreatedAt,
 			&task.UpdatedAt,
 			&task.CompletedAt,
-			&task.NotifyAt,
+			&task.NextReminderAt,
+			&task.DueAt,
+			&task.Recurrence,
+			&task.Tags,
+			&task.EstimateMinutes,
+			&task.ParentID,
 			&task.UserID,
 		)
 		if err != nil {
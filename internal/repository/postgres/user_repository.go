@@ -27,82 +27,208 @@ func NewUserRepository(db *Database) domain.UserRepository {
 
 // Create создает нового пользователя
 func (r *UserRepositoryImpl) Create(ctx context.Context, user *domain.User) error {
-	query := r.sq.Insert("users").
-		Columns("telegram_id", "username", "first_name", "last_name").
-		Values(user.TelegramID, user.Username, user.FirstName, user.LastName).
-		Suffix("RETURNING id, created_at, updated_at, last_login_at")
+	return insertUser(ctx, r.db.DB, r.sq, user)
+}
+
+// queryRower - общее у *sql.DB и *sql.Tx, чтобы пользователя можно было создать и в транзакции
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertUser добавляет пользователя и заполняет его ID и время создания
+func insertUser(ctx context.Context, db queryRower, sq squirrel.StatementBuilderType, user *domain.User) error {
+	query, args, err := sq.Insert("users").
+		Columns("telegram_id", "username", "first_name", "last_name", "role", "password_hash").
+		Values(user.TelegramID, user.Username, user.FirstName, user.LastName, user.Role, user.PasswordHash).
+		Suffix("RETURNING id, created_at, updated_at, last_login_at").
+		ToSql()
 
-	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	err = r.db.DB.QueryRowContext(ctx, sql, args...).Scan(
+	err = db.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to create user: %w", dbError(err))
 	}
 
 	user.IsActive = true
 	return nil
 }
 
+// GetByID получает пользователя по внутреннему ID
+func (r *UserRepositoryImpl) GetByID(ctx context.Context, id int64) (*domain.User, error) {
+	return r.getOne(ctx, squirrel.Eq{"id": id})
+}
+
 // GetByTelegramID получает пользователя по Telegram ID
 func (r *UserRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int64) (*domain.User, error) {
-	query, args, err := r.sq.
-		Select(
-			"id", "telegram_id", "username", "first_name", "last_name",
-			"is_active", "created_at", "updated_at", "last_login_at").
-		From("users").
-		Where(squirrel.Eq{"telegram_id": telegramID}).
-		ToSql()
+	return r.getOne(ctx, squirrel.Eq{"telegram_id": telegramID})
+}
 
+// getOne получает одного пользователя по условию
+func (r *UserRepositoryImpl) getOne(ctx context.Context, where squirrel.Sqlizer) (*domain.User, error) {
+	query, args, err := r.selectUsers().Where(where).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NotFound(domain.EntityUser, 0)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// List возвращает всех пользователей, начиная с недавно входивших
+func (r *UserRepositoryImpl) List(ctx context.Context) ([]*domain.User, error) {
+	query, args, err := r.selectUsers().OrderBy("last_login_at DESC", "id").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// selectUsers возвращает запрос со всеми колонками пользователя в порядке scanUser
+func (r *UserRepositoryImpl) selectUsers() squirrel.SelectBuilder {
+	return r.sq.
+		Select(
+			"id", "telegram_id", "username", "first_name", "last_name", "is_active",
+			"timezone", "role", "password_hash", "totp_secret", "totp_enabled", "totp_last_step",
+			"created_at", "updated_at", "last_login_at").
+		From("users")
+}
+
+// scanUser считывает пользователя из строки результата
+func scanUser(row interface{ Scan(dest ...any) error }) (*domain.User, error) {
 	user := &domain.User{}
-	err = r.db.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.TelegramID,
 		&user.Username,
 		&user.FirstName,
 		&user.LastName,
 		&user.IsActive,
+		&user.Timezone,
+		&user.Role,
+		&user.PasswordHash,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateProfile обновляет имя пользователя в Telegram и время последнего входа
+func (r *UserRepositoryImpl) UpdateProfile(ctx context.Context, user *domain.User) error {
+	return r.update(ctx, user.ID, map[string]any{
+		"username":      user.Username,
+		"first_name":    user.FirstName,
+		"last_name":     user.LastName,
+		"last_login_at": utc(user.LastLoginAt),
+	})
+}
+
+// SetActive блокирует или разблокирует пользователя
+func (r *UserRepositoryImpl) SetActive(ctx context.Context, userID int64, active bool) error {
+	return r.update(ctx, userID, map[string]any{"is_active": active})
+}
+
+// SetRole устанавливает роль пользователя
+func (r *UserRepositoryImpl) SetRole(ctx context.Context, userID int64, role domain.UserRole) error {
+	return r.update(ctx, userID, map[string]any{"role": role})
+}
+
+// SetTimezone устанавливает часовой пояс пользователя
+func (r *UserRepositoryImpl) SetTimezone(ctx context.Context, userID int64, timezone string) error {
+	return r.update(ctx, userID, map[string]any{"timezone": timezone})
+}
+
+// SetPasswordHash устанавливает хеш личного пароля
+func (r *UserRepositoryImpl) SetPasswordHash(ctx context.Context, userID int64, hash string) error {
+	return r.update(ctx, userID, map[string]any{"password_hash": hash})
+}
+
+// SetTOTPState сохраняет секрет TOTP и признак включения двухфакторной аутентификации
+func (r *UserRepositoryImpl) SetTOTPState(ctx context.Context, userID int64, secret string, enabled bool) error {
+	return r.update(ctx, userID, map[string]any{"totp_secret": secret, "totp_enabled": enabled})
+}
+
+// update меняет только переданные колонки пользователя, чтобы одновременные изменения
+// других полей (например, блокировка во время входа) не перезаписывались старыми значениями
+func (r *UserRepositoryImpl) update(ctx context.Context, userID int64, columns map[string]any) error {
+	query, args, err := r.sq.
+		Update("users").
+		SetMap(columns).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": userID}).
+		ToSql()
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	return user, nil
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", dbError(err))
+	}
+
+	return requireAffected(result, domain.EntityUser, userID)
 }
 
-// Update обновляет пользователя
-func (r *UserRepositoryImpl) Update(ctx context.Context, user *domain.User) error {
+// UseTOTPStep запоминает шаг принятого кода одним UPDATE, поэтому один код нельзя
+// использовать дважды даже при одновременных попытках
+func (r *UserRepositoryImpl) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
 	query, args, err := r.sq.
 		Update("users").
-		Set("username", user.Username).
-		Set("first_name", user.FirstName).
-		Set("last_name", user.LastName).
-		Set("is_active", user.IsActive).
-		Set("updated_at", "CURRENT_TIMESTAMP").
-		Set("last_login_at", user.LastLoginAt).
-		Where(squirrel.Eq{"id": user.ID}).
+		Set("totp_last_step", step).
+		Where(squirrel.Eq{"id": userID}).
+		Where(squirrel.Lt{"totp_last_step": step}).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	if _, err = r.db.DB.ExecContext(ctx, query, args...); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update totp step: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		return domain.Conflict(domain.EntityUser, userID, "этот код уже использован, дождитесь следующего")
 	}
 
 	return nil
@@ -0,0 +1,65 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestUserRepositoryTargetedUpdates проверяет, что изменение одних полей по устаревшей копии
+// пользователя не откатывает другие: вход не снимает блокировку, а смена секрета не сбрасывает шаг TOTP
+func TestUserRepositoryTargetedUpdates(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Open(t)
+	repo := postgres.NewUserRepository(db)
+
+	userID := createUser(t, db, 4001)
+	stale, err := repo.GetByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	if err := repo.SetActive(ctx, userID, false); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	if err := repo.UseTOTPStep(ctx, userID, 100); err != nil {
+		t.Fatalf("use totp step: %v", err)
+	}
+
+	stale.Username = "renamed"
+	stale.LastLoginAt = time.Now()
+	if err := repo.UpdateProfile(ctx, stale); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if err := repo.SetTOTPState(ctx, userID, "secret", true); err != nil {
+		t.Fatalf("set totp state: %v", err)
+	}
+	if err := repo.SetTimezone(ctx, userID, "Europe/Berlin"); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+
+	user, err := repo.GetByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.IsActive {
+		t.Error("profile update lifted the ban")
+	}
+	if user.TOTPLastStep != 100 {
+		t.Errorf("totp_last_step = %d, want 100", user.TOTPLastStep)
+	}
+	if user.Username != "renamed" || user.TOTPSecret != "secret" || !user.TOTPEnabled || user.Timezone != "Europe/Berlin" {
+		t.Errorf("user = %+v, want all targeted updates applied", user)
+	}
+
+	if err := repo.SetRole(ctx, userID+1000, domain.UserRoleAdmin); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("set role of a missing user: %v, want ErrNotFound", err)
+	}
+}
@@ -2,18 +2,30 @@ package scheduler
 
 import (
 	"context"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
+	"todolist/config"
+	"todolist/internal/domain"
 	"todolist/internal/usecase"
 )
 
-// CronScheduler представляет планировщик задач
+// conversationStateRetention - сколько истекший диалог хранится после истечения,
+// чтобы пользователь, вернувшийся к нему, получил сообщение об отмене
+const conversationStateRetention = 24 * time.Hour
+
+// CronScheduler представляет планировщик задач.
+// Каждая задача выполняется под блокировкой, поэтому при запуске нескольких
+// экземпляров бота с одной базой данных задача выполняется только в одном из них.
 type CronScheduler struct {
 	cron                *cron.Cron
 	notificationService *usecase.NotificationService
 	authService         *usecase.AuthService
+	states              domain.ConversationStateRepository
+	locker              domain.JobLocker
+	config              *config.Config
 	logger              *zap.Logger
 }
 
@@ -21,6 +33,9 @@ type CronScheduler struct {
 func NewCronScheduler(
 	notificationService *usecase.NotificationService,
 	authService *usecase.AuthService,
+	states domain.ConversationStateRepository,
+	locker domain.JobLocker,
+	config *config.Config,
 	logger *zap.Logger,
 ) *CronScheduler {
 	c := cron.New(cron.WithSeconds())
@@ -29,6 +44,9 @@ func NewCronScheduler(
 		cron:                c,
 		notificationService: notificationService,
 		authService:         authService,
+		states:              states,
+		locker:              locker,
+		config:              config,
 		logger:              logger,
 	}
 }
@@ -37,17 +55,31 @@ func NewCronScheduler(
 func (s *CronScheduler) Start(ctx context.Context) error {
 	s.logger.Info("starting cron scheduler...")
 
-	// Отправка уведомлений каждую минуту
+	// Постановка наступивших напоминаний в очередь каждую минуту
 	_, err := s.cron.AddFunc("0 * * * * *", func() {
-		s.sendNotifications(ctx)
+		s.runLocked(ctx, "enqueue_notifications", s.enqueueNotifications)
 	})
 	if err != nil {
 		return err
 	}
 
-	// Очистка истекших сессий каждые 30 минут
+	// Обработчик очереди уведомлений; следующий проход не начинается, пока не закончен предыдущий
+	deliverJob := cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(cron.FuncJob(func() {
+		s.runLocked(ctx, "deliver_notifications", s.deliverNotifications)
+	}))
+	s.cron.Schedule(cron.Every(s.config.Notify.PollInterval), deliverJob)
+
+	// Очистка истекших сессий и старых попыток входа каждые 30 минут
 	_, err = s.cron.AddFunc("0 */30 * * * *", func() {
-		s.cleanupSessions(ctx)
+		s.runLocked(ctx, "cleanup_sessions", s.cleanupSessions)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Очистка брошенных диалогов раз в час
+	_, err = s.cron.AddFunc("0 15 * * * *", func() {
+		s.runLocked(ctx, "cleanup_conversations", s.cleanupConversations)
 	})
 	if err != nil {
 		return err
@@ -67,20 +99,60 @@ func (s *CronScheduler) Start(ctx context.Context) error {
 	return ctx.Err()
 }
 
-// sendNotifications отправляет уведомления о задачах
-func (s *CronScheduler) sendNotifications(ctx context.Context) {
-	s.logger.Debug("checking for notifications to send...")
+// runLocked выполняет задачу, если ее блокировку не держит другой экземпляр бота
+func (s *CronScheduler) runLocked(ctx context.Context, name string, job func(ctx context.Context)) {
+	unlock, ok, err := s.locker.TryLock(ctx, name)
+	if err != nil {
+		s.logger.Error("failed to acquire job lock", zap.String("job", name), zap.Error(err))
+		return
+	}
+	if !ok {
+		s.logger.Debug("job is running on another instance, skipping", zap.String("job", name))
+		return
+	}
+	defer unlock()
+
+	job(ctx)
+}
+
+// enqueueNotifications ставит наступившие напоминания в очередь отправки
+func (s *CronScheduler) enqueueNotifications(ctx context.Context) {
+	s.logger.Debug("checking for reminders to enqueue...")
+
+	if err := s.notificationService.EnqueueDueReminders(ctx); err != nil {
+		s.logger.Error("failed to enqueue notifications", zap.Error(err))
+	}
+}
 
-	if err := s.notificationService.SendTaskNotifications(ctx); err != nil {
-		s.logger.Error("failed to send notifications", zap.Error(err))
+// deliverNotifications отправляет уведомления из очереди
+func (s *CronScheduler) deliverNotifications(ctx context.Context) {
+	if _, err := s.notificationService.DeliverPending(ctx); err != nil {
+		s.logger.Error("failed to deliver notifications", zap.Error(err))
 	}
 }
 
-// cleanupSessions очищает истекшие сессии
+// cleanupSessions очищает истекшие сессии и старые записи о попытках входа
 func (s *CronScheduler) cleanupSessions(ctx context.Context) {
 	s.logger.Debug("cleaning up expired sessions...")
 
 	if err := s.authService.CleanupExpiredSessions(ctx); err != nil {
 		s.logger.Error("failed to cleanup sessions", zap.Error(err))
 	}
+
+	if err := s.authService.CleanupLoginAttempts(ctx); err != nil {
+		s.logger.Error("failed to cleanup login attempts", zap.Error(err))
+	}
+}
+
+// cleanupConversations удаляет диалоги, которые пользователи бросили и не возобновили
+func (s *CronScheduler) cleanupConversations(ctx context.Context) {
+	deleted, err := s.states.DeleteExpiredBefore(ctx, time.Now().Add(-conversationStateRetention))
+	if err != nil {
+		s.logger.Error("failed to cleanup conversation states", zap.Error(err))
+		return
+	}
+
+	if deleted > 0 {
+		s.logger.Info("expired conversation states cleaned up", zap.Int64("deleted", deleted))
+	}
 }
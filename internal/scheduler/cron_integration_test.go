@@ -0,0 +1,77 @@
+//go:build integration
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todolist/config"
+	"todolist/internal/repository/postgres"
+	"todolist/internal/repository/postgres/pgtest"
+)
+
+// TestJobRunsOncePerTick запускает задачу одновременно в двух планировщиках с одной базой,
+// как это происходит в двух экземплярах бота, и проверяет, что на каждом такте
+// тело задачи выполняется ровно один раз
+func TestJobRunsOncePerTick(t *testing.T) {
+	const ticks = 20
+
+	ctx := context.Background()
+	cfg := &config.Config{}
+
+	// Отдельные пулы соединений изображают разные процессы
+	schedulers := []*CronScheduler{
+		NewCronScheduler(nil, nil, nil, postgres.NewJobLocker(pgtest.Open(t)), cfg, zap.NewNop()),
+		NewCronScheduler(nil, nil, nil, postgres.NewJobLocker(pgtest.Connect(t)), cfg, zap.NewNop()),
+	}
+
+	for tick := 0; tick < ticks; tick++ {
+		var runs atomic.Int32
+		job := func(ctx context.Context) {
+			runs.Add(1)
+			// Задача длится дольше, чем второй экземпляр пытается взять блокировку
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for _, s := range schedulers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				s.runLocked(ctx, "test_job", job)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if got := runs.Load(); got != 1 {
+			t.Fatalf("tick %d: job ran %d times, want 1", tick, got)
+		}
+	}
+}
+
+// TestJobLockReleased проверяет, что после выполнения задачи блокировка снимается
+// и на следующем такте задачу может выполнить другой экземпляр
+func TestJobLockReleased(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+
+	first := NewCronScheduler(nil, nil, nil, postgres.NewJobLocker(pgtest.Open(t)), cfg, zap.NewNop())
+	second := NewCronScheduler(nil, nil, nil, postgres.NewJobLocker(pgtest.Connect(t)), cfg, zap.NewNop())
+
+	var runs [2]int
+	first.runLocked(ctx, "test_job", func(context.Context) { runs[0]++ })
+	second.runLocked(ctx, "test_job", func(context.Context) { runs[1]++ })
+
+	if runs != [2]int{1, 1} {
+		t.Fatalf("runs = %v, want each scheduler to run the job once", runs)
+	}
+}
@@ -0,0 +1,328 @@
+// Package sender отправляет запросы в Telegram с соблюдением ограничений частоты.
+// Все исходящие сообщения бота проходят через Sender: он выдерживает общий лимит и лимит на чат,
+// выполняет ответы пользователям раньше массовых рассылок и повторяет запросы после ответа 429.
+package sender
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"todolist/config"
+)
+
+// Priority - приоритет отправки
+type Priority int
+
+const (
+	// PriorityInteractive - ответы на действия пользователя, занимают ближайшие свободные слоты
+	PriorityInteractive Priority = iota
+	// PriorityBulk - напоминания и рассылки, отправляются только когда нет ожидающих ответов
+	PriorityBulk
+)
+
+const (
+	// bulkPollInterval - как часто массовая отправка проверяет, освободился ли слот
+	bulkPollInterval = 50 * time.Millisecond
+	// chatPruneThreshold - после этого числа записей о чатах устаревшие записи удаляются
+	chatPruneThreshold = 1024
+)
+
+// metrics публикуется через expvar в /debug/vars
+var metrics = expvar.NewMap("telegram_sender")
+
+// Sender отправляет запросы в Telegram с ограничением частоты.
+// Лимиты реализованы по алгоритму GCRA: для каждого чата и для бота в целом хранится время,
+// с которого можно отправить следующий запрос. Ответы пользователям резервируют слоты заранее
+// и ждут своей очереди, массовая отправка берет слот, только если он свободен прямо сейчас.
+type Sender struct {
+	api    *tgbotapi.BotAPI
+	config config.SenderConfig
+	logger *zap.Logger
+
+	// now возвращает текущее время; в тестах заменяется управляемыми часами
+	now func() time.Time
+
+	mu sync.Mutex
+	// globalNext - время, с которого бот может отправить следующий запрос
+	globalNext time.Time
+	// chatNext - то же для каждого чата, с учетом допустимой серии сообщений
+	chatNext map[int64]time.Time
+	// chatPausedUntil - до этого времени чат не получает запросов после ответа 429.
+	// В отличие от chatNext, серия сообщений здесь не учитывается: retry_after соблюдается точно
+	chatPausedUntil map[int64]time.Time
+	// pausedUntil - вся отправка, и ответы, и рассылки, приостановлена после ответа 429
+	pausedUntil time.Time
+	// interactiveWaiting - число ответов, ожидающих слот; пока они есть, массовая отправка ждет
+	interactiveWaiting int
+}
+
+// New создает Sender с лимитами из SENDER_*
+func New(api *tgbotapi.BotAPI, cfg config.SenderConfig, logger *zap.Logger) *Sender {
+	if cfg.GlobalRate < 1 {
+		cfg.GlobalRate = 1
+	}
+	if cfg.ChatBurst < 1 {
+		cfg.ChatBurst = 1
+	}
+
+	return &Sender{
+		api:             api,
+		config:          cfg,
+		logger:          logger,
+		now:             time.Now,
+		chatNext:        make(map[int64]time.Time),
+		chatPausedUntil: make(map[int64]time.Time),
+	}
+}
+
+// Send отправляет сообщение, редактирование или файл и возвращает отправленное сообщение
+func (s *Sender) Send(ctx context.Context, c tgbotapi.Chattable, priority Priority) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+	err := s.do(ctx, c, priority, func() error {
+		var err error
+		message, err = s.api.Send(c)
+		return err
+	})
+	return message, err
+}
+
+// Request выполняет запрос, ответ на который не является сообщением (удаление, ответ на callback)
+func (s *Sender) Request(ctx context.Context, c tgbotapi.Chattable, priority Priority) error {
+	return s.do(ctx, c, priority, func() error {
+		_, err := s.api.Request(c)
+		return err
+	})
+}
+
+// do ждет слот и выполняет запрос. После ответа 429 запрос повторяется через retry_after,
+// но не больше SENDER_MAX_RETRIES раз; затем возвращается ошибка Telegram.
+func (s *Sender) do(ctx context.Context, c tgbotapi.Chattable, priority Priority, request func() error) error {
+	chatID, limited := chatOf(c)
+
+	for attempt := 0; ; attempt++ {
+		if limited {
+			if err := s.wait(ctx, chatID, priority); err != nil {
+				metrics.Add("cancelled", 1)
+				return err
+			}
+		}
+
+		err := request()
+		if err == nil {
+			metrics.Add("sent", 1)
+			return nil
+		}
+
+		var apiErr *tgbotapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+			metrics.Add("failed", 1)
+			return err
+		}
+
+		retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		metrics.Add("rate_limited", 1)
+		s.pause(chatID, retryAfter)
+
+		if attempt >= s.config.MaxRetries {
+			metrics.Add("failed", 1)
+			return err
+		}
+
+		s.logger.Warn("telegram rate limit, request will be retried",
+			zap.Int64("chat_id", chatID),
+			zap.Duration("retry_after", retryAfter),
+			zap.Int("attempt", attempt+1))
+		metrics.Add("retries", 1)
+	}
+}
+
+// wait блокируется до слота, в который можно отправить запрос в чат chatID
+func (s *Sender) wait(ctx context.Context, chatID int64, priority Priority) error {
+	started := s.now()
+	defer func() {
+		metrics.Add("wait_ms", s.now().Sub(started).Milliseconds())
+	}()
+
+	if priority == PriorityInteractive {
+		return s.waitInteractive(ctx, chatID)
+	}
+	return s.waitBulk(ctx, chatID)
+}
+
+// waitInteractive резервирует ближайший слот и ждет его
+func (s *Sender) waitInteractive(ctx context.Context, chatID int64) error {
+	s.mu.Lock()
+	at := s.reserve(chatID, s.now())
+	s.interactiveWaiting++
+	s.mu.Unlock()
+
+	metrics.Add("waiting_interactive", 1)
+	defer func() {
+		s.mu.Lock()
+		s.interactiveWaiting--
+		s.mu.Unlock()
+		metrics.Add("waiting_interactive", -1)
+	}()
+
+	return sleep(ctx, at.Sub(s.now()))
+}
+
+// waitBulk ждет, пока слот освободится и не останется ожидающих ответов пользователям
+func (s *Sender) waitBulk(ctx context.Context, chatID int64) error {
+	metrics.Add("waiting_bulk", 1)
+	defer metrics.Add("waiting_bulk", -1)
+
+	for {
+		s.mu.Lock()
+		now := s.now()
+		at, ok := s.tryReserveBulk(chatID, now)
+		s.mu.Unlock()
+		if ok {
+			return nil
+		}
+
+		delay := at.Sub(now)
+		if delay < bulkPollInterval {
+			delay = bulkPollInterval
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// tryReserveBulk занимает слот для массовой отправки, если он свободен в момент now
+// и нет ожидающих ответов пользователям. Иначе возвращает время ближайшего слота.
+// Вызывается под s.mu.
+func (s *Sender) tryReserveBulk(chatID int64, now time.Time) (time.Time, bool) {
+	at := s.earliest(chatID, now)
+	if at.After(now) || s.interactiveWaiting > 0 {
+		return at, false
+	}
+	return s.reserve(chatID, now), true
+}
+
+// earliest возвращает время, с которого можно отправить запрос в чат.
+// Вызывается под s.mu.
+func (s *Sender) earliest(chatID int64, now time.Time) time.Time {
+	at := now
+	if s.globalNext.After(at) {
+		at = s.globalNext
+	}
+	if s.pausedUntil.After(at) {
+		at = s.pausedUntil
+	}
+
+	// Чат допускает серию из ChatBurst сообщений, затем одно сообщение в ChatInterval
+	burst := time.Duration(s.config.ChatBurst-1) * s.config.ChatInterval
+	if chatAt := s.chatNext[chatID].Add(-burst); chatAt.After(at) {
+		at = chatAt
+	}
+	if pausedAt := s.chatPausedUntil[chatID]; pausedAt.After(at) {
+		at = pausedAt
+	}
+
+	return at
+}
+
+// reserve занимает ближайший слот и возвращает его время. Вызывается под s.mu.
+func (s *Sender) reserve(chatID int64, now time.Time) time.Time {
+	at := s.earliest(chatID, now)
+
+	s.globalNext = at.Add(time.Second / time.Duration(s.config.GlobalRate))
+
+	next := s.chatNext[chatID]
+	if next.Before(at) {
+		next = at
+	}
+	s.chatNext[chatID] = next.Add(s.config.ChatInterval)
+
+	if len(s.chatNext) > chatPruneThreshold {
+		s.prune(now)
+	}
+
+	return at
+}
+
+// pause откладывает отправку в чат на retryAfter после ответа 429.
+// Остальные чаты тоже ждут: 429 означает, что бот отправляет слишком много.
+func (s *Sender) pause(chatID int64, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := s.now().Add(retryAfter)
+	if s.chatPausedUntil[chatID].Before(until) {
+		s.chatPausedUntil[chatID] = until
+	}
+	if s.pausedUntil.Before(until) {
+		s.pausedUntil = until
+	}
+}
+
+// prune удаляет записи о чатах, лимит которых уже восстановился. Вызывается под s.mu.
+func (s *Sender) prune(now time.Time) {
+	for chatID, next := range s.chatNext {
+		if next.Before(now) {
+			delete(s.chatNext, chatID)
+		}
+	}
+	for chatID, until := range s.chatPausedUntil {
+		if until.Before(now) {
+			delete(s.chatPausedUntil, chatID)
+		}
+	}
+}
+
+// chatOf возвращает чат запроса и признак того, что запрос учитывается в лимитах.
+// Ответы на callback и удаление сообщений не расходуют лимит сообщений.
+func chatOf(c tgbotapi.Chattable) (int64, bool) {
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		return v.ChatID, true
+	case tgbotapi.PhotoConfig:
+		return v.ChatID, true
+	case tgbotapi.DocumentConfig:
+		return v.ChatID, true
+	case tgbotapi.VideoConfig:
+		return v.ChatID, true
+	case tgbotapi.AudioConfig:
+		return v.ChatID, true
+	case tgbotapi.VoiceConfig:
+		return v.ChatID, true
+	case tgbotapi.EditMessageTextConfig:
+		return v.ChatID, true
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return v.ChatID, true
+	case tgbotapi.DeleteMessageConfig:
+		return v.ChatID, false
+	default:
+		return 0, false
+	}
+}
+
+// sleep ждет d или отмены ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,145 @@
+package sender
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"todolist/config"
+)
+
+var start = time.Date(2025, time.June, 11, 12, 0, 0, 0, time.UTC)
+
+// newTestSender создает Sender с часами, которые двигает только тест
+func newTestSender(cfg config.SenderConfig) (*Sender, *time.Time) {
+	now := start
+	s := New(nil, cfg, zap.NewNop())
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// testConfig - лимит чата по умолчанию и высокий общий лимит, чтобы он не мешал проверять лимит чата
+var testConfig = config.SenderConfig{GlobalRate: 1000, ChatInterval: time.Second, ChatBurst: 3}
+
+func TestReserveChatBurst(t *testing.T) {
+	s, _ := newTestSender(testConfig)
+	const chat = 42
+
+	// Серия из ChatBurst сообщений уходит сразу, с шагом общего лимита
+	for i := 0; i < testConfig.ChatBurst; i++ {
+		if at := s.reserve(chat, start); at.Sub(start) > 10*time.Millisecond {
+			t.Fatalf("message %d of the burst delayed until +%v", i+1, at.Sub(start))
+		}
+	}
+
+	// Другой чат лимитом первого не ограничен
+	if at := s.earliest(chat+1, start); at.Sub(start) > 10*time.Millisecond {
+		t.Fatalf("other chat delayed until +%v", at.Sub(start))
+	}
+
+	// Дальше - одно сообщение в ChatInterval
+	for i := 1; i <= 3; i++ {
+		want := start.Add(time.Duration(i) * testConfig.ChatInterval)
+		if at := s.reserve(chat, start); !at.Equal(want) {
+			t.Fatalf("message after the burst #%d at +%v, want +%v", i, at.Sub(start), want.Sub(start))
+		}
+	}
+}
+
+func TestReserveGlobalRate(t *testing.T) {
+	s, _ := newTestSender(config.SenderConfig{GlobalRate: 10, ChatInterval: time.Second, ChatBurst: 1})
+
+	for i := 0; i < 5; i++ {
+		want := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		if at := s.reserve(int64(i), start); !at.Equal(want) {
+			t.Fatalf("chat %d at +%v, want +%v", i, at.Sub(start), want.Sub(start))
+		}
+	}
+}
+
+// TestPauseHonorsRetryAfter проверяет, что после 429 запрос не уходит раньше retry_after
+// ни в этот чат (допустимая серия не сокращает паузу), ни в другие, ни с высоким приоритетом
+func TestPauseHonorsRetryAfter(t *testing.T) {
+	for _, retryAfter := range []time.Duration{time.Second, 2 * time.Second, 5 * time.Second} {
+		s, now := newTestSender(testConfig)
+		const chat = 42
+
+		s.pause(chat, retryAfter)
+		want := start.Add(retryAfter)
+
+		if at := s.earliest(chat, start); !at.Equal(want) {
+			t.Fatalf("retry_after %v: chat available at +%v", retryAfter, at.Sub(start))
+		}
+		if at := s.earliest(chat+1, start); !at.Equal(want) {
+			t.Fatalf("retry_after %v: other chat available at +%v", retryAfter, at.Sub(start))
+		}
+		if at := s.reserve(chat, start); !at.Equal(want) {
+			t.Fatalf("retry_after %v: interactive slot at +%v", retryAfter, at.Sub(start))
+		}
+		if _, ok := s.tryReserveBulk(chat+1, start); ok {
+			t.Fatalf("retry_after %v: bulk slot reserved during the pause", retryAfter)
+		}
+
+		// После паузы серия снова доступна
+		*now = want.Add(time.Minute)
+		if _, ok := s.tryReserveBulk(chat+1, *now); !ok {
+			t.Fatalf("retry_after %v: bulk slot not available after the pause", retryAfter)
+		}
+	}
+}
+
+// TestBulkWaitsForInteractive проверяет, что рассылка не занимает слот,
+// пока ответы пользователям ждут своей очереди
+func TestBulkWaitsForInteractive(t *testing.T) {
+	s, _ := newTestSender(testConfig)
+
+	s.interactiveWaiting = 1
+	if _, ok := s.tryReserveBulk(1, start); ok {
+		t.Fatal("bulk slot reserved while an interactive request is waiting")
+	}
+
+	s.interactiveWaiting = 0
+	if _, ok := s.tryReserveBulk(1, start); !ok {
+		t.Fatal("bulk slot not reserved when nothing is waiting")
+	}
+
+	// Занятый слот рассылка не резервирует заранее, а ответ пользователю резервирует
+	s2, _ := newTestSender(config.SenderConfig{GlobalRate: 1, ChatInterval: time.Second, ChatBurst: 1})
+	s2.reserve(1, start)
+	if at, ok := s2.tryReserveBulk(2, start); ok || !at.Equal(start.Add(time.Second)) {
+		t.Fatalf("bulk = %v, %v, want wait until +1s", at.Sub(start), ok)
+	}
+	if at := s2.reserve(2, start); !at.Equal(start.Add(time.Second)) {
+		t.Fatalf("interactive slot at +%v, want +1s", at.Sub(start))
+	}
+}
+
+func TestChatOf(t *testing.T) {
+	file := tgbotapi.FileID("file")
+	tests := []struct {
+		name    string
+		c       tgbotapi.Chattable
+		limited bool
+	}{
+		{"message", tgbotapi.NewMessage(7, "text"), true},
+		{"photo", tgbotapi.NewPhoto(7, file), true},
+		{"document", tgbotapi.NewDocument(7, file), true},
+		{"video", tgbotapi.NewVideo(7, file), true},
+		{"audio", tgbotapi.NewAudio(7, file), true},
+		{"voice", tgbotapi.NewVoice(7, file), true},
+		{"edit", tgbotapi.NewEditMessageText(7, 1, "text"), true},
+		{"delete", tgbotapi.NewDeleteMessage(7, 1), false},
+		{"callback", tgbotapi.NewCallback("id", "text"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID, limited := chatOf(tt.c)
+			if limited != tt.limited || (limited && chatID != 7) {
+				t.Fatalf("chatOf = %d, %v, want 7, %v", chatID, limited, tt.limited)
+			}
+		})
+	}
+}
@@ -0,0 +1,722 @@
+// Package timeparse разбирает дату и время, записанные естественным языком
+// на русском или английском: "через 2 часа", "in 3 days", "в пятницу в 9",
+// "next monday", "послезавтра утром", "end of month", "25.12 14:00".
+//
+// Пакет не обращается к системным часам: все выражения вычисляются
+// относительно переданного момента now и в его часовом поясе.
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmpty возвращается для пустой строки
+	ErrEmpty = errors.New("empty time expression")
+	// ErrUnrecognized возвращается, если часть выражения не удалось разобрать
+	ErrUnrecognized = errors.New("unrecognized time expression")
+	// ErrInvalidDate возвращается для несуществующей даты, например 31.02
+	ErrInvalidDate = errors.New("invalid date")
+	// ErrInvalidTime возвращается для некорректного времени, например 25:00
+	ErrInvalidTime = errors.New("invalid time")
+	// ErrConflict возвращается, если в выражении указано несколько дат или времен
+	ErrConflict = errors.New("conflicting date or time")
+	// ErrPast возвращается, если указанный момент уже прошел, например "сегодня в 9" после 9 утра
+	ErrPast = errors.New("time is in the past")
+	// ErrOutOfRange возвращается для момента дальше MaxYears лет от текущего
+	ErrOutOfRange = errors.New("time is out of range")
+)
+
+// DefaultHour - время, которое подставляется, если указана только дата
+const DefaultHour = 9
+
+// MaxYears - насколько далеко вперед можно указать момент
+const MaxYears = 100
+
+// endOfHour - время для выражений "конец дня", "конец месяца", "end of week"
+const endOfHour = 18
+
+// rollover определяет, как переносить результат, оказавшийся в прошлом
+type rollover int
+
+const (
+	rollNone rollover = iota
+	rollDay
+	rollWeek
+	rollYear
+)
+
+var (
+	clockRegex   = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	dateRegex    = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?$`)
+	isoDateRegex = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	numberRegex  = regexp.MustCompile(`^\d+$`)
+	yearRegex    = regexp.MustCompile(`^\d{4}$`)
+
+	digitLetterRegex = regexp.MustCompile(`(\d)([\p{L}])`)
+	letterDigitRegex = regexp.MustCompile(`([\p{L}])(\d)`)
+)
+
+// parser хранит разобранные части выражения
+type parser struct {
+	now    time.Time
+	tokens []string
+
+	date    time.Time
+	hasDate bool
+	// dateEnd - индекс токена сразу после даты
+	dateEnd int
+
+	hour, minute int
+	hasTime      bool
+	pm           *bool
+
+	period      period
+	keepClock   bool
+	defaultHour int
+	roll        rollover
+}
+
+// Parse разбирает выражение даты и времени относительно момента now.
+//
+// Если указано только время, которое сегодня уже прошло, результат переносится
+// на завтра; день недели без "next" - на следующую неделю; дата без года - на
+// следующий год. Если указана только дата, время берется равным DefaultHour.
+// Момент, который уже прошел и не переносится ("сегодня" после 9 утра, дата
+// с годом в прошлом), возвращает ErrPast.
+func Parse(input string, now time.Time) (time.Time, error) {
+	tokens := tokenize(input)
+	if len(tokens) == 0 {
+		return time.Time{}, ErrEmpty
+	}
+
+	p := &parser{
+		now:         now,
+		tokens:      tokens,
+		defaultHour: DefaultHour,
+	}
+
+	for i := 0; i < len(tokens); {
+		hadDate := p.hasDate
+		consumed, err := p.match(i)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !hadDate && p.hasDate {
+			p.dateEnd = i + consumed
+		}
+
+		if consumed == 0 {
+			if !fillerWords[tokens[i]] {
+				return time.Time{}, fmt.Errorf("%w: %q", ErrUnrecognized, tokens[i])
+			}
+			consumed = 1
+		}
+
+		i += consumed
+	}
+
+	return p.result()
+}
+
+// tokenize приводит строку к нижнему регистру и разбивает ее на слова,
+// отделяя числа от единиц измерения ("2h" -> "2 h", "9am" -> "9 am")
+func tokenize(input string) []string {
+	s := strings.ToLower(strings.TrimSpace(input))
+	s = strings.ReplaceAll(s, "ё", "е")
+	s = strings.NewReplacer(",", " ", ";", " ", "!", " ", "?", " ").Replace(s)
+	s = digitLetterRegex.ReplaceAllString(s, "$1 $2")
+	s = letterDigitRegex.ReplaceAllString(s, "$1 $2")
+
+	fields := strings.Fields(s)
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimRight(field, ".")
+		if field != "" {
+			tokens = append(tokens, field)
+		}
+	}
+
+	return tokens
+}
+
+// match пробует разобрать выражение, начинающееся с токена i,
+// и возвращает количество использованных токенов
+func (p *parser) match(i int) (int, error) {
+	matchers := []func(int) (int, error){
+		p.matchRelative,
+		p.matchEndOf,
+		p.matchNext,
+		p.matchDayOffset,
+		p.matchWeekday,
+		p.matchMonthDate,
+		p.matchNumericDate,
+		p.matchClock,
+		p.matchPeriod,
+	}
+
+	for _, matcher := range matchers {
+		consumed, err := matcher(i)
+		if err != nil || consumed > 0 {
+			return consumed, err
+		}
+	}
+
+	return 0, nil
+}
+
+// token возвращает токен i или пустую строку, если токенов меньше
+func (p *parser) token(i int) string {
+	if i < 0 || i >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[i]
+}
+
+// today возвращает начало текущего дня
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+}
+
+// setDate запоминает дату и способ переноса, если она окажется в прошлом
+func (p *parser) setDate(date time.Time, roll rollover) error {
+	if p.hasDate {
+		return ErrConflict
+	}
+
+	p.date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, p.now.Location())
+	p.hasDate = true
+	p.roll = roll
+	return nil
+}
+
+// setClock запоминает время суток
+func (p *parser) setClock(hour, minute int) error {
+	if p.hasTime {
+		return ErrConflict
+	}
+
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return fmt.Errorf("%w: %02d:%02d", ErrInvalidTime, hour, minute)
+	}
+
+	p.hour, p.minute = hour, minute
+	p.hasTime = true
+	return nil
+}
+
+// matchRelative разбирает "через 2 часа", "через полчаса", "in 3 days", "in a week", "+30m"
+func (p *parser) matchRelative(i int) (int, error) {
+	j := i
+	count := 1
+
+	switch tok := p.token(i); {
+	case relativeWords[tok]:
+		j++
+	case strings.HasPrefix(tok, "+") && numberRegex.MatchString(tok[1:]):
+		u, ok := unitWords[p.token(i+1)]
+		if !ok {
+			return 0, nil
+		}
+		count, _ = strconv.Atoi(tok[1:])
+		return p.applyRelative(2, count, u)
+	default:
+		return 0, nil
+	}
+
+	// "через полчаса", "in half an hour"
+	if p.token(j) == "полчаса" {
+		return p.applyRelativeDuration(j-i+1, 30*time.Minute)
+	}
+	if p.token(j) == "half" {
+		k := j + 1
+		if p.token(k) == "an" || p.token(k) == "a" {
+			k++
+		}
+		if u, ok := unitWords[p.token(k)]; ok && u == unitHour {
+			return p.applyRelativeDuration(k-i+1, 30*time.Minute)
+		}
+		return 0, nil
+	}
+
+	if n, ok := parseCount(p.token(j)); ok {
+		count = n
+		j++
+		// "a couple of hours"
+		if p.token(j) == "couple" {
+			count = 2
+			j++
+		}
+		if p.token(j) == "of" {
+			j++
+		}
+	}
+
+	u, ok := unitWords[p.token(j)]
+	if !ok {
+		return 0, nil
+	}
+
+	return p.applyRelative(j-i+1, count, u)
+}
+
+// applyRelative применяет смещение на count единиц u
+func (p *parser) applyRelative(consumed, count int, u unit) (int, error) {
+	// Ограничение проверяется до умножения, иначе time.Duration переполнится
+	if count > maxCounts[u] {
+		return 0, fmt.Errorf("%w: %d", ErrOutOfRange, count)
+	}
+
+	switch u {
+	case unitMinute:
+		return p.applyRelativeDuration(consumed, time.Duration(count)*time.Minute)
+	case unitHour:
+		return p.applyRelativeDuration(consumed, time.Duration(count)*time.Hour)
+	}
+
+	var date time.Time
+	switch u {
+	case unitDay:
+		date = p.now.AddDate(0, 0, count)
+	case unitWeek:
+		date = p.now.AddDate(0, 0, 7*count)
+	case unitMonth:
+		date = p.now.AddDate(0, count, 0)
+	case unitYear:
+		date = p.now.AddDate(count, 0, 0)
+	}
+
+	if err := p.setDate(date, rollNone); err != nil {
+		return 0, err
+	}
+
+	// "через 3 дня" без времени - в то же время, что и сейчас
+	p.keepClock = true
+	return consumed, nil
+}
+
+// applyRelativeDuration устанавливает точный момент now + d
+func (p *parser) applyRelativeDuration(consumed int, d time.Duration) (int, error) {
+	at := p.now.Add(d).Truncate(time.Minute)
+
+	if err := p.setDate(at, rollNone); err != nil {
+		return 0, err
+	}
+	if err := p.setClock(at.Hour(), at.Minute()); err != nil {
+		return 0, err
+	}
+
+	return consumed, nil
+}
+
+// parseCount разбирает количество, записанное цифрами или словом
+func parseCount(tok string) (int, bool) {
+	if numberRegex.MatchString(tok) {
+		n, err := strconv.Atoi(tok)
+		return n, err == nil
+	}
+
+	n, ok := numberWords[tok]
+	return n, ok
+}
+
+// matchEndOf разбирает "end of month", "end of the week", "конец месяца", "в конце года"
+func (p *parser) matchEndOf(i int) (int, error) {
+	j := i
+	switch p.token(j) {
+	case "end":
+		j++
+		if p.token(j) != "of" {
+			return 0, nil
+		}
+		j++
+		if p.token(j) == "the" || p.token(j) == "this" {
+			j++
+		}
+	case "конец", "конце":
+		j++
+		if p.token(j) == "этого" {
+			j++
+		}
+	default:
+		return 0, nil
+	}
+
+	u, ok := unitWords[p.token(j)]
+	if !ok {
+		return 0, nil
+	}
+
+	today := p.today()
+	var date time.Time
+	roll := rollNone
+	switch u {
+	case unitDay:
+		// Конец дня, который уже наступил, переносится на завтра
+		date = today
+		roll = rollDay
+	case unitWeek:
+		// Конец рабочей недели - пятница
+		days := (int(time.Friday) - int(today.Weekday()) + 7) % 7
+		date = today.AddDate(0, 0, days)
+	case unitMonth:
+		date = time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location())
+	case unitYear:
+		date = time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, today.Location())
+	default:
+		return 0, nil
+	}
+
+	if err := p.setDate(date, roll); err != nil {
+		return 0, err
+	}
+
+	p.defaultHour = endOfHour
+	return j - i + 1, nil
+}
+
+// matchNext разбирает "next monday", "в следующую пятницу", "next week", "на следующей неделе"
+func (p *parser) matchNext(i int) (int, error) {
+	if !nextWords[p.token(i)] {
+		return 0, nil
+	}
+
+	next := p.token(i + 1)
+	today := p.today()
+
+	if weekday, ok := weekdayWords[next]; ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		if err := p.setDate(today.AddDate(0, 0, days), rollNone); err != nil {
+			return 0, err
+		}
+		return 2, nil
+	}
+
+	var date time.Time
+	switch u, ok := unitWords[next]; {
+	case ok && u == unitWeek:
+		// Следующая неделя начинается с понедельника
+		days := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		date = today.AddDate(0, 0, days)
+	case ok && u == unitMonth:
+		date = time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location())
+	case ok && u == unitYear:
+		date = time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location())
+	default:
+		return 0, nil
+	}
+
+	if err := p.setDate(date, rollNone); err != nil {
+		return 0, err
+	}
+	return 2, nil
+}
+
+// matchDayOffset разбирает "сегодня", "завтра", "послезавтра", "tomorrow", "day after tomorrow"
+func (p *parser) matchDayOffset(i int) (int, error) {
+	if p.token(i) == "day" && p.token(i+1) == "after" && p.token(i+2) == "tomorrow" {
+		if err := p.setDate(p.today().AddDate(0, 0, 2), rollNone); err != nil {
+			return 0, err
+		}
+		return 3, nil
+	}
+
+	offset, ok := dayOffsetWords[p.token(i)]
+	if !ok {
+		return 0, nil
+	}
+
+	if err := p.setDate(p.today().AddDate(0, 0, offset), rollNone); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// matchWeekday разбирает день недели: ближайший такой день, включая сегодня
+func (p *parser) matchWeekday(i int) (int, error) {
+	weekday, ok := weekdayWords[p.token(i)]
+	if !ok {
+		return 0, nil
+	}
+
+	today := p.today()
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+
+	if err := p.setDate(today.AddDate(0, 0, days), rollWeek); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// matchMonthDate разбирает "25 декабря", "25 dec 2025", "december 25"
+func (p *parser) matchMonthDate(i int) (int, error) {
+	var day int
+	var month time.Month
+	consumed := 2
+
+	if m, ok := monthWords[p.token(i+1)]; ok && numberRegex.MatchString(p.token(i)) {
+		day, _ = strconv.Atoi(p.token(i))
+		month = m
+	} else if m, ok := monthWords[p.token(i)]; ok && numberRegex.MatchString(p.token(i+1)) && len(p.token(i+1)) <= 2 {
+		day, _ = strconv.Atoi(p.token(i + 1))
+		month = m
+	} else {
+		return 0, nil
+	}
+
+	if !yearRegex.MatchString(p.token(i + consumed)) {
+		date, err := p.makeYearlessDate(month, day)
+		if err != nil {
+			return 0, err
+		}
+		if err := p.setDate(date, rollYear); err != nil {
+			return 0, err
+		}
+		return consumed, nil
+	}
+
+	year, _ := strconv.Atoi(p.token(i + consumed))
+	date, err := p.makeDate(year, month, day)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.setDate(date, rollNone); err != nil {
+		return 0, err
+	}
+	return consumed + 1, nil
+}
+
+// matchNumericDate разбирает "25.12", "25.12.2025", "25.12.25", "2025-12-25"
+func (p *parser) matchNumericDate(i int) (int, error) {
+	tok := p.token(i)
+
+	if m := isoDateRegex.FindStringSubmatch(tok); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+
+		date, err := p.makeDate(year, time.Month(month), day)
+		if err != nil {
+			return 0, err
+		}
+		if err := p.setDate(date, rollNone); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	m := dateRegex.FindStringSubmatch(tok)
+	if m == nil {
+		return 0, nil
+	}
+
+	day, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+
+	if m[3] == "" {
+		date, err := p.makeYearlessDate(time.Month(month), day)
+		if err != nil {
+			return 0, err
+		}
+		if err := p.setDate(date, rollYear); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	year, _ := strconv.Atoi(m[3])
+	if year < 100 {
+		year += 2000
+	}
+
+	date, err := p.makeDate(year, time.Month(month), day)
+	if err != nil {
+		return 0, err
+	}
+	if err := p.setDate(date, rollNone); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// makeDate создает дату и проверяет, что она существует
+func (p *parser) makeDate(year int, month time.Month, day int) (time.Time, error) {
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+	if month < time.January || month > time.December || date.Day() != day || date.Month() != month {
+		return time.Time{}, fmt.Errorf("%w: %02d.%02d.%d", ErrInvalidDate, day, int(month), year)
+	}
+	return date, nil
+}
+
+// makeYearlessDate создает дату без года: в текущем году, а если такого дня в нем нет
+// (29.02 в невисокосный год) - в ближайшем году, где он есть
+func (p *parser) makeYearlessDate(month time.Month, day int) (time.Time, error) {
+	date, ok := p.nextDate(p.now.Year(), month, day)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %02d.%02d", ErrInvalidDate, day, int(month))
+	}
+	return date, nil
+}
+
+// nextDate ищет день month/day начиная с года year. Между високосными годами бывает
+// до 8 лет (2096 и 2104), поэтому дальше искать не нужно.
+func (p *parser) nextDate(year int, month time.Month, day int) (time.Time, bool) {
+	for y := year; y <= year+8; y++ {
+		if date, err := p.makeDate(y, month, day); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// matchClock разбирает "15:30", "в 9", "at 9", "9 am", "7 вечера", "в 10 часов", "полдень"
+func (p *parser) matchClock(i int) (int, error) {
+	tok := p.token(i)
+
+	if hour, ok := fixedClocks[tok]; ok {
+		return 1, p.setClock(hour, 0)
+	}
+
+	hour, minute := -1, 0
+	if m := clockRegex.FindStringSubmatch(tok); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+	} else if numberRegex.MatchString(tok) && len(tok) <= 2 {
+		next := p.token(i + 1)
+		_, isMeridiem := meridiemWords[next]
+		// Число сразу после даты без времени - тоже час: "friday 9", "завтра 10"
+		afterDate := p.hasDate && !p.hasTime && i == p.dateEnd
+		if !clockPrefixes[p.token(i-1)] && !isMeridiem && !hourWords[next] && !afterDate {
+			return 0, nil
+		}
+		hour, _ = strconv.Atoi(tok)
+	} else {
+		return 0, nil
+	}
+
+	consumed := 1
+	next := p.token(i + consumed)
+	if hourWords[next] {
+		consumed++
+		next = p.token(i + consumed)
+	}
+
+	if pm, ok := meridiemWords[next]; ok {
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("%w: %d %s", ErrInvalidTime, hour, next)
+		}
+		hour = applyMeridiem(hour, pm, next)
+		p.pm = &pm
+		consumed++
+	}
+
+	return consumed, p.setClock(hour, minute)
+}
+
+// applyMeridiem переводит час 12-часового формата в 24-часовой
+func applyMeridiem(hour int, pm bool, word string) int {
+	switch {
+	case word == "дня":
+		// "3 дня" - 15:00, "12 дня" - 12:00
+		if hour < 6 {
+			return hour + 12
+		}
+		return hour
+	case word == "ночи":
+		// "11 ночи" - 23:00, "2 ночи" - 02:00, "12 ночи" - 00:00
+		if hour >= 9 && hour < 12 {
+			return hour + 12
+		}
+		if hour == 12 {
+			return 0
+		}
+		return hour
+	case pm && hour < 12:
+		return hour + 12
+	case !pm && hour == 12:
+		return 0
+	}
+	return hour
+}
+
+// matchPeriod разбирает части суток: "утром", "вечером", "morning"
+func (p *parser) matchPeriod(i int) (int, error) {
+	pd, ok := periodWords[p.token(i)]
+	if !ok {
+		return 0, nil
+	}
+
+	if p.period != periodNone {
+		return 0, ErrConflict
+	}
+
+	p.period = pd
+	return 1, nil
+}
+
+// result собирает итоговый момент времени из разобранных частей
+func (p *parser) result() (time.Time, error) {
+	if !p.hasDate && !p.hasTime && p.period == periodNone {
+		return time.Time{}, ErrUnrecognized
+	}
+
+	date := p.today()
+	if p.hasDate {
+		date = p.date
+	}
+
+	hour, minute := p.hour, p.minute
+	switch {
+	case p.hasTime:
+		// "вечером в 7" - 19:00, если время не уточнено как утреннее
+		if p.pm == nil && hour < 12 && (p.period == periodDay || p.period == periodEvening) {
+			hour += 12
+		}
+	case p.period != periodNone:
+		hour, minute = periodHours[p.period], 0
+	case p.keepClock:
+		hour, minute = p.now.Hour(), p.now.Minute()
+	default:
+		hour, minute = p.defaultHour, 0
+	}
+
+	result := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, p.now.Location())
+
+	roll := p.roll
+	if !p.hasDate {
+		roll = rollDay
+	}
+
+	if !result.After(p.now) {
+		switch roll {
+		case rollDay:
+			result = result.AddDate(0, 0, 1)
+		case rollWeek:
+			result = result.AddDate(0, 0, 7)
+		case rollYear:
+			// AddDate(1, 0, 0) превратил бы 29.02 в 01.03
+			next, _ := p.nextDate(result.Year()+1, result.Month(), result.Day())
+			result = time.Date(next.Year(), next.Month(), next.Day(), hour, minute, 0, 0, p.now.Location())
+		default:
+			return time.Time{}, fmt.Errorf("%w: %s", ErrPast, result.Format("02.01.2006 15:04"))
+		}
+	}
+
+	if result.After(p.now.AddDate(MaxYears, 0, 0)) {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrOutOfRange, result.Format("02.01.2006"))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package timeparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// msk - фиксированный пояс, чтобы тест не зависел от базы часовых поясов системы
+var msk = time.FixedZone("MSK", 3*60*60)
+
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, msk)
+}
+
+func TestParse(t *testing.T) {
+	// Среда, 11 июня 2025, 14:30
+	wednesday := at(2025, time.June, 11, 14, 30)
+	// Последний день года, вечер
+	yearEnd := at(2025, time.December, 31, 18, 0)
+
+	tests := []struct {
+		name    string
+		input   string
+		now     time.Time
+		want    time.Time
+		wantErr error
+	}{
+		{name: "через часы", input: "через 2 часа", now: wednesday, want: at(2025, time.June, 11, 16, 30)},
+		{name: "через полчаса", input: "через полчаса", now: wednesday, want: at(2025, time.June, 11, 15, 0)},
+		{name: "in days keeps clock", input: "in 3 days", now: wednesday, want: at(2025, time.June, 14, 14, 30)},
+		{name: "сокращение", input: "+30m", now: wednesday, want: at(2025, time.June, 11, 15, 0)},
+		{name: "день недели с предлогом", input: "в пятницу в 9", now: wednesday, want: at(2025, time.June, 13, 9, 0)},
+		{name: "weekday bare hour", input: "friday 9", now: wednesday, want: at(2025, time.June, 13, 9, 0)},
+		{name: "weekday hour with am", input: "friday 9 pm", now: wednesday, want: at(2025, time.June, 13, 21, 0)},
+		{name: "сегодняшний день недели в прошлом", input: "среда 10", now: wednesday, want: at(2025, time.June, 18, 10, 0)},
+		{name: "next weekday", input: "next monday", now: wednesday, want: at(2025, time.June, 16, 9, 0)},
+		{name: "next week", input: "на следующей неделе", now: wednesday, want: at(2025, time.June, 16, 9, 0)},
+		{name: "послезавтра утром", input: "послезавтра утром", now: wednesday, want: at(2025, time.June, 13, 9, 0)},
+		{name: "завтра с числом", input: "завтра 10", now: wednesday, want: at(2025, time.June, 12, 10, 0)},
+		{name: "сегодня вечером", input: "сегодня вечером", now: wednesday, want: at(2025, time.June, 11, 19, 0)},
+		{name: "вечером в 7", input: "вечером в 7", now: wednesday, want: at(2025, time.June, 11, 19, 0)},
+		{name: "end of month", input: "end of month", now: wednesday, want: at(2025, time.June, 30, 18, 0)},
+		{name: "end of day", input: "end of day", now: wednesday, want: at(2025, time.June, 11, 18, 0)},
+		{name: "end of day after it", input: "конец дня", now: at(2025, time.June, 11, 20, 0), want: at(2025, time.June, 12, 18, 0)},
+		{name: "конец недели", input: "в конце недели", now: wednesday, want: at(2025, time.June, 13, 18, 0)},
+		{name: "время сегодня", input: "15:00", now: wednesday, want: at(2025, time.June, 11, 15, 0)},
+		{name: "прошедшее время переносится на завтра", input: "14:00", now: wednesday, want: at(2025, time.June, 12, 14, 0)},
+		{name: "дата и время", input: "25.12 14:00", now: wednesday, want: at(2025, time.December, 25, 14, 0)},
+		{name: "дата словом", input: "25 декабря", now: wednesday, want: at(2025, time.December, 25, 9, 0)},
+		{name: "iso", input: "2025-07-01 8 am", now: wednesday, want: at(2025, time.July, 1, 8, 0)},
+
+		{name: "конец года: дата следующего года", input: "01.01 10:00", now: yearEnd, want: at(2026, time.January, 1, 10, 0)},
+		{name: "конец года: прошедшее время сегодня", input: "31.12 09:00", now: yearEnd, want: at(2026, time.December, 31, 9, 0)},
+		{name: "конец года: месяц словом", input: "2 января в 12:00", now: yearEnd, want: at(2026, time.January, 2, 12, 0)},
+
+		{name: "29.02 в невисокосный год", input: "29.02", now: at(2027, time.January, 10, 12, 0), want: at(2028, time.February, 29, 9, 0)},
+		{name: "29.02 после даты в високосный год", input: "29.02", now: at(2028, time.March, 1, 12, 0), want: at(2032, time.February, 29, 9, 0)},
+		{name: "29 февраля", input: "29 февраля", now: at(2025, time.June, 11, 12, 0), want: at(2028, time.February, 29, 9, 0)},
+
+		{name: "сегодня уже прошло", input: "сегодня", now: wednesday, wantErr: ErrPast},
+		{name: "сегодня в прошедший час", input: "сегодня в 9", now: wednesday, wantErr: ErrPast},
+		{name: "дата с годом в прошлом", input: "01.01.2020", now: wednesday, wantErr: ErrPast},
+		{name: "огромное количество лет", input: "через 1000000000 лет", now: wednesday, wantErr: ErrOutOfRange},
+		{name: "огромное количество минут", input: "in 99999999999 minutes", now: wednesday, wantErr: ErrOutOfRange},
+		{name: "далекий год", input: "01.01.9999", now: wednesday, wantErr: ErrOutOfRange},
+		{name: "несуществующая дата", input: "31.02", now: wednesday, wantErr: ErrInvalidDate},
+		{name: "несуществующее время", input: "25:00", now: wednesday, wantErr: ErrInvalidTime},
+		{name: "две даты", input: "завтра послезавтра", now: wednesday, wantErr: ErrConflict},
+		{name: "пустая строка", input: "  ", now: wednesday, wantErr: ErrEmpty},
+		{name: "непонятное слово", input: "когда-нибудь", now: wednesday, wantErr: ErrUnrecognized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.now)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %v (result %v)", tt.input, err, tt.wantErr, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package timeparse
+
+import "time"
+
+// unit - единица относительного смещения
+type unit int
+
+const (
+	unitMinute unit = iota
+	unitHour
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+// period - часть суток ("утром", "вечером")
+type period int
+
+const (
+	periodNone period = iota
+	periodMorning
+	periodDay
+	periodEvening
+	periodNight
+)
+
+// relativeWords - слова, начинающие относительное выражение ("через 2 часа", "in 3 days")
+var relativeWords = map[string]bool{
+	"через": true,
+	"in":    true,
+}
+
+// fillerWords - предлоги и артикли, которые не влияют на результат
+var fillerWords = map[string]bool{
+	"в": true, "во": true, "на": true, "к": true, "до": true, "и": true,
+	"at": true, "in": true, "on": true, "by": true, "the": true, "of": true, "this": true,
+	"этот": true, "эту": true, "это": true, "эта": true,
+}
+
+// clockPrefixes - слова, после которых одиночное число считается часом ("в 9", "at 9")
+var clockPrefixes = map[string]bool{
+	"в": true, "во": true, "к": true, "at": true, "by": true, "до": true,
+}
+
+// nextWords - слова, указывающие на следующий день недели или период
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующую": true, "следующее": true,
+	"следующая": true, "следующей": true, "следующем": true, "след": true,
+}
+
+// numberWords - числительные, которые можно написать словом
+var numberWords = map[string]int{
+	"один": 1, "одну": 1, "одна": 1, "одно": 1, "пару": 2, "два": 2, "две": 2,
+	"три": 3, "четыре": 4, "пять": 5, "шесть": 6, "семь": 7, "восемь": 8,
+	"девять": 9, "десять": 10, "пятнадцать": 15, "двадцать": 20, "тридцать": 30,
+	"сорок": 40,
+	"a":     1, "an": 1, "one": 1, "couple": 2, "two": 2, "three": 3, "four": 4,
+	"five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"fifteen": 15, "twenty": 20, "thirty": 30, "forty": 40,
+}
+
+// unitWords - названия единиц времени во всех падежах и сокращения
+var unitWords = map[string]unit{
+	"м": unitMinute, "мин": unitMinute, "минута": unitMinute, "минуту": unitMinute,
+	"минуты": unitMinute, "минут": unitMinute,
+	"m": unitMinute, "min": unitMinute, "mins": unitMinute, "minute": unitMinute, "minutes": unitMinute,
+
+	"ч": unitHour, "час": unitHour, "часа": unitHour, "часов": unitHour,
+	"h": unitHour, "hr": unitHour, "hrs": unitHour, "hour": unitHour, "hours": unitHour,
+
+	"д": unitDay, "дн": unitDay, "день": unitDay, "дня": unitDay, "дней": unitDay,
+	"сутки": unitDay, "суток": unitDay,
+	"d": unitDay, "day": unitDay, "days": unitDay,
+
+	"нед": unitWeek, "неделя": unitWeek, "неделю": unitWeek, "недели": unitWeek, "неделе": unitWeek, "недель": unitWeek,
+	"w": unitWeek, "wk": unitWeek, "week": unitWeek, "weeks": unitWeek,
+
+	"мес": unitMonth, "месяц": unitMonth, "месяца": unitMonth, "месяце": unitMonth, "месяцев": unitMonth,
+	"mo": unitMonth, "month": unitMonth, "months": unitMonth,
+
+	"год": unitYear, "года": unitYear, "году": unitYear, "лет": unitYear,
+	"y": unitYear, "yr": unitYear, "year": unitYear, "years": unitYear,
+}
+
+// maxCounts - наибольшее количество единиц в относительном выражении, не дальше MaxYears лет
+var maxCounts = map[unit]int{
+	unitMinute: MaxYears * 366 * 24 * 60,
+	unitHour:   MaxYears * 366 * 24,
+	unitDay:    MaxYears * 366,
+	unitWeek:   MaxYears * 53,
+	unitMonth:  MaxYears * 12,
+	unitYear:   MaxYears,
+}
+
+// dayOffsetWords - слова, задающие день относительно сегодняшнего
+var dayOffsetWords = map[string]int{
+	"сегодня": 0, "today": 0,
+	"завтра": 1, "tomorrow": 1,
+	"послезавтра": 2,
+}
+
+// weekdayWords - названия дней недели во всех используемых падежах и сокращения
+var weekdayWords = map[string]time.Weekday{
+	"понедельник": time.Monday, "пн": time.Monday,
+	"вторник": time.Tuesday, "вт": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday,
+	"четверг": time.Thursday, "чт": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday,
+	"воскресенье": time.Sunday, "вс": time.Sunday,
+
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// monthWords - названия месяцев (в родительном падеже для русского языка)
+var monthWords = map[string]time.Month{
+	"января": time.January, "янв": time.January,
+	"февраля": time.February, "фев": time.February,
+	"марта": time.March, "мар": time.March,
+	"апреля": time.April, "апр": time.April,
+	"мая":  time.May,
+	"июня": time.June, "июн": time.June,
+	"июля": time.July, "июл": time.July,
+	"августа": time.August, "авг": time.August,
+	"сентября": time.September, "сен": time.September, "сент": time.September,
+	"октября": time.October, "окт": time.October,
+	"ноября": time.November, "ноя": time.November,
+	"декабря": time.December, "дек": time.December,
+
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// periodWords - части суток
+var periodWords = map[string]period{
+	"утром": periodMorning, "утро": periodMorning, "morning": periodMorning,
+	"днем": periodDay, "afternoon": periodDay,
+	"вечером": periodEvening, "вечер": periodEvening, "evening": periodEvening, "tonight": periodEvening,
+	"ночью": periodNight, "night": periodNight,
+}
+
+// periodHours - время по умолчанию для частей суток
+var periodHours = map[period]int{
+	periodMorning: 9,
+	periodDay:     13,
+	periodEvening: 19,
+	periodNight:   23,
+}
+
+// meridiemWords - уточнения к часу ("9 утра", "7 вечера", "9 pm"):
+// true означает время после полудня
+var meridiemWords = map[string]bool{
+	"am": false, "утра": false, "ночи": false,
+	"pm": true, "дня": true, "вечера": true,
+}
+
+// hourWords - слова после числа, подтверждающие, что это час ("в 9 часов")
+var hourWords = map[string]bool{
+	"час": true, "часа": true, "часов": true, "ч": true, "o'clock": true, "oclock": true,
+}
+
+// fixedClocks - слова, задающие точное время
+var fixedClocks = map[string]int{
+	"полдень": 12, "noon": 12,
+	"полночь": 0, "midnight": 0,
+}
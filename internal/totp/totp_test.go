@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ SHA-1 из приложения B RFC 6238 ("12345678901234567890") в base32
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestCodeRFC6238 сверяет коды с тестовыми векторами RFC 6238 для HMAC-SHA1.
+// В RFC коды 8-значные, приложения используют последние 6 цифр.
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		want := tt.want[len(tt.want)-Digits:]
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	previous, err := Code(rfcSecret, Step(now)-1)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "текущий шаг", code: code, at: now, skew: 0, wantStep: Step(now), wantOK: true},
+		{name: "пробелы вокруг кода", code: " " + code + " ", at: now, skew: 0, wantStep: Step(now), wantOK: true},
+		{name: "предыдущий шаг в пределах допуска", code: previous, at: now, skew: 1, wantStep: Step(now) - 1, wantOK: true},
+		{name: "предыдущий шаг без допуска", code: previous, at: now, skew: 0},
+		{name: "код устарел", code: code, at: now.Add(2 * Period), skew: 1},
+		{name: "неверная длина", code: code[:Digits-1], at: now, skew: 1},
+		{name: "неверный код", code: "000000", at: now, skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("Validate = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Fatal("Code with an invalid secret returned no error")
+	}
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"todolist/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// AdminService предоставляет методы администрирования.
+// Права администратора проверяет вызывающий код (middleware обработчика телеграм бота).
+type AdminService struct {
+	userRepository      domain.UserRepository
+	sessionRepository   domain.SessionRepository
+	statsRepository     domain.StatsRepository
+	notificationService *NotificationService
+	logger              *zap.Logger
+
+	// broadcasting не дает запустить вторую рассылку, пока идет первая
+	broadcasting atomic.Bool
+}
+
+// BroadcastResult - итог рассылки
+type BroadcastResult struct {
+	Sent   int
+	Failed int
+	// Err - причина, по которой рассылка прервана, например остановка бота
+	Err error
+}
+
+// NewAdminService создает новый экземпляр AdminService
+func NewAdminService(
+	userRepository domain.UserRepository,
+	sessionRepository domain.SessionRepository,
+	statsRepository domain.StatsRepository,
+	notificationService *NotificationService,
+	logger *zap.Logger,
+) *AdminService {
+	return &AdminService{
+		userRepository:      userRepository,
+		sessionRepository:   sessionRepository,
+		statsRepository:     statsRepository,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// ListUsers возвращает всех пользователей, начиная с недавно входивших
+func (s *AdminService) ListUsers(ctx context.Context) ([]*domain.User, error) {
+	users, err := s.userRepository.List(ctx)
+	if err != nil {
+		s.logger.Error("failed to list users", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения пользователей: %w", err)
+	}
+	return users, nil
+}
+
+// SetUserActive блокирует или разблокирует пользователя.
+// При блокировке сессия пользователя завершается сразу, а не по истечении срока.
+func (s *AdminService) SetUserActive(ctx context.Context, admin *domain.User, userID int64, active bool) (*domain.User, error) {
+	if userID == admin.ID && !active {
+		return nil, domain.NewValidationError("user", "нельзя заблокировать самого себя")
+	}
+
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsActive == active {
+		return user, nil
+	}
+
+	if err := s.userRepository.SetActive(ctx, user.ID, active); err != nil {
+		s.logger.Error("failed to update user", zap.Error(err))
+		return nil, fmt.Errorf("ошибка изменения пользователя: %w", err)
+	}
+	user.IsActive = active
+
+	if !active {
+		if _, err := s.sessionRepository.DeleteByUserID(ctx, user.ID); err != nil {
+			s.logger.Error("failed to revoke sessions of banned user", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("user active flag changed",
+		zap.Int64("user_id", user.ID),
+		zap.Bool("active", active),
+		zap.Int64("admin_id", admin.ID))
+	return user, nil
+}
+
+// ListSessions возвращает действующие сессии
+func (s *AdminService) ListSessions(ctx context.Context) ([]*domain.Session, error) {
+	sessions, err := s.sessionRepository.ListActive(ctx)
+	if err != nil {
+		s.logger.Error("failed to list sessions", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения сессий: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает все сессии пользователя. Пользователь сможет снова войти через /start.
+func (s *AdminService) RevokeSession(ctx context.Context, admin *domain.User, userID int64) (*domain.User, error) {
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.sessionRepository.DeleteByUserID(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("failed to revoke session", zap.Error(err))
+		return nil, fmt.Errorf("ошибка завершения сессии: %w", err)
+	}
+
+	if count == 0 {
+		return nil, domain.Conflict(domain.EntitySession, user.ID, "у пользователя нет активных сессий")
+	}
+
+	s.logger.Info("session revoked", zap.Int64("user_id", user.ID), zap.Int64("admin_id", admin.ID))
+	return user, nil
+}
+
+// StartBroadcast запускает рассылку сообщения всем активным пользователям в отдельной горутине
+// и сразу возвращает число получателей. По завершении вызывается done с итогом.
+// Рассылка не занимает воркер администратора, а частоту отправки ограничивает Sender:
+// рассылка уступает ответам пользователям. Отмена ctx прерывает рассылку.
+func (s *AdminService) StartBroadcast(ctx context.Context, admin *domain.User, text string, done func(BroadcastResult)) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, domain.NewValidationError("text", "текст рассылки не может быть пустым")
+	}
+
+	users, err := s.ListUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var recipients []int64
+	for _, user := range users {
+		if user.IsActive {
+			recipients = append(recipients, user.TelegramID)
+		}
+	}
+
+	if !s.broadcasting.CompareAndSwap(false, true) {
+		return 0, domain.NewValidationError("text", "предыдущая рассылка еще не завершена")
+	}
+
+	s.logger.Info("broadcast started", zap.Int64("admin_id", admin.ID), zap.Int("recipients", len(recipients)))
+
+	go func() {
+		defer s.broadcasting.Store(false)
+
+		result := s.broadcast(ctx, recipients, "📢 "+text)
+		s.logger.Info("broadcast finished",
+			zap.Int64("admin_id", admin.ID),
+			zap.Int("sent", result.Sent),
+			zap.Int("failed", result.Failed),
+			zap.Error(result.Err))
+		done(result)
+	}()
+
+	return len(recipients), nil
+}
+
+// broadcast отправляет сообщение получателям по очереди
+func (s *AdminService) broadcast(ctx context.Context, recipients []int64, text string) BroadcastResult {
+	var result BroadcastResult
+	for _, telegramID := range recipients {
+		if err := s.notificationService.SendMessage(ctx, telegramID, text); err != nil {
+			if ctx.Err() != nil {
+				result.Err = ctx.Err()
+				return result
+			}
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+	}
+	return result
+}
+
+// GetStats возвращает общую статистику
+func (s *AdminService) GetStats(ctx context.Context) (*domain.Stats, error) {
+	stats, err := s.statsRepository.Get(ctx)
+	if err != nil {
+		s.logger.Error("failed to get stats", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения статистики: %w", err)
+	}
+	return stats, nil
+}
@@ -2,135 +2,593 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"todolist/config"
 	"todolist/internal/domain"
 
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// minPasswordLength - минимальная длина личного пароля
+const minPasswordLength = 8
+
+// maxPasswordBytes - bcrypt учитывает только первые 72 байта пароля
+const maxPasswordBytes = 72
+
+// sessionRenewInterval - сессия продлевается не чаще этого интервала, чтобы не писать в базу на каждое сообщение
+const sessionRenewInterval = time.Minute
+
+// loginAttemptsRetention - сколько хранятся записи о попытках входа
+const loginAttemptsRetention = 30 * 24 * time.Hour
+
+// secondFactorTimeout - сколько ждать код двухфакторной аутентификации после верного пароля
+const secondFactorTimeout = 5 * time.Minute
+
+// ErrSecondFactorRequired возвращается из Login, если пароль верный, но для входа нужен
+// код двухфакторной аутентификации. Код передается в VerifySecondFactor.
+var ErrSecondFactorRequired = errors.New("second factor required")
+
+// errInvalidCredentials не уточняет, что именно неверно: пароль или код приглашения
+var errInvalidCredentials = domain.NewValidationError("password", "неверный пароль или код приглашения")
+
+// errUserBanned возвращается при входе в аккаунт, отключенный администратором
+var errUserBanned = domain.Conflict(domain.EntityUser, 0, "аккаунт заблокирован администратором")
+
 // AuthService предоставляет методы для авторизации
 type AuthService struct {
 	userRepository    domain.UserRepository
 	sessionRepository domain.SessionRepository
+	inviteRepository  domain.InviteRepository
+	loginAttempts     domain.LoginAttemptRepository
+	twoFactor         *TwoFactorService
+	notifications     *NotificationService
 	config            *config.Config
 	logger            *zap.Logger
+
+	// globalAlertMu защищает lastGlobalAlert: о превышении общего лимита
+	// администраторы узнают не чаще одного раза за AUTH_LOGIN_GLOBAL_WINDOW
+	globalAlertMu   sync.Mutex
+	lastGlobalAlert time.Time
 }
 
 // NewAuthService создает новый экземпляр AuthService
 func NewAuthService(
 	userRepository domain.UserRepository,
 	sessionRepository domain.SessionRepository,
+	inviteRepository domain.InviteRepository,
+	loginAttempts domain.LoginAttemptRepository,
+	twoFactor *TwoFactorService,
+	notifications *NotificationService,
 	config *config.Config,
 	logger *zap.Logger,
 ) *AuthService {
 	return &AuthService{
 		userRepository:    userRepository,
 		sessionRepository: sessionRepository,
+		inviteRepository:  inviteRepository,
+		loginAttempts:     loginAttempts,
+		twoFactor:         twoFactor,
+		notifications:     notifications,
 		config:            config,
 		logger:            logger,
 	}
 }
 
-// Login выполняет авторизацию пользователя
-func (s *AuthService) Login(ctx context.Context, telegramID int64, username, firstName, lastName, password string) (*domain.User, error) {
-	// Проверяем пароль
-	if password != s.config.Auth.Password {
-		s.logger.Warn("invalid password attempt", zap.Int64("telegram_id", telegramID))
-		return nil, fmt.Errorf("неверный пароль")
+// Login выполняет авторизацию пользователя в чате chatID. secret - личный пароль, код приглашения или общий пароль:
+// пользователь с личным паролем входит только по нему, пользователь без личного пароля - по общему паролю,
+// новый пользователь регистрируется по коду приглашения (администратор из AUTH_ADMIN_IDS - и по общему паролю).
+// Частые неудачные попытки блокируют вход с domain.RateLimitError.
+// Если у пользователя включена двухфакторная аутентификация, возвращается ErrSecondFactorRequired
+// и создается неактивная сессия, которую активирует VerifySecondFactor.
+// Вход в одном чате не затрагивает сессии пользователя в других чатах.
+func (s *AuthService) Login(ctx context.Context, telegramID, chatID int64, username, firstName, lastName, secret string) (*domain.User, error) {
+	if err := s.checkLoginAllowed(ctx, telegramID); err != nil {
+		return nil, err
 	}
 
-	// Получаем или создаем пользователя
-	user, err := s.userRepository.GetByTelegramID(ctx, telegramID)
+	user, err := s.authenticate(ctx, telegramID, username, firstName, lastName, secret)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			// Создаем нового пользователя
-			user = &domain.User{
-				TelegramID: telegramID,
-				Username:   username,
-				FirstName:  firstName,
-				LastName:   lastName,
-				IsActive:   true,
-			}
+		if errors.Is(err, errInvalidCredentials) {
+			s.recordFailure(ctx, telegramID)
+		}
+		return nil, err
+	}
 
-			if err := s.userRepository.Create(ctx, user); err != nil {
-				s.logger.Error("failed to create user", zap.Error(err))
-				return nil, fmt.Errorf("не удалось создать пользователя")
-			}
+	if user.TOTPEnabled {
+		return user, s.startSecondFactor(ctx, user, chatID)
+	}
+
+	s.recordAttempt(ctx, telegramID, true)
+
+	// Создаем сессию
+	now := time.Now()
+	session := &domain.Session{
+		UserID:     user.ID,
+		TelegramID: telegramID,
+		ChatID:     chatID,
+		IsActive:   true,
+		CreatedAt:  now,
+	}
+	session.Renew(now, s.config.Auth.SessionTimeout, s.config.Auth.SessionMaxLifetime)
+
+	if err := s.sessionRepository.Create(ctx, session); err != nil {
+		s.logger.Error("failed to create session", zap.Error(err))
+		return nil, fmt.Errorf("ошибка создания сессии: %w", err)
+	}
+
+	s.logger.Info("user logged in",
+		zap.Int64("user_id", user.ID),
+		zap.Int64("telegram_id", telegramID),
+		zap.Int64("chat_id", chatID))
+	return user, nil
+}
+
+// startSecondFactor создает неактивную сессию, которая ждет код двухфакторной аутентификации
+func (s *AuthService) startSecondFactor(ctx context.Context, user *domain.User, chatID int64) error {
+	now := time.Now()
+	session := &domain.Session{
+		UserID:         user.ID,
+		TelegramID:     user.TelegramID,
+		ChatID:         chatID,
+		IsActive:       false,
+		CreatedAt:      now,
+		LastActivityAt: now,
+		ExpiresAt:      now.Add(secondFactorTimeout),
+	}
+
+	if err := s.sessionRepository.Create(ctx, session); err != nil {
+		s.logger.Error("failed to create pending session", zap.Error(err))
+		return fmt.Errorf("ошибка создания сессии: %w", err)
+	}
+
+	s.logger.Info("second factor requested", zap.Int64("user_id", user.ID))
+	return ErrSecondFactorRequired
+}
+
+// HasPendingSecondFactor сообщает, ждет ли пользователь в чате ввода кода двухфакторной аутентификации:
+// есть неактивная сессия, созданная после верного пароля, и время на ввод кода не истекло
+func (s *AuthService) HasPendingSecondFactor(ctx context.Context, telegramID, chatID int64) bool {
+	session, err := s.sessionRepository.Get(ctx, telegramID, chatID)
+	if err != nil {
+		return false
+	}
+	return !session.IsActive && !session.IsExpired()
+}
+
+// VerifySecondFactor проверяет код двухфакторной аутентификации после верного пароля и активирует сессию.
+// Неверные коды учитываются вместе с неверными паролями. Возвращает ErrNotFound для сессии,
+// если код не ожидается или время на его ввод истекло.
+func (s *AuthService) VerifySecondFactor(ctx context.Context, telegramID, chatID int64, code string) (*domain.User, error) {
+	session, err := s.sessionRepository.Get(ctx, telegramID, chatID)
+	if err != nil || session.IsActive || session.IsExpired() {
+		return nil, domain.NotFound(domain.EntitySession, 0)
+	}
+
+	if err := s.checkLoginAllowed(ctx, telegramID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.GetByID(ctx, session.UserID)
+	if err != nil {
+		s.logger.Error("failed to get user", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil, errUserBanned
+	}
+
+	if err := s.twoFactor.Verify(ctx, user, code); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			s.logger.Warn("invalid second factor attempt", zap.Int64("telegram_id", telegramID))
+			s.recordFailure(ctx, telegramID)
+		}
+		return nil, err
+	}
+
+	s.recordAttempt(ctx, telegramID, true)
+
+	now := time.Now()
+	session.IsActive = true
+	session.Renew(now, s.config.Auth.SessionTimeout, s.config.Auth.SessionMaxLifetime)
+
+	if err := s.sessionRepository.Update(ctx, session); err != nil {
+		s.logger.Error("failed to activate session", zap.Error(err))
+		return nil, fmt.Errorf("ошибка создания сессии: %w", err)
+	}
 
-			s.logger.Info("new user created", zap.Int64("telegram_id", telegramID))
-		} else {
-			s.logger.Error("failed to get user", zap.Error(err))
-			return nil, fmt.Errorf("ошибка получения пользователя")
+	s.logger.Info("user logged in with second factor", zap.Int64("user_id", user.ID), zap.Int64("telegram_id", telegramID))
+	return user, nil
+}
+
+// authenticate проверяет секрет и возвращает существующего или только что зарегистрированного пользователя
+func (s *AuthService) authenticate(ctx context.Context, telegramID int64, username, firstName, lastName, secret string) (*domain.User, error) {
+	user, err := s.userRepository.GetByTelegramID(ctx, telegramID)
+	switch {
+	case err == nil:
+		if !s.checkPassword(user, secret) {
+			s.logger.Warn("invalid password attempt", zap.Int64("telegram_id", telegramID))
+			return nil, errInvalidCredentials
+		}
+
+		if !user.IsActive {
+			s.logger.Warn("banned user login attempt", zap.Int64("telegram_id", telegramID))
+			return nil, errUserBanned
 		}
-	} else {
+
+		// Администраторы из AUTH_ADMIN_IDS получают роль при входе
+		if s.config.Auth.IsAdmin(telegramID) && user.Role != domain.UserRoleAdmin {
+			if err := s.userRepository.SetRole(ctx, user.ID, domain.UserRoleAdmin); err != nil {
+				s.logger.Error("failed to grant admin role", zap.Error(err))
+			} else {
+				user.Role = domain.UserRoleAdmin
+			}
+		}
+
 		// Обновляем информацию о пользователе
 		user.Username = username
 		user.FirstName = firstName
 		user.LastName = lastName
 		user.LastLoginAt = time.Now()
 
-		if err := s.userRepository.Update(ctx, user); err != nil {
+		if err := s.userRepository.UpdateProfile(ctx, user); err != nil {
 			s.logger.Error("failed to update user", zap.Error(err))
 		}
+
+	case errors.Is(err, domain.ErrNotFound):
+		user = &domain.User{
+			TelegramID: telegramID,
+			Username:   username,
+			FirstName:  firstName,
+			LastName:   lastName,
+			IsActive:   true,
+			Role:       domain.UserRoleUser,
+		}
+		if err := s.register(ctx, user, secret); err != nil {
+			return nil, err
+		}
+
+	default:
+		s.logger.Error("failed to get user", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
 	}
 
-	// Создаем сессию
-	session := &domain.Session{
-		UserID:     user.ID,
+	return user, nil
+}
+
+// checkLoginAllowed проверяет блокировку Telegram ID после частых ошибок.
+// Общее число ошибок вход не блокирует, иначе любой отправитель мог бы закрыть вход для всех
+// неверными паролями: о нем только предупреждаются администраторы (см. recordFailure).
+// Если проверить попытки не удалось, вход запрещается: защита важнее доступности.
+func (s *AuthService) checkLoginAllowed(ctx context.Context, telegramID int64) error {
+	auth := s.config.Auth
+	now := time.Now()
+
+	if auth.LoginMaxAttempts > 0 {
+		failures, err := s.loginAttempts.GetFailures(ctx, telegramID, now.Add(-auth.LoginLockoutMax))
+		if err != nil {
+			s.logger.Error("failed to get login failures", zap.Error(err))
+			return fmt.Errorf("ошибка проверки попыток входа: %w", err)
+		}
+
+		if failures.Count >= auth.LoginMaxAttempts {
+			until := failures.LastFailure.Add(s.lockoutDuration(failures.Count))
+			if now.Before(until) {
+				return &domain.RateLimitError{Until: until}
+			}
+		}
+	}
+
+	return nil
+}
+
+// lockoutDuration возвращает блокировку после failures ошибок подряд:
+// base после AUTH_LOGIN_MAX_ATTEMPTS ошибок, затем вдвое больше за каждую следующую, но не больше max
+func (s *AuthService) lockoutDuration(failures int) time.Duration {
+	auth := s.config.Auth
+	lockout := auth.LoginLockoutBase
+	for i := auth.LoginMaxAttempts; i < failures && lockout < auth.LoginLockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > auth.LoginLockoutMax {
+		lockout = auth.LoginLockoutMax
+	}
+	return lockout
+}
+
+// recordAttempt сохраняет попытку входа. Ошибка записи только логируется, чтобы не мешать входу
+func (s *AuthService) recordAttempt(ctx context.Context, telegramID int64, success bool) {
+	attempt := &domain.LoginAttempt{
 		TelegramID: telegramID,
-		IsActive:   true,
-		ExpiresAt:  time.Now().Add(s.config.Auth.SessionTimeout),
+		Success:    success,
+		CreatedAt:  time.Now(),
 	}
+	if err := s.loginAttempts.Create(ctx, attempt); err != nil {
+		s.logger.Error("failed to record login attempt", zap.Int64("telegram_id", telegramID), zap.Error(err))
+	}
+}
 
-	if err := s.sessionRepository.Create(ctx, session); err != nil {
-		s.logger.Error("failed to create session", zap.Error(err))
-		return nil, fmt.Errorf("ошибка создания сессии")
+// recordFailure сохраняет неудачную попытку и сообщает администраторам, если Telegram ID заблокирован
+// или ошибок от всех пользователей стало подозрительно много
+func (s *AuthService) recordFailure(ctx context.Context, telegramID int64) {
+	s.recordAttempt(ctx, telegramID, false)
+	s.checkGlobalFailures(ctx)
+
+	auth := s.config.Auth
+	if auth.LoginMaxAttempts <= 0 {
+		return
 	}
 
-	s.logger.Info("user logged in", zap.Int64("user_id", user.ID), zap.Int64("telegram_id", telegramID))
-	return user, nil
+	failures, err := s.loginAttempts.GetFailures(ctx, telegramID, time.Now().Add(-auth.LoginLockoutMax))
+	if err != nil {
+		s.logger.Error("failed to get login failures", zap.Error(err))
+		return
+	}
+
+	if failures.Count < auth.LoginMaxAttempts {
+		return
+	}
+
+	lockout := s.lockoutDuration(failures.Count)
+	s.logger.Warn("login locked out",
+		zap.Int64("telegram_id", telegramID),
+		zap.Int("failures", failures.Count),
+		zap.Duration("lockout", lockout))
+
+	s.notifications.NotifyAdmins(ctx, fmt.Sprintf(
+		"🚨 Подозрительная активность\n\n%d неудачных попыток входа подряд с Telegram ID %d.\nВход заблокирован на %s",
+		failures.Count, telegramID, lockout))
+}
+
+// checkGlobalFailures предупреждает администраторов, если за AUTH_LOGIN_GLOBAL_WINDOW
+// набралось AUTH_LOGIN_GLOBAL_LIMIT неудачных попыток от всех пользователей
+func (s *AuthService) checkGlobalFailures(ctx context.Context) {
+	auth := s.config.Auth
+	if auth.LoginGlobalLimit <= 0 {
+		return
+	}
+
+	count, err := s.loginAttempts.CountFailuresSince(ctx, time.Now().Add(-auth.LoginGlobalWindow))
+	if err != nil {
+		s.logger.Error("failed to count login failures", zap.Error(err))
+		return
+	}
+
+	if count >= auth.LoginGlobalLimit {
+		s.logger.Warn("global login failure threshold exceeded", zap.Int("failures", count))
+		s.alertGlobalLimit(ctx, count)
+	}
+}
+
+// alertGlobalLimit сообщает администраторам о превышении общего порога не чаще одного раза за окно
+func (s *AuthService) alertGlobalLimit(ctx context.Context, failures int) {
+	window := s.config.Auth.LoginGlobalWindow
+
+	s.globalAlertMu.Lock()
+	if time.Since(s.lastGlobalAlert) < window {
+		s.globalAlertMu.Unlock()
+		return
+	}
+	s.lastGlobalAlert = time.Now()
+	s.globalAlertMu.Unlock()
+
+	s.notifications.NotifyAdmins(ctx, fmt.Sprintf(
+		"🚨 Возможен подбор паролей\n\n%d неудачных попыток входа за %s с разных Telegram ID. "+
+			"Каждый из них блокируется отдельно, вход для остальных пользователей открыт",
+		failures, window))
+}
+
+// register создает нового пользователя по коду приглашения или, для администратора, по общему паролю
+func (s *AuthService) register(ctx context.Context, user *domain.User, secret string) error {
+	if s.config.Auth.IsAdmin(user.TelegramID) && s.matchesSharedPassword(secret) {
+		user.Role = domain.UserRoleAdmin
+		if err := s.userRepository.Create(ctx, user); err != nil {
+			s.logger.Error("failed to create user", zap.Error(err))
+			return fmt.Errorf("не удалось создать пользователя: %w", err)
+		}
+
+		s.logger.Info("admin registered", zap.Int64("telegram_id", user.TelegramID))
+		return nil
+	}
+
+	invite, err := s.inviteRepository.Redeem(ctx, hashCode(secret), time.Now(), user)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			s.logger.Warn("invalid invite attempt", zap.Int64("telegram_id", user.TelegramID))
+			return errInvalidCredentials
+		}
+		s.logger.Error("failed to register user by invite", zap.Error(err))
+		return fmt.Errorf("не удалось создать пользователя: %w", err)
+	}
+
+	s.logger.Info("user registered by invite",
+		zap.Int64("telegram_id", user.TelegramID),
+		zap.Int("invite_id", invite.ID),
+		zap.Int64("invited_by", invite.CreatedBy))
+	return nil
 }
 
-// IsAuthenticated проверяет, авторизован ли пользователь
-func (s *AuthService) IsAuthenticated(ctx context.Context, telegramID int64) (*domain.User, error) {
-	session, err := s.sessionRepository.GetByTelegramID(ctx, telegramID)
+// checkPassword проверяет личный пароль пользователя, а если он не установлен - общий пароль
+func (s *AuthService) checkPassword(user *domain.User, password string) bool {
+	if user.HasPassword() {
+		return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+	}
+	return s.matchesSharedPassword(password)
+}
+
+// matchesSharedPassword сравнивает пароль с AUTH_PASSWORD за постоянное время.
+// Пустой AUTH_PASSWORD отключает вход по общему паролю.
+func (s *AuthService) matchesSharedPassword(password string) bool {
+	shared := s.config.Auth.Password
+	return shared != "" && subtle.ConstantTimeCompare([]byte(password), []byte(shared)) == 1
+}
+
+// SetPassword устанавливает личный пароль пользователя.
+// После этого общий пароль для входа в аккаунт больше не действует.
+func (s *AuthService) SetPassword(ctx context.Context, userID int64, password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return domain.NewValidationError("password", fmt.Sprintf("пароль должен содержать не меньше %d символов", minPasswordLength))
+	}
+
+	if len(password) > maxPasswordBytes {
+		return domain.NewValidationError("password", "пароль слишком длинный")
+	}
+
+	if s.matchesSharedPassword(password) {
+		return domain.NewValidationError("password", "личный пароль не должен совпадать с общим")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, fmt.Errorf("сессия не найдена")
+		s.logger.Error("failed to hash password", zap.Error(err))
+		return fmt.Errorf("ошибка установки пароля: %w", err)
 	}
 
+	if err := s.userRepository.SetPasswordHash(ctx, userID, string(hash)); err != nil {
+		s.logger.Error("failed to save password", zap.Error(err))
+		return fmt.Errorf("ошибка установки пароля: %w", err)
+	}
+
+	s.logger.Info("password set", zap.Int64("user_id", userID))
+	return nil
+}
+
+// CreateInvite выдает одноразовый код приглашения от имени администратора.
+// Код возвращается один раз, в базе хранится только его хеш.
+func (s *AuthService) CreateInvite(ctx context.Context, admin *domain.User) (string, *domain.Invite, error) {
+	if !admin.IsAdmin() {
+		return "", nil, domain.Forbidden(domain.EntityInvite, 0)
+	}
+
+	code, err := generateCode(inviteCodeSize)
+	if err != nil {
+		s.logger.Error("failed to generate invite code", zap.Error(err))
+		return "", nil, fmt.Errorf("ошибка создания приглашения: %w", err)
+	}
+
+	invite := &domain.Invite{
+		CodeHash:  hashCode(code),
+		CreatedBy: admin.ID,
+		ExpiresAt: time.Now().Add(s.config.Auth.InviteTTL),
+	}
+
+	if err := s.inviteRepository.Create(ctx, invite); err != nil {
+		s.logger.Error("failed to create invite", zap.Error(err))
+		return "", nil, fmt.Errorf("ошибка создания приглашения: %w", err)
+	}
+
+	s.logger.Info("invite created", zap.Int("invite_id", invite.ID), zap.Int64("created_by", admin.ID))
+	return code, invite, nil
+}
+
+// inviteCodeSize - размер кода приглашения в байтах (16 символов base32)
+const inviteCodeSize = 10
+
+// generateCode генерирует случайный код из size байт в base32: 5 байт дают 8 символов
+func generateCode(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf), nil
+}
+
+// hashCode возвращает SHA-256 одноразового кода без учета регистра, пробелов и дефисов
+func hashCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAuthenticated проверяет, авторизован ли пользователь в чате
+func (s *AuthService) IsAuthenticated(ctx context.Context, telegramID, chatID int64) (*domain.User, error) {
+	session, err := s.sessionRepository.Get(ctx, telegramID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("сессия не найдена: %w", err)
+	}
+
+	// Истекшая или неактивированная сессия для пользователя то же, что ее отсутствие
 	if !session.IsValid() {
 		s.logger.Info("invalid session", zap.Int64("telegram_id", telegramID))
-		return nil, fmt.Errorf("сессия истекла или недействительна")
+		return nil, domain.NotFound(domain.EntitySession, telegramID)
 	}
 
 	user, err := s.userRepository.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		s.logger.Error("failed to get user", zap.Error(err))
-		return nil, fmt.Errorf("пользователь не найден")
+		return nil, fmt.Errorf("пользователь не найден: %w", err)
 	}
 
 	if !user.IsActive {
-		return nil, fmt.Errorf("пользователь неактивен")
+		return nil, domain.Forbidden(domain.EntityUser, user.ID)
+	}
+
+	// Каждое действие продлевает сессию, но не дольше AUTH_SESSION_MAX_LIFETIME с момента входа
+	if now := time.Now(); now.Sub(session.LastActivityAt) >= sessionRenewInterval {
+		session.Renew(now, s.config.Auth.SessionTimeout, s.config.Auth.SessionMaxLifetime)
+		if err := s.sessionRepository.Update(ctx, session); err != nil {
+			s.logger.Error("failed to renew session", zap.Int64("telegram_id", telegramID), zap.Error(err))
+		}
 	}
 
 	return user, nil
 }
 
-// Logout выполняет выход пользователя из системы
-func (s *AuthService) Logout(ctx context.Context, telegramID int64) error {
-	if err := s.sessionRepository.Delete(ctx, telegramID); err != nil {
+// ListSessions возвращает действующие сессии пользователя во всех чатах
+func (s *AuthService) ListSessions(ctx context.Context, userID int64) ([]*domain.Session, error) {
+	sessions, err := s.sessionRepository.ListByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to list sessions", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения сессий: %w", err)
+	}
+	return sessions, nil
+}
+
+// Logout выполняет выход пользователя из системы в чате
+func (s *AuthService) Logout(ctx context.Context, telegramID, chatID int64) error {
+	if err := s.sessionRepository.Delete(ctx, telegramID, chatID); err != nil {
 		s.logger.Error("failed to delete session", zap.Error(err))
-		return fmt.Errorf("ошибка выхода из системы")
+		return fmt.Errorf("ошибка выхода из системы: %w", err)
+	}
+
+	s.logger.Info("user logged out", zap.Int64("telegram_id", telegramID), zap.Int64("chat_id", chatID))
+	return nil
+}
+
+// RevokeSession завершает одну из сессий пользователя
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	if err := s.sessionRepository.DeleteByID(ctx, sessionID, userID); err != nil {
+		return fmt.Errorf("ошибка завершения сессии: %w", err)
 	}
 
-	s.logger.Info("user logged out", zap.Int64("telegram_id", telegramID))
+	s.logger.Info("session revoked", zap.Int64("user_id", userID), zap.Int64("session_id", sessionID))
 	return nil
 }
 
+// LogoutEverywhere завершает сессии пользователя во всех чатах и возвращает их количество
+func (s *AuthService) LogoutEverywhere(ctx context.Context, userID int64) (int64, error) {
+	count, err := s.sessionRepository.DeleteByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to delete sessions", zap.Error(err))
+		return 0, fmt.Errorf("ошибка выхода из системы: %w", err)
+	}
+
+	s.logger.Info("user logged out everywhere", zap.Int64("user_id", userID), zap.Int64("sessions", count))
+	return count, nil
+}
+
 // CleanupExpiredSessions удаляет истекшие сессии
 func (s *AuthService) CleanupExpiredSessions(ctx context.Context) error {
 	if err := s.sessionRepository.CleanupExpired(ctx); err != nil {
@@ -141,3 +599,13 @@ func (s *AuthService) CleanupExpiredSessions(ctx context.Context) error {
 	s.logger.Info("expired sessions cleaned up")
 	return nil
 }
+
+// CleanupLoginAttempts удаляет записи о попытках входа старше 30 дней
+func (s *AuthService) CleanupLoginAttempts(ctx context.Context) error {
+	if err := s.loginAttempts.DeleteOlderThan(ctx, time.Now().Add(-loginAttemptsRetention)); err != nil {
+		s.logger.Error("failed to cleanup login attempts", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -80,9 +80,10 @@ func (s *NoteService) CreateNoteFromFile(ctx context.Context, userID int64, titl
 	return note, nil
 }
 
-// GetNote получает заметку по ID
-func (s *NoteService) GetNote(ctx context.Context, id int) (*domain.Note, error) {
-	note, err := s.noteRepo.GetByID(ctx, id)
+// GetNote получает заметку пользователя по ID.
+// Возвращает domain.ErrNotFound или domain.ErrForbidden, если заметка недоступна пользователю.
+func (s *NoteService) GetNote(ctx context.Context, id int, userID int64) (*domain.Note, error) {
+	note, err := s.noteRepo.GetByID(ctx, id, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
@@ -90,6 +91,21 @@ func (s *NoteService) GetNote(ctx context.Context, id int) (*domain.Note, error)
 	return note, nil
 }
 
+// GetAttachment получает заметку пользователя с файлом для отправки вложения.
+// Доступ проверяется так же, как в GetNote.
+func (s *NoteService) GetAttachment(ctx context.Context, id int, userID int64) (*domain.Note, error) {
+	note, err := s.noteRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+
+	if !note.IsFile() || note.FileID == "" {
+		return nil, domain.NewValidationError("file", "к заметке не прикреплен файл")
+	}
+
+	return note, nil
+}
+
 // GetUserNotes получает все заметки пользователя
 func (s *NoteService) GetUserNotes(ctx context.Context, userID int64) ([]*domain.Note, error) {
 	notes, err := s.noteRepo.GetByUserID(ctx, userID)
@@ -133,7 +149,7 @@ func (s *NoteService) GetFavoriteNotes(ctx context.Context, userID int64) ([]*do
 // SearchNotes выполняет поиск заметок
 func (s *NoteService) SearchNotes(ctx context.Context, userID int64, query string) ([]*domain.Note, error) {
 	if query == "" {
-		return nil, fmt.Errorf("search query cannot be empty")
+		return nil, domain.NewValidationError("query", "поисковый запрос не может быть пустым")
 	}
 
 	notes, err := s.noteRepo.Search(ctx, userID, query)
@@ -144,8 +160,8 @@ func (s *NoteService) SearchNotes(ctx context.Context, userID int64, query strin
 	return notes, nil
 }
 
-// UpdateNote обновляет заметку
-func (s *NoteService) UpdateNote(ctx context.Context, note *domain.Note) error {
+// UpdateNote обновляет заметку пользователя
+func (s *NoteService) UpdateNote(ctx context.Context, note *domain.Note, userID int64) error {
 	// Обновляем время изменения
 	note.UpdatedAt = time.Now()
 
@@ -157,7 +173,7 @@ func (s *NoteService) UpdateNote(ctx context.Context, note *domain.Note) error {
 		}
 	}
 
-	err := s.noteRepo.Update(ctx, note)
+	err := s.noteRepo.Update(ctx, note, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update note: %w", err)
 	}
@@ -165,16 +181,16 @@ func (s *NoteService) UpdateNote(ctx context.Context, note *domain.Note) error {
 	return nil
 }
 
-// ToggleFavorite переключает статус избранного для заметки
-func (s *NoteService) ToggleFavorite(ctx context.Context, noteID int) (*domain.Note, error) {
-	note, err := s.noteRepo.GetByID(ctx, noteID)
+// ToggleFavorite переключает статус избранного для заметки пользователя
+func (s *NoteService) ToggleFavorite(ctx context.Context, noteID int, userID int64) (*domain.Note, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
 
 	note.ToggleFavorite()
 
-	err = s.noteRepo.Update(ctx, note)
+	err = s.noteRepo.Update(ctx, note, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to toggle favorite: %w", err)
 	}
@@ -182,9 +198,9 @@ func (s *NoteService) ToggleFavorite(ctx context.Context, noteID int) (*domain.N
 	return note, nil
 }
 
-// DeleteNote удаляет заметку
-func (s *NoteService) DeleteNote(ctx context.Context, id int) error {
-	err := s.noteRepo.Delete(ctx, id)
+// DeleteNote удаляет заметку пользователя
+func (s *NoteService) DeleteNote(ctx context.Context, id int, userID int64) error {
+	err := s.noteRepo.Delete(ctx, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete note: %w", err)
 	}
@@ -227,8 +243,8 @@ func (s *NoteService) extractURL(content string) string {
 	return ""
 }
 
-// FormatNoteForDisplay форматирует заметку для отображения
-func (s *NoteService) FormatNoteForDisplay(note *domain.Note) string {
+// FormatNoteForDisplay форматирует заметку для отображения в часовом поясе loc
+func (s *NoteService) FormatNoteForDisplay(note *domain.Note, loc *time.Location) string {
 	var builder strings.Builder
 
 	// Заголовок с иконкой типа
@@ -273,7 +289,7 @@ func (s *NoteService) FormatNoteForDisplay(note *domain.Note) string {
 	}
 
 	// Дата создания
-	builder.WriteString(fmt.Sprintf("📅 %s", note.CreatedAt.Format("02.01.2006 15:04")))
+	builder.WriteString(fmt.Sprintf("📅 %s", note.CreatedAt.In(loc).Format("02.01.2006 15:04")))
 
 	return builder.String()
 }
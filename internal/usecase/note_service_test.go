@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"todolist/internal/domain"
+)
+
+// fakeNoteRepository хранит заметки в памяти и проверяет владельца так же, как репозиторий PostgreSQL
+type fakeNoteRepository struct {
+	notes  map[int]*domain.Note
+	nextID int
+}
+
+func newFakeNoteRepository() *fakeNoteRepository {
+	return &fakeNoteRepository{notes: make(map[int]*domain.Note)}
+}
+
+func (r *fakeNoteRepository) Create(ctx context.Context, note *domain.Note) error {
+	r.nextID++
+	note.ID = r.nextID
+	stored := *note
+	r.notes[note.ID] = &stored
+	return nil
+}
+
+func (r *fakeNoteRepository) GetByID(ctx context.Context, id int, userID int64) (*domain.Note, error) {
+	note, ok := r.notes[id]
+	if !ok {
+		return nil, domain.NotFound(domain.EntityNote, int64(id))
+	}
+	if note.UserID != userID {
+		return nil, domain.Forbidden(domain.EntityNote, int64(id))
+	}
+	copied := *note
+	return &copied, nil
+}
+
+func (r *fakeNoteRepository) filter(userID int64, match func(*domain.Note) bool) []*domain.Note {
+	var notes []*domain.Note
+	for _, note := range r.notes {
+		if note.UserID == userID && match(note) {
+			copied := *note
+			notes = append(notes, &copied)
+		}
+	}
+	return notes
+}
+
+func (r *fakeNoteRepository) GetByUserID(ctx context.Context, userID int64) ([]*domain.Note, error) {
+	return r.filter(userID, func(*domain.Note) bool { return true }), nil
+}
+
+func (r *fakeNoteRepository) GetByCategory(ctx context.Context, userID int64, category domain.NoteCategory) ([]*domain.Note, error) {
+	return r.filter(userID, func(n *domain.Note) bool { return n.Category == category }), nil
+}
+
+func (r *fakeNoteRepository) GetByType(ctx context.Context, userID int64, noteType domain.NoteType) ([]*domain.Note, error) {
+	return r.filter(userID, func(n *domain.Note) bool { return n.Type == noteType }), nil
+}
+
+func (r *fakeNoteRepository) GetFavorites(ctx context.Context, userID int64) ([]*domain.Note, error) {
+	return r.filter(userID, func(n *domain.Note) bool { return n.IsFavorite }), nil
+}
+
+func (r *fakeNoteRepository) Search(ctx context.Context, userID int64, query string) ([]*domain.Note, error) {
+	return r.filter(userID, func(n *domain.Note) bool { return n.Title == query }), nil
+}
+
+func (r *fakeNoteRepository) Update(ctx context.Context, note *domain.Note, userID int64) error {
+	if _, err := r.GetByID(ctx, note.ID, userID); err != nil {
+		return err
+	}
+	stored := *note
+	stored.UserID = userID
+	r.notes[note.ID] = &stored
+	return nil
+}
+
+func (r *fakeNoteRepository) Delete(ctx context.Context, id int, userID int64) error {
+	if _, err := r.GetByID(ctx, id, userID); err != nil {
+		return err
+	}
+	delete(r.notes, id)
+	return nil
+}
+
+// TestNoteServiceCrossUserAccess проверяет, что пользователь B получает ErrForbidden
+// при любой попытке обратиться к заметке пользователя A, а заметка A не меняется
+func TestNoteServiceCrossUserAccess(t *testing.T) {
+	const (
+		userA int64 = 1
+		userB int64 = 2
+	)
+
+	ctx := context.Background()
+	repo := newFakeNoteRepository()
+	service := NewNoteService(repo)
+
+	note, err := service.CreateNoteFromFile(ctx, userA, "Договор", "file-id", "contract.pdf", 1024, domain.NoteTypeDocument, "", "")
+	if err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+
+	attempts := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := service.GetNote(ctx, note.ID, userB)
+			return err
+		}},
+		{"update", func() error {
+			return service.UpdateNote(ctx, &domain.Note{ID: note.ID, Title: "взлом", Content: "взлом"}, userB)
+		}},
+		{"delete", func() error {
+			return service.DeleteNote(ctx, note.ID, userB)
+		}},
+		{"favorite add", func() error {
+			_, err := service.ToggleFavorite(ctx, note.ID, userB)
+			return err
+		}},
+		{"favorite remove", func() error {
+			repo.notes[note.ID].IsFavorite = true
+			defer func() { repo.notes[note.ID].IsFavorite = false }()
+			_, err := service.ToggleFavorite(ctx, note.ID, userB)
+			return err
+		}},
+		{"attachment download", func() error {
+			_, err := service.GetAttachment(ctx, note.ID, userB)
+			return err
+		}},
+	}
+
+	for _, attempt := range attempts {
+		t.Run(attempt.name, func(t *testing.T) {
+			err := attempt.call()
+			if !errors.Is(err, domain.ErrForbidden) {
+				t.Fatalf("error = %v, want ErrForbidden", err)
+			}
+			if errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("error = %v must not be ErrNotFound", err)
+			}
+
+			stored, err := service.GetNote(ctx, note.ID, userA)
+			if err != nil {
+				t.Fatalf("owner lost access: %v", err)
+			}
+			if stored.Title != "Договор" || stored.IsFavorite {
+				t.Fatalf("note changed by another user: %+v", stored)
+			}
+		})
+	}
+
+	// Чужие заметки не попадают в списки
+	if notes, _ := service.GetUserNotes(ctx, userB); len(notes) != 0 {
+		t.Errorf("user B sees %d notes of user A", len(notes))
+	}
+}
+
+// TestNoteServiceMissingNote проверяет, что для несуществующей заметки возвращается ErrNotFound
+func TestNoteServiceMissingNote(t *testing.T) {
+	ctx := context.Background()
+	service := NewNoteService(newFakeNoteRepository())
+
+	if _, err := service.GetNote(ctx, 42, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetNote error = %v, want ErrNotFound", err)
+	}
+	if err := service.DeleteNote(ctx, 42, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("DeleteNote error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestNoteServiceAttachmentOwner проверяет, что владелец получает файл, а у текстовой заметки файла нет
+func TestNoteServiceAttachmentOwner(t *testing.T) {
+	ctx := context.Background()
+	service := NewNoteService(newFakeNoteRepository())
+
+	file, _ := service.CreateNoteFromFile(ctx, 1, "Фото", "photo-id", "photo.jpg", 10, domain.NoteTypeImage, "", "")
+	text, _ := service.CreateNote(ctx, 1, "Текст", "просто текст", "", "")
+
+	note, err := service.GetAttachment(ctx, file.ID, 1)
+	if err != nil || note.FileID != "photo-id" {
+		t.Fatalf("GetAttachment = %+v, %v", note, err)
+	}
+
+	if _, err := service.GetAttachment(ctx, text.ID, 1); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("GetAttachment for text note error = %v, want ErrValidation", err)
+	}
+}
@@ -2,65 +2,229 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 
+	"todolist/config"
+	"todolist/internal/callbackdata"
 	"todolist/internal/domain"
+	"todolist/internal/sender"
 )
 
+const (
+	// outboxBatchSize - сколько уведомлений обработчик забирает из очереди за один проход
+	outboxBatchSize = 20
+	// outboxLockTimeout - после этого времени отправка считается прерванной
+	outboxLockTimeout = 5 * time.Minute
+)
+
+// errTaskInactive возвращается, если задача уведомления выполнена или удалена до отправки
+var errTaskInactive = errors.New("task is no longer pending")
+
 // NotificationService предоставляет методы для отправки уведомлений
 type NotificationService struct {
-	bot         *tgbotapi.BotAPI
-	taskService *TaskService
-	logger      *zap.Logger
+	sender           *sender.Sender
+	callbacks        *callbackdata.Codec
+	taskService      *TaskService
+	outboxRepository domain.NotificationOutboxRepository
+	userRepository   domain.UserRepository
+	config           *config.Config
+	logger           *zap.Logger
 }
 
 // NewNotificationService создает новый экземпляр NotificationService
-func NewNotificationService(bot *tgbotapi.BotAPI, taskService *TaskService, logger *zap.Logger) *NotificationService {
+func NewNotificationService(
+	sender *sender.Sender,
+	taskService *TaskService,
+	outboxRepository domain.NotificationOutboxRepository,
+	userRepository domain.UserRepository,
+	config *config.Config,
+	logger *zap.Logger,
+) *NotificationService {
 	return &NotificationService{
-		bot:         bot,
-		taskService: taskService,
-		logger:      logger,
+		sender:           sender,
+		callbacks:        callbackdata.NewCodec(config.Bot.CallbackSecret),
+		taskService:      taskService,
+		outboxRepository: outboxRepository,
+		userRepository:   userRepository,
+		config:           config,
+		logger:           logger,
 	}
 }
 
-// SendTaskNotifications отправляет уведомления о задачах
-func (s *NotificationService) SendTaskNotifications(ctx context.Context) error {
-	tasks, err := s.taskService.GetTasksForNotification(ctx)
+// EnqueueDueReminders ставит наступившие напоминания в очередь отправки.
+// Само сообщение отправляет DeliverPending.
+func (s *NotificationService) EnqueueDueReminders(ctx context.Context) error {
+	count, err := s.outboxRepository.EnqueueDueReminders(ctx, time.Now())
 	if err != nil {
-		s.logger.Error("failed to get notification tasks", zap.Error(err))
+		s.logger.Error("failed to enqueue reminders", zap.Error(err))
 		return err
 	}
 
-	for _, task := range tasks {
-		if err := s.sendTaskNotification(task); err != nil {
-			s.logger.Error("failed to send notification",
-				zap.Int("task_id", task.ID),
-				zap.Int64("user_id", task.UserID),
-				zap.Error(err))
-			continue
+	if count > 0 {
+		s.logger.Info("reminders enqueued", zap.Int64("count", count))
+	}
+
+	return nil
+}
+
+// DeliverPending отправляет уведомления из очереди и возвращает количество доставленных.
+// Неудачные попытки повторяются с экспоненциальной задержкой, после NOTIFY_MAX_ATTEMPTS
+// попыток уведомление помечается недоставленным (dead). Sender повторяет запрос после ответа 429;
+// если лимит не снят и после повторов, оставшиеся уведомления откладываются на retry_after.
+func (s *NotificationService) DeliverPending(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	stale, err := s.outboxRepository.FailStale(ctx, now.Add(-outboxLockTimeout))
+	if err != nil {
+		s.logger.Error("failed to fail stale notifications", zap.Error(err))
+	} else if stale > 0 {
+		s.logger.Warn("interrupted notifications marked as dead", zap.Int64("count", stale))
+	}
+
+	messages, err := s.outboxRepository.Claim(ctx, now, outboxBatchSize)
+	if err != nil {
+		s.logger.Error("failed to claim notifications", zap.Error(err))
+		return 0, err
+	}
+
+	delivered := 0
+	var pausedUntil time.Time
+
+	for _, message := range messages {
+		if !pausedUntil.IsZero() {
+			// Telegram просит подождать - не тратим попытку на оставшиеся уведомления
+			message.Attempts--
+			message.Retry(pausedUntil, "rate limited")
+		} else {
+			err := s.deliver(ctx, message)
+			if err == nil {
+				delivered++
+			}
+			pausedUntil = s.applyDeliveryResult(message, err)
 		}
 
-		// Очищаем время уведомления, чтобы не отправлять повторно
-		task.NotifyAt = nil
-		if err := s.taskService.taskRepository.Update(ctx, task); err != nil {
-			s.logger.Error("failed to clear notification time",
-				zap.Int("task_id", task.ID),
+		// Результат сохраняется и при остановке, иначе уведомление останется заблокированным
+		if err := s.outboxRepository.Update(context.WithoutCancel(ctx), message); err != nil {
+			s.logger.Error("failed to update notification",
+				zap.Int64("notification_id", message.ID),
 				zap.Error(err))
 		}
 	}
 
-	if len(tasks) > 0 {
-		s.logger.Info("notifications sent", zap.Int("count", len(tasks)))
+	if delivered > 0 {
+		s.logger.Info("notifications sent", zap.Int("count", delivered))
 	}
 
-	return nil
+	return delivered, nil
+}
+
+// deliver отправляет одно уведомление из очереди
+func (s *NotificationService) deliver(ctx context.Context, message *domain.OutboxMessage) error {
+	task, err := s.taskService.taskRepository.GetByID(ctx, message.TaskID)
+	if err != nil {
+		// Удаленная задача отменяет уведомление, остальные ошибки базы повторяются как сбой отправки
+		if errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("%w: %v", errTaskInactive, err)
+		}
+		return fmt.Errorf("failed to get notification task: %w", err)
+	}
+
+	if task.Status != domain.TaskStatusPending {
+		return errTaskInactive
+	}
+
+	// Задачи хранят внутренний ID пользователя, а сообщение нужно отправить в его чат
+	user, err := s.userRepository.GetByID(ctx, task.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+
+	return s.sendTaskNotification(ctx, user, task)
+}
+
+// applyDeliveryResult переводит уведомление в следующий статус по результату отправки.
+// Возвращает время, до которого нужно приостановить отправку, если Telegram ограничил частоту.
+func (s *NotificationService) applyDeliveryResult(message *domain.OutboxMessage, err error) time.Time {
+	fields := []zap.Field{
+		zap.Int64("notification_id", message.ID),
+		zap.Int("task_id", message.TaskID),
+		zap.Int("attempt", message.Attempts),
+		zap.Error(err),
+	}
+
+	if err == nil {
+		message.MarkSent()
+		return time.Time{}
+	}
+
+	if errors.Is(err, errTaskInactive) {
+		message.Fail(domain.OutboxStatusCancelled, err.Error())
+		return time.Time{}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// Отправка прервана остановкой приложения, а не ошибкой Telegram - попытку не засчитываем
+		message.Attempts--
+		message.Retry(time.Now(), err.Error())
+		return time.Time{}
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests:
+			retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+			if retryAfter <= 0 {
+				retryAfter = time.Second
+			}
+			retryAt := time.Now().Add(retryAfter)
+
+			message.Attempts--
+			message.Retry(retryAt, err.Error())
+			s.logger.Warn("telegram rate limit, delivery paused", append(fields, zap.Duration("retry_after", retryAfter))...)
+			return retryAt
+
+		case http.StatusBadRequest, http.StatusForbidden:
+			// Чат не найден или бот заблокирован - повтор не поможет
+			message.Fail(domain.OutboxStatusDead, err.Error())
+			s.logger.Error("notification rejected by telegram", fields...)
+			return time.Time{}
+		}
+	}
+
+	if message.Attempts >= s.config.Notify.MaxAttempts {
+		message.Fail(domain.OutboxStatusDead, err.Error())
+		s.logger.Error("notification dead after max attempts", fields...)
+		return time.Time{}
+	}
+
+	message.Retry(time.Now().Add(s.retryDelay(message.Attempts)), err.Error())
+	s.logger.Warn("notification delivery failed, will retry", fields...)
+	return time.Time{}
+}
+
+// retryDelay возвращает задержку перед следующей попыткой: base * 2^(attempts-1), но не больше max
+func (s *NotificationService) retryDelay(attempts int) time.Duration {
+	delay := s.config.Notify.RetryBaseDelay
+	for i := 1; i < attempts && delay < s.config.Notify.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.Notify.RetryMaxDelay {
+		delay = s.config.Notify.RetryMaxDelay
+	}
+	return delay
 }
 
-// sendTaskNotification отправляет уведомление о конкретной задаче
-func (s *NotificationService) sendTaskNotification(task *domain.Task) error {
+// sendTaskNotification отправляет уведомление о конкретной задаче в часовом поясе пользователя
+func (s *NotificationService) sendTaskNotification(ctx context.Context, user *domain.User, task *domain.Task) error {
+	loc := user.Location(s.config.Bot.Location())
+
 	message := fmt.Sprintf("⏰ Напоминание о задаче!\n\n")
 	message += fmt.Sprintf("📌 %s\n", task.Title)
 
@@ -68,35 +232,50 @@ func (s *NotificationService) sendTaskNotification(task *domain.Task) error {
 		message += fmt.Sprintf("💬 %s\n", task.Description)
 	}
 
+	if task.DueAt != nil {
+		message += fmt.Sprintf("📅 Срок: %s\n", task.DueAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
 	message += fmt.Sprintf("\n🆔 Задача [%d]", task.ID)
 
-	msg := tgbotapi.NewMessage(task.UserID, message)
+	msg := tgbotapi.NewMessage(user.TelegramID, message)
 
-	// Добавляем inline клавиатуру для быстрых действий
+	// Добавляем inline клавиатуру для быстрых действий и откладывания напоминания
 	keyboard := tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.InlineKeyboardButton{
-					Text:         "✅ Выполнить",
-					CallbackData: &[]string{fmt.Sprintf("complete_%d", task.ID)}[0],
-				},
-				tgbotapi.InlineKeyboardButton{
-					Text:         "📋 Подробнее",
-					CallbackData: &[]string{fmt.Sprintf("show_%d", task.ID)}[0],
-				},
+				s.button("✅ Выполнить", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionComplete, task.ID)),
+				s.button("📋 Подробнее", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionShow, task.ID)),
+			},
+			{
+				s.button("💤 10 мин", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionSnooze, task.ID, "10m")),
+				s.button("💤 1 час", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionSnooze, task.ID, "1h")),
+			},
+			{
+				s.button("🌅 Завтра утром", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionSnooze, task.ID, "tm")),
+				s.button("✏️ Другое время", callbackdata.New(callbackdata.EntityTask, callbackdata.ActionSnooze, task.ID, "custom")),
 			},
 		},
 	}
 	msg.ReplyMarkup = keyboard
 
-	_, err := s.bot.Send(msg)
+	_, err := s.sender.Send(ctx, msg, sender.PriorityBulk)
 	return err
 }
 
-// SendMessage отправляет сообщение пользователю
-func (s *NotificationService) SendMessage(userID int64, text string) error {
+// button создает inline-кнопку с закодированными данными
+func (s *NotificationService) button(text string, data callbackdata.Data) tgbotapi.InlineKeyboardButton {
+	encoded, err := s.callbacks.EncodeOrMenu(data)
+	if err != nil {
+		s.logger.Error("failed to encode callback data", zap.String("button", data.Key()), zap.Error(err))
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, encoded)
+}
+
+// SendMessage отправляет сообщение пользователю с приоритетом массовой рассылки
+func (s *NotificationService) SendMessage(ctx context.Context, userID int64, text string) error {
 	msg := tgbotapi.NewMessage(userID, text)
-	_, err := s.bot.Send(msg)
+	_, err := s.sender.Send(ctx, msg, sender.PriorityBulk)
 	if err != nil {
 		s.logger.Error("failed to send message",
 			zap.Int64("user_id", userID),
@@ -104,3 +283,18 @@ func (s *NotificationService) SendMessage(userID int64, text string) error {
 	}
 	return err
 }
+
+// NotifyAdmins отправляет сообщение всем активным администраторам
+func (s *NotificationService) NotifyAdmins(ctx context.Context, text string) {
+	users, err := s.userRepository.List(ctx)
+	if err != nil {
+		s.logger.Error("failed to list admins", zap.Error(err))
+		return
+	}
+
+	for _, user := range users {
+		if user.IsAdmin() && user.IsActive {
+			_ = s.SendMessage(ctx, user.TelegramID, text)
+		}
+	}
+}
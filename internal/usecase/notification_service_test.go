@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todolist/config"
+	"todolist/internal/domain"
+)
+
+// stubTaskRepository возвращает заданную ошибку из GetByID. Остальные методы не нужны
+// обработчику очереди и паникуют через встроенный nil-интерфейс.
+type stubTaskRepository struct {
+	domain.TaskRepository
+	err error
+}
+
+func (r *stubTaskRepository) GetByID(ctx context.Context, id int) (*domain.Task, error) {
+	return nil, r.err
+}
+
+// fakeOutboxRepository выдает одно уведомление и запоминает его сохраненное состояние
+type fakeOutboxRepository struct {
+	message *domain.OutboxMessage
+	saved   *domain.OutboxMessage
+}
+
+func (r *fakeOutboxRepository) EnqueueDueReminders(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOutboxRepository) Claim(ctx context.Context, now time.Time, limit int) ([]*domain.OutboxMessage, error) {
+	return []*domain.OutboxMessage{r.message}, nil
+}
+
+func (r *fakeOutboxRepository) Update(ctx context.Context, message *domain.OutboxMessage) error {
+	saved := *message
+	r.saved = &saved
+	return nil
+}
+
+func (r *fakeOutboxRepository) FailStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestDeliverPendingTaskLookupError(t *testing.T) {
+	cfg := &config.Config{Notify: config.NotifyConfig{
+		MaxAttempts: 5, RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour,
+	}}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus domain.OutboxStatus
+	}{
+		{name: "задача удалена", err: domain.NotFound(domain.EntityTask, 7), wantStatus: domain.OutboxStatusCancelled},
+		{name: "сбой базы", err: errors.New("connection reset by peer"), wantStatus: domain.OutboxStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &fakeOutboxRepository{message: &domain.OutboxMessage{
+				ID: 1, TaskID: 7, Status: domain.OutboxStatusSending, Attempts: 1,
+			}}
+			taskService := NewTaskService(&stubTaskRepository{err: tt.err}, nil, nil, cfg, zap.NewNop())
+			service := NewNotificationService(nil, taskService, outbox, nil, cfg, zap.NewNop())
+
+			delivered, err := service.DeliverPending(context.Background())
+			if err != nil || delivered != 0 {
+				t.Fatalf("DeliverPending = %d, %v, want 0, nil", delivered, err)
+			}
+			if outbox.saved == nil {
+				t.Fatal("notification was not saved")
+			}
+			if outbox.saved.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s (%s)", outbox.saved.Status, tt.wantStatus, outbox.saved.LastError)
+			}
+			if tt.wantStatus == domain.OutboxStatusPending && !outbox.saved.NextAttemptAt.After(time.Now()) {
+				t.Fatalf("retry is not delayed: next attempt at %v", outbox.saved.NextAttemptAt)
+			}
+		})
+	}
+}
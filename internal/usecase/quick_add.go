@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"todolist/internal/domain"
+	"todolist/internal/timeparse"
+)
+
+// QuickAdd содержит задачу, разобранную из одной строки быстрого добавления
+type QuickAdd struct {
+	Title    string
+	Priority domain.TaskPriority
+	Tags     []string
+	DueAt    *time.Time
+	Estimate time.Duration
+}
+
+// quickAddPriorities - значения маркера приоритета "!high", "!важно", "!3"
+var quickAddPriorities = map[string]domain.TaskPriority{
+	"high": domain.TaskPriorityHigh, "h": domain.TaskPriorityHigh, "1": domain.TaskPriorityHigh,
+	"высокий": domain.TaskPriorityHigh, "важно": domain.TaskPriorityHigh, "срочно": domain.TaskPriorityHigh,
+	"medium": domain.TaskPriorityMedium, "med": domain.TaskPriorityMedium, "m": domain.TaskPriorityMedium,
+	"2": domain.TaskPriorityMedium, "средний": domain.TaskPriorityMedium,
+	"low": domain.TaskPriorityLow, "l": domain.TaskPriorityLow, "3": domain.TaskPriorityLow,
+	"низкий": domain.TaskPriorityLow,
+}
+
+var (
+	quickAddTagRegex = regexp.MustCompile(`^#[\p{L}\p{N}_-]+$`)
+	estimateUnits    = strings.NewReplacer("часа", "h", "час", "h", "ч", "h", "минут", "m", "мин", "m", "м", "m")
+)
+
+// maxEstimate - максимальная оценка трудозатрат задачи
+const maxEstimate = 1000 * time.Hour
+
+// ParseQuickAdd разбирает строку вида "Позвонить в банк !high #finance @завтра 10:00 ~30m".
+//
+// Маркеры:
+//   - !приоритет - !high, !medium, !low (или !1, !2, !3, !важно);
+//   - #тег - любое количество тегов;
+//   - @время - срок выполнения на естественном языке (см. пакет timeparse),
+//     выражение продолжается до следующего маркера или до слов, которые не удалось разобрать;
+//   - ~оценка - оценка трудозатрат: ~30m, ~1h30m, ~2ч, ~45 (минуты).
+//
+// Остальные слова составляют название задачи. Маркеры, которые не удалось разобрать
+// ("!срочно!", "~5km", "@username"), тоже остаются в названии: обычный текст
+// без маркеров всегда становится задачей.
+func ParseQuickAdd(input string, now time.Time) (*QuickAdd, error) {
+	result := &QuickAdd{Priority: domain.TaskPriorityMedium}
+
+	tokens := strings.Fields(input)
+	var title []string
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		switch {
+		case isPriorityMarker(tok):
+			result.Priority = quickAddPriorities[strings.ToLower(tok[1:])]
+
+		case quickAddTagRegex.MatchString(tok):
+			result.Tags = append(result.Tags, strings.ToLower(tok[1:]))
+
+		case isEstimateMarker(tok):
+			result.Estimate, _ = parseEstimate(tok[1:])
+
+		case strings.HasPrefix(tok, "@"):
+			// Выражение времени - все слова до следующего маркера
+			words := []string{strings.TrimPrefix(tok, "@")}
+			if words[0] == "" {
+				words = words[:0]
+			}
+			j := i + 1
+			for ; j < len(tokens) && !isQuickAddMarker(tokens[j]); j++ {
+				words = append(words, tokens[j])
+			}
+
+			dueAt, used := parseLongestTime(words, now)
+			if used == 0 {
+				// Не время (например, упоминание @username) - оставляем в названии
+				title = append(title, tok)
+				continue
+			}
+			if result.DueAt != nil {
+				return nil, domain.NewValidationError("due_at", "срок указан несколько раз")
+			}
+			result.DueAt = &dueAt
+
+			// Слова после выражения времени возвращаются в название
+			skipped := len(words) - used
+			i = j - 1 - skipped
+
+		default:
+			title = append(title, tok)
+		}
+	}
+
+	result.Title = strings.Join(title, " ")
+	if result.Title == "" {
+		return nil, domain.NewValidationError("title", "название задачи не может быть пустым")
+	}
+
+	return result, nil
+}
+
+// parseLongestTime разбирает самое длинное начало слов, являющееся выражением времени,
+// и возвращает количество использованных слов
+func parseLongestTime(words []string, now time.Time) (time.Time, int) {
+	for n := len(words); n > 0; n-- {
+		if at, err := timeparse.Parse(strings.Join(words[:n], " "), now); err == nil {
+			return at, n
+		}
+	}
+	return time.Time{}, 0
+}
+
+// parseEstimate разбирает оценку трудозатрат: "30m", "1h30m", "1.5h", "2ч", "45"
+func parseEstimate(value string) (time.Duration, error) {
+	value = strings.ToLower(value)
+
+	var estimate time.Duration
+	if minutes, err := strconv.Atoi(value); err == nil {
+		estimate = time.Duration(minutes) * time.Minute
+	} else {
+		parsed, err := time.ParseDuration(estimateUnits.Replace(value))
+		if err != nil {
+			return 0, domain.NewValidationError("estimate", fmt.Sprintf("неверная оценка времени %q, пример: ~30m или ~1h30m", "~"+value))
+		}
+		estimate = parsed
+	}
+
+	if estimate < time.Minute || estimate > maxEstimate {
+		return 0, domain.NewValidationError("estimate", fmt.Sprintf("оценка времени должна быть от 1 минуты до %d часов", int(maxEstimate.Hours())))
+	}
+
+	return estimate.Round(time.Minute), nil
+}
+
+// isPriorityMarker проверяет, является ли слово маркером известного приоритета
+func isPriorityMarker(tok string) bool {
+	if !strings.HasPrefix(tok, "!") {
+		return false
+	}
+	_, ok := quickAddPriorities[strings.ToLower(tok[1:])]
+	return ok
+}
+
+// isEstimateMarker проверяет, является ли слово верной оценкой трудозатрат
+func isEstimateMarker(tok string) bool {
+	if !strings.HasPrefix(tok, "~") || len(tok) == 1 {
+		return false
+	}
+	_, err := parseEstimate(tok[1:])
+	return err == nil
+}
+
+// isQuickAddMarker проверяет, начинается ли со слова новый маркер
+func isQuickAddMarker(tok string) bool {
+	return isPriorityMarker(tok) ||
+		quickAddTagRegex.MatchString(tok) ||
+		isEstimateMarker(tok) ||
+		strings.HasPrefix(tok, "@")
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"todolist/internal/domain"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// Среда, 11 июня 2025, 14:30 UTC
+	now := time.Date(2025, time.June, 11, 14, 30, 0, 0, time.UTC)
+	tomorrow10 := time.Date(2025, time.June, 12, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  QuickAdd
+	}{
+		{
+			name:  "все маркеры",
+			input: "Позвонить в банк !high #finance @завтра 10:00 ~30m",
+			want: QuickAdd{
+				Title: "Позвонить в банк", Priority: domain.TaskPriorityHigh,
+				Tags: []string{"finance"}, DueAt: &tomorrow10, Estimate: 30 * time.Minute,
+			},
+		},
+		{
+			name:  "обычный текст",
+			input: "купить молоко",
+			want:  QuickAdd{Title: "купить молоко", Priority: domain.TaskPriorityMedium},
+		},
+		{
+			name:  "известный приоритет и неизвестное слово с !",
+			input: "купить молоко !срочно !купон",
+			want:  QuickAdd{Title: "купить молоко !купон", Priority: domain.TaskPriorityHigh},
+		},
+		{
+			name:  "неизвестный приоритет остается в названии",
+			input: "ура !!! праздник",
+			want:  QuickAdd{Title: "ура !!! праздник", Priority: domain.TaskPriorityMedium},
+		},
+		{
+			name:  "~ не оценка",
+			input: "пробежка ~5km",
+			want:  QuickAdd{Title: "пробежка ~5km", Priority: domain.TaskPriorityMedium},
+		},
+		{
+			name:  "слишком большая оценка остается в названии",
+			input: "проект ~99999h",
+			want:  QuickAdd{Title: "проект ~99999h", Priority: domain.TaskPriorityMedium},
+		},
+		{
+			name:  "упоминание не время",
+			input: "написать @ivan про отчет",
+			want:  QuickAdd{Title: "написать @ivan про отчет", Priority: domain.TaskPriorityMedium},
+		},
+		{
+			name:  "слова после времени возвращаются в название",
+			input: "встреча @завтра 10:00 взять ноутбук",
+			want:  QuickAdd{Title: "встреча взять ноутбук", Priority: domain.TaskPriorityMedium, DueAt: &tomorrow10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuickAdd(tt.input, now)
+			if err != nil {
+				t.Fatalf("ParseQuickAdd(%q) unexpected error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseQuickAdd(%q) = %+v, want %+v", tt.input, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuickAddEmptyTitle(t *testing.T) {
+	if _, err := ParseQuickAdd("!high #tag", time.Now()); !errors.Is(err, domain.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
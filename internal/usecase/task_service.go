@@ -2,34 +2,56 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"todolist/config"
 	"todolist/internal/domain"
 
 	"go.uber.org/zap"
 )
 
+// ErrOpenSubtasks возвращается при попытке завершить задачу с невыполненными подзадачами.
+// Ошибка оборачивает конфликт domain.Conflict с ID задачи, поэтому errorMessage показывает ее причину.
+var ErrOpenSubtasks = errors.New("task has open subtasks")
+
+// maxPendingReminders - максимальное количество неотправленных напоминаний у задачи
+const maxPendingReminders = 10
+
 // TaskService предоставляет методы для работы с задачами
 type TaskService struct {
-	taskRepository domain.TaskRepository
-	logger         *zap.Logger
+	taskRepository     domain.TaskRepository
+	reminderRepository domain.ReminderRepository
+	userRepository     domain.UserRepository
+	config             *config.Config
+	logger             *zap.Logger
 }
 
 // NewTaskService создает новый экземпляр TaskService
-func NewTaskService(taskRepository domain.TaskRepository, logger *zap.Logger) *TaskService {
+func NewTaskService(
+	taskRepository domain.TaskRepository,
+	reminderRepository domain.ReminderRepository,
+	userRepository domain.UserRepository,
+	config *config.Config,
+	logger *zap.Logger,
+) *TaskService {
 	return &TaskService{
-		taskRepository: taskRepository,
-		logger:         logger,
+		taskRepository:     taskRepository,
+		reminderRepository: reminderRepository,
+		userRepository:     userRepository,
+		config:             config,
+		logger:             logger,
 	}
 }
 
 // CreateTask создает новую задачу
 func (s *TaskService) CreateTask(ctx context.Context, userID int64, title, description string, priority domain.TaskPriority) (*domain.Task, error) {
 	if strings.TrimSpace(title) == "" {
-		return nil, fmt.Errorf("название задачи не может быть пустым")
+		return nil, domain.NewValidationError("title", "название задачи не может быть пустым")
 	}
 
 	task := &domain.Task{
@@ -44,7 +66,50 @@ func (s *TaskService) CreateTask(ctx context.Context, userID int64, title, descr
 
 	if err := s.taskRepository.Create(ctx, task); err != nil {
 		s.logger.Error("failed to create task", zap.Error(err))
-		return nil, fmt.Errorf("ошибка создания задачи")
+		return nil, fmt.Errorf("ошибка создания задачи: %w", err)
+	}
+
+	s.logger.Info("task created", zap.Int("task_id", task.ID), zap.Int64("user_id", userID))
+	return task, nil
+}
+
+// QuickAddTask создает задачу из строки быстрого добавления с маркерами
+// приоритета, тегов, срока и оценки (см. ParseQuickAdd)
+func (s *TaskService) QuickAddTask(ctx context.Context, userID int64, input string) (*domain.Task, error) {
+	now := time.Now().In(userLocation(ctx, s.userRepository, s.config, userID))
+
+	parsed, err := ParseQuickAdd(input, now)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &domain.Task{
+		Title:           parsed.Title,
+		Status:          domain.TaskStatusPending,
+		Priority:        parsed.Priority,
+		EstimateMinutes: int(parsed.Estimate.Minutes()),
+		UserID:          userID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	task.SetTags(parsed.Tags)
+
+	if parsed.DueAt != nil {
+		if !parsed.DueAt.After(now) {
+			return nil, domain.NewValidationError("due_at", "срок выполнения должен быть в будущем")
+		}
+		task.DueAt = parsed.DueAt
+	}
+
+	if err := s.taskRepository.Create(ctx, task); err != nil {
+		s.logger.Error("failed to create task", zap.Error(err))
+		return nil, fmt.Errorf("ошибка создания задачи: %w", err)
+	}
+
+	if task.DueAt != nil {
+		if err := s.scheduleReminders(ctx, task, s.reminderTimesForDue(*task.DueAt)); err != nil {
+			s.logger.Error("failed to schedule reminders", zap.Int("task_id", task.ID), zap.Error(err))
+		}
 	}
 
 	s.logger.Info("task created", zap.Int("task_id", task.ID), zap.Int64("user_id", userID))
@@ -56,7 +121,7 @@ func (s *TaskService) GetTasks(ctx context.Context, userID int64) ([]*domain.Tas
 	tasks, err := s.taskRepository.GetAll(ctx, userID)
 	if err != nil {
 		s.logger.Error("failed to get tasks", zap.Error(err))
-		return nil, fmt.Errorf("ошибка получения задач")
+		return nil, fmt.Errorf("ошибка получения задач: %w", err)
 	}
 
 	return tasks, nil
@@ -67,7 +132,18 @@ func (s *TaskService) GetTasksByStatus(ctx context.Context, userID int64, status
 	tasks, err := s.taskRepository.GetByUserID(ctx, userID, status)
 	if err != nil {
 		s.logger.Error("failed to get tasks by status", zap.Error(err))
-		return nil, fmt.Errorf("ошибка получения задач")
+		return nil, fmt.Errorf("ошибка получения задач: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// GetOverdueTasks получает просроченные задачи пользователя
+func (s *TaskService) GetOverdueTasks(ctx context.Context, userID int64) ([]*domain.Task, error) {
+	tasks, err := s.taskRepository.GetOverdue(ctx, userID, time.Now())
+	if err != nil {
+		s.logger.Error("failed to get overdue tasks", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения задач: %w", err)
 	}
 
 	return tasks, nil
@@ -77,39 +153,321 @@ func (s *TaskService) GetTasksByStatus(ctx context.Context, userID int64, status
 func (s *TaskService) GetTaskByID(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
 	task, err := s.taskRepository.GetByID(ctx, taskID)
 	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
 		s.logger.Error("failed to get task", zap.Error(err))
-		return nil, fmt.Errorf("задача не найдена")
+		return nil, fmt.Errorf("ошибка получения задачи: %w", err)
 	}
 
 	if task.UserID != userID {
-		return nil, fmt.Errorf("задача не принадлежит пользователю")
+		return nil, domain.Forbidden(domain.EntityTask, int64(taskID))
 	}
 
 	return task, nil
 }
 
-// CompleteTask помечает задачу как выполненную
-func (s *TaskService) CompleteTask(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
+// GetTaskWithSubtasks получает задачу по ID вместе с подзадачами и напоминаниями
+func (s *TaskService) GetTaskWithSubtasks(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
 	task, err := s.GetTaskByID(ctx, taskID, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.loadSubtasks(ctx, task); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadReminders(ctx, task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// CompleteTask помечает задачу как выполненную.
+// Задачу с невыполненными подзадачами завершить нельзя - для этого есть CompleteTaskWithSubtasks.
+func (s *TaskService) CompleteTask(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
+	return s.completeTask(ctx, taskID, userID, false)
+}
+
+// CompleteTaskWithSubtasks помечает задачу и все ее подзадачи как выполненные
+func (s *TaskService) CompleteTaskWithSubtasks(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
+	return s.completeTask(ctx, taskID, userID, true)
+}
+
+// completeTask завершает задачу, при force завершая и открытые подзадачи
+func (s *TaskService) completeTask(ctx context.Context, taskID int, userID int64, force bool) (*domain.Task, error) {
+	task, err := s.GetTaskWithSubtasks(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	if task.IsCompleted() {
-		return nil, fmt.Errorf("задача уже выполнена")
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "задача уже выполнена")
+	}
+
+	if task.HasOpenSubtasks() {
+		if !force {
+			return nil, fmt.Errorf("%w: %w", ErrOpenSubtasks,
+				domain.Conflict(domain.EntityTask, int64(taskID), "у задачи есть невыполненные подзадачи"))
+		}
+
+		for _, subtask := range task.Subtasks {
+			if subtask.IsCompleted() {
+				continue
+			}
+
+			subtask.Complete()
+			if err := s.taskRepository.Update(ctx, subtask); err != nil {
+				s.logger.Error("failed to complete subtask", zap.Int("task_id", subtask.ID), zap.Error(err))
+				return nil, fmt.Errorf("ошибка завершения подзадачи [%d]: %w", subtask.ID, err)
+			}
+		}
 	}
 
 	task.Complete()
 
 	if err := s.taskRepository.Update(ctx, task); err != nil {
 		s.logger.Error("failed to complete task", zap.Error(err))
-		return nil, fmt.Errorf("ошибка завершения задачи")
+		return nil, fmt.Errorf("ошибка завершения задачи: %w", err)
 	}
 
 	s.logger.Info("task completed", zap.Int("task_id", taskID), zap.Int64("user_id", userID))
+
+	if task.IsRecurring() {
+		if _, err := s.spawnNextOccurrence(ctx, task); err != nil {
+			s.logger.Error("failed to spawn next occurrence", zap.Int("task_id", taskID), zap.Error(err))
+		}
+	}
+
 	return task, nil
 }
 
+// AddSubtask добавляет подзадачу (пункт чек-листа) к задаче
+func (s *TaskService) AddSubtask(ctx context.Context, parentID int, userID int64, title string) (*domain.Task, error) {
+	if strings.TrimSpace(title) == "" {
+		return nil, domain.NewValidationError("title", "название подзадачи не может быть пустым")
+	}
+
+	parent, err := s.GetTaskByID(ctx, parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parent.IsSubtask() {
+		return nil, domain.Conflict(domain.EntityTask, int64(parentID), "нельзя добавить подзадачу к подзадаче")
+	}
+
+	if parent.IsCompleted() || parent.IsDeleted() {
+		return nil, domain.Conflict(domain.EntityTask, int64(parentID), "нельзя добавить подзадачу к завершенной или удаленной задаче")
+	}
+
+	subtask := &domain.Task{
+		Title:     strings.TrimSpace(title),
+		Status:    domain.TaskStatusPending,
+		Priority:  parent.Priority,
+		ParentID:  &parent.ID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.taskRepository.Create(ctx, subtask); err != nil {
+		s.logger.Error("failed to create subtask", zap.Error(err))
+		return nil, fmt.Errorf("ошибка создания подзадачи: %w", err)
+	}
+
+	s.logger.Info("subtask created", zap.Int("task_id", subtask.ID), zap.Int("parent_id", parentID))
+	return subtask, nil
+}
+
+// ToggleSubtask отмечает подзадачу выполненной или возвращает ее в работу
+func (s *TaskService) ToggleSubtask(ctx context.Context, subtaskID int, userID int64) (*domain.Task, error) {
+	subtask, err := s.GetTaskByID(ctx, subtaskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !subtask.IsSubtask() {
+		return nil, domain.Conflict(domain.EntityTask, int64(subtaskID), fmt.Sprintf("задача [%d] не является подзадачей", subtaskID))
+	}
+
+	if subtask.IsCompleted() {
+		subtask.Reopen()
+	} else {
+		subtask.Complete()
+	}
+
+	if err := s.taskRepository.Update(ctx, subtask); err != nil {
+		s.logger.Error("failed to toggle subtask", zap.Error(err))
+		return nil, fmt.Errorf("ошибка обновления подзадачи: %w", err)
+	}
+
+	s.logger.Info("subtask toggled", zap.Int("task_id", subtaskID), zap.String("status", string(subtask.Status)))
+	return subtask, nil
+}
+
+// loadSubtasks загружает подзадачи задачи
+func (s *TaskService) loadSubtasks(ctx context.Context, task *domain.Task) error {
+	if task.IsSubtask() {
+		return nil
+	}
+
+	subtasks, err := s.taskRepository.GetSubtasks(ctx, task.ID)
+	if err != nil {
+		s.logger.Error("failed to get subtasks", zap.Error(err))
+		return fmt.Errorf("ошибка получения подзадач: %w", err)
+	}
+
+	task.Subtasks = subtasks
+	return nil
+}
+
+// loadReminders загружает все напоминания задачи, включая отправленные
+func (s *TaskService) loadReminders(ctx context.Context, task *domain.Task) error {
+	reminders, err := s.reminderRepository.GetByTaskID(ctx, task.ID)
+	if err != nil {
+		s.logger.Error("failed to get reminders", zap.Error(err))
+		return fmt.Errorf("ошибка получения напоминаний: %w", err)
+	}
+
+	task.Reminders = reminders
+	return nil
+}
+
+// SetTaskRecurrence устанавливает правило повторения задачи.
+// Пустое правило или "off" отключает повторение.
+func (s *TaskService) SetTaskRecurrence(ctx context.Context, taskID int, userID int64, rule string) (*domain.Task, error) {
+	task, err := s.GetTaskByID(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.IsCompleted() || task.IsDeleted() {
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "нельзя настроить повторение для завершенной или удаленной задачи")
+	}
+
+	var recurrence *domain.Recurrence
+	switch strings.ToLower(strings.TrimSpace(rule)) {
+	case "", "off", "нет", "-":
+	default:
+		recurrence, err = domain.ParseRecurrenceRule(rule)
+		if err != nil {
+			return nil, domain.NewValidationError("recurrence", err.Error())
+		}
+
+		// Без явного времени повторения привязываемся ко времени срока или напоминания
+		anchor := task.DueAt
+		if anchor == nil {
+			anchor = task.NextReminderAt
+		}
+		loc := userLocation(ctx, s.userRepository, s.config, userID)
+		if !recurrence.HasTime && anchor != nil {
+			local := anchor.In(loc)
+			recurrence.Hour, recurrence.Minute = local.Hour(), local.Minute()
+			recurrence.HasTime = true
+		}
+
+		// День месяца берется из исходной даты, чтобы повторения не смещались после коротких месяцев
+		pinned := time.Now()
+		if anchor != nil {
+			pinned = *anchor
+		}
+		recurrence.PinDate(pinned.In(loc))
+	}
+
+	task.SetRecurrence(recurrence)
+
+	if err := s.taskRepository.Update(ctx, task); err != nil {
+		s.logger.Error("failed to set recurrence", zap.Error(err))
+		return nil, fmt.Errorf("ошибка настройки повторения: %w", err)
+	}
+
+	s.logger.Info("recurrence set", zap.Int("task_id", taskID), zap.String("recurrence", task.Recurrence))
+	return task, nil
+}
+
+// spawnNextOccurrence создает следующее повторение выполненной задачи
+func (s *TaskService) spawnNextOccurrence(ctx context.Context, task *domain.Task) (*domain.Task, error) {
+	recurrence, err := domain.ParseRecurrence(task.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence %q: %w", task.Recurrence, err)
+	}
+
+	// Повторения отсчитываются от срока выполнения, а без него - от времени напоминания
+	now := time.Now()
+	base := now
+	switch {
+	case task.DueAt != nil:
+		base = *task.DueAt
+	case task.NextReminderAt != nil:
+		base = *task.NextReminderAt
+	}
+
+	// Время и дни повторения заданы в часовом поясе пользователя, а не сервера
+	base = base.In(userLocation(ctx, s.userRepository, s.config, task.UserID))
+
+	// Пропускаем повторения, которые уже в прошлом (задачу выполнили с опозданием)
+	next := recurrence.Next(base)
+	for i := 0; i < 1000 && !next.IsZero() && !next.After(now); i++ {
+		next = recurrence.Next(next)
+	}
+	if next.IsZero() || !next.After(now) {
+		return nil, fmt.Errorf("no next occurrence for recurrence %q", task.Recurrence)
+	}
+
+	nextTask := &domain.Task{
+		Title:           task.Title,
+		Description:     task.Description,
+		Status:          domain.TaskStatusPending,
+		Priority:        task.Priority,
+		Recurrence:      task.Recurrence,
+		Tags:            task.Tags,
+		EstimateMinutes: task.EstimateMinutes,
+		UserID:          task.UserID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	reminders := []time.Time{next}
+	if task.DueAt != nil {
+		nextTask.DueAt = &next
+		reminders = s.reminderTimesForDue(next)
+	}
+
+	if err := s.taskRepository.Create(ctx, nextTask); err != nil {
+		return nil, fmt.Errorf("failed to create next occurrence: %w", err)
+	}
+
+	if err := s.scheduleReminders(ctx, nextTask, reminders); err != nil {
+		return nil, fmt.Errorf("failed to schedule reminders for next occurrence: %w", err)
+	}
+
+	// Чек-лист переносится в следующее повторение невыполненным
+	for _, subtask := range task.Subtasks {
+		nextSubtask := &domain.Task{
+			Title:     subtask.Title,
+			Status:    domain.TaskStatusPending,
+			Priority:  subtask.Priority,
+			ParentID:  &nextTask.ID,
+			UserID:    task.UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err := s.taskRepository.Create(ctx, nextSubtask); err != nil {
+			return nil, fmt.Errorf("failed to copy subtask %d: %w", subtask.ID, err)
+		}
+	}
+
+	s.logger.Info("next occurrence created",
+		zap.Int("task_id", task.ID),
+		zap.Int("next_task_id", nextTask.ID),
+		zap.Time("notify_at", next))
+	return nextTask, nil
+}
+
 // DeleteTask удаляет задачу
 func (s *TaskService) DeleteTask(ctx context.Context, taskID int, userID int64) error {
 	task, err := s.GetTaskByID(ctx, taskID, userID)
@@ -118,12 +476,12 @@ func (s *TaskService) DeleteTask(ctx context.Context, taskID int, userID int64)
 	}
 
 	if task.IsDeleted() {
-		return fmt.Errorf("задача уже удалена")
+		return domain.Conflict(domain.EntityTask, int64(taskID), "задача уже удалена")
 	}
 
 	if err := s.taskRepository.Delete(ctx, taskID); err != nil {
 		s.logger.Error("failed to delete task", zap.Error(err))
-		return fmt.Errorf("ошибка удаления задачи")
+		return fmt.Errorf("ошибка удаления задачи: %w", err)
 	}
 
 	s.logger.Info("task deleted", zap.Int("task_id", taskID), zap.Int64("user_id", userID))
@@ -138,7 +496,7 @@ func (s *TaskService) UpdateTask(ctx context.Context, taskID int, userID int64,
 	}
 
 	if task.IsCompleted() || task.IsDeleted() {
-		return nil, fmt.Errorf("нельзя редактировать завершенную или удаленную задачу")
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "нельзя редактировать завершенную или удаленную задачу")
 	}
 
 	if strings.TrimSpace(title) != "" {
@@ -150,48 +508,209 @@ func (s *TaskService) UpdateTask(ctx context.Context, taskID int, userID int64,
 
 	if err := s.taskRepository.Update(ctx, task); err != nil {
 		s.logger.Error("failed to update task", zap.Error(err))
-		return nil, fmt.Errorf("ошибка обновления задачи")
+		return nil, fmt.Errorf("ошибка обновления задачи: %w", err)
 	}
 
 	s.logger.Info("task updated", zap.Int("task_id", taskID), zap.Int64("user_id", userID))
 	return task, nil
 }
 
-// SetTaskNotification устанавливает уведомление для задачи
-func (s *TaskService) SetTaskNotification(ctx context.Context, taskID int, userID int64, notifyAt time.Time) (*domain.Task, error) {
+// AddReminder добавляет напоминание о задаче и возвращает задачу со всеми напоминаниями.
+// Остальные напоминания задачи сохраняются.
+func (s *TaskService) AddReminder(ctx context.Context, taskID int, userID int64, remindAt time.Time) (*domain.Task, error) {
+	task, err := s.GetTaskByID(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.IsCompleted() || task.IsDeleted() {
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "нельзя установить уведомление для завершенной или удаленной задачи")
+	}
+
+	if remindAt.Before(time.Now()) {
+		return nil, domain.NewValidationError("remind_at", "время уведомления должно быть в будущем")
+	}
+
+	if err := s.loadReminders(ctx, task); err != nil {
+		return nil, err
+	}
+
+	if len(task.PendingReminders()) >= maxPendingReminders {
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID),
+			fmt.Sprintf("у задачи уже %d напоминаний, удалите лишние", maxPendingReminders))
+	}
+
+	reminder := &domain.Reminder{TaskID: task.ID, RemindAt: remindAt}
+	if err := s.reminderRepository.Create(ctx, reminder); err != nil {
+		s.logger.Error("failed to add reminder", zap.Error(err))
+		return nil, fmt.Errorf("ошибка установки уведомления: %w", err)
+	}
+
+	s.logger.Info("reminder added",
+		zap.Int("task_id", taskID),
+		zap.Int("reminder_id", reminder.ID),
+		zap.Time("remind_at", remindAt))
+	return s.GetTaskWithSubtasks(ctx, taskID, userID)
+}
+
+// RemoveReminder удаляет напоминание и возвращает задачу с оставшимися напоминаниями
+func (s *TaskService) RemoveReminder(ctx context.Context, reminderID int, userID int64) (*domain.Task, error) {
+	reminder, err := s.reminderRepository.GetByID(ctx, reminderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+		s.logger.Error("failed to get reminder", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения напоминания: %w", err)
+	}
+
+	// Проверяем, что задача напоминания принадлежит пользователю
+	if _, err := s.GetTaskByID(ctx, reminder.TaskID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.reminderRepository.Delete(ctx, reminderID); err != nil {
+		s.logger.Error("failed to delete reminder", zap.Error(err))
+		return nil, fmt.Errorf("ошибка удаления напоминания: %w", err)
+	}
+
+	s.logger.Info("reminder removed", zap.Int("task_id", reminder.TaskID), zap.Int("reminder_id", reminderID))
+	return s.GetTaskWithSubtasks(ctx, reminder.TaskID, userID)
+}
+
+// SetTaskDueDate устанавливает срок выполнения задачи.
+// Неотправленные напоминания заменяются новыми, рассчитанными от срока
+// по настройке TASKS_REMINDER_OFFSETS.
+func (s *TaskService) SetTaskDueDate(ctx context.Context, taskID int, userID int64, dueAt time.Time) (*domain.Task, error) {
 	task, err := s.GetTaskByID(ctx, taskID, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	if task.IsCompleted() || task.IsDeleted() {
-		return nil, fmt.Errorf("нельзя установить уведомление для завершенной или удаленной задачи")
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "нельзя установить срок для завершенной или удаленной задачи")
 	}
 
-	if notifyAt.Before(time.Now()) {
-		return nil, fmt.Errorf("время уведомления должно быть в будущем")
+	if dueAt.Before(time.Now()) {
+		return nil, domain.NewValidationError("due_at", "срок выполнения должен быть в будущем")
 	}
 
-	task.SetNotification(notifyAt)
+	task.SetDueDate(&dueAt)
 
 	if err := s.taskRepository.Update(ctx, task); err != nil {
-		s.logger.Error("failed to set notification", zap.Error(err))
-		return nil, fmt.Errorf("ошибка установки уведомления")
+		s.logger.Error("failed to set due date", zap.Error(err))
+		return nil, fmt.Errorf("ошибка установки срока: %w", err)
 	}
 
-	s.logger.Info("notification set", zap.Int("task_id", taskID), zap.Time("notify_at", notifyAt))
+	if err := s.reminderRepository.DeletePending(ctx, task.ID); err != nil {
+		s.logger.Error("failed to reset reminders", zap.Error(err))
+		return nil, fmt.Errorf("ошибка установки напоминаний: %w", err)
+	}
+
+	if err := s.scheduleReminders(ctx, task, s.reminderTimesForDue(dueAt)); err != nil {
+		s.logger.Error("failed to schedule reminders", zap.Error(err))
+		return nil, fmt.Errorf("ошибка установки напоминаний: %w", err)
+	}
+
+	s.logger.Info("due date set", zap.Int("task_id", taskID), zap.Time("due_at", dueAt))
 	return task, nil
 }
 
-// GetTasksForNotification получает задачи для отправки уведомлений
-func (s *TaskService) GetTasksForNotification(ctx context.Context) ([]*domain.Task, error) {
-	tasks, err := s.taskRepository.GetTasksForNotification(ctx, time.Now())
+// ClearTaskDueDate убирает срок выполнения задачи вместе с неотправленными напоминаниями,
+// рассчитанными от него. Напоминания, добавленные на другое время, сохраняются.
+func (s *TaskService) ClearTaskDueDate(ctx context.Context, taskID int, userID int64) (*domain.Task, error) {
+	task, err := s.GetTaskByID(ctx, taskID, userID)
 	if err != nil {
-		s.logger.Error("failed to get notification tasks", zap.Error(err))
 		return nil, err
 	}
 
-	return tasks, nil
+	if task.IsCompleted() || task.IsDeleted() {
+		return nil, domain.Conflict(domain.EntityTask, int64(taskID), "нельзя изменить срок завершенной или удаленной задачи")
+	}
+
+	if task.DueAt == nil {
+		return s.GetTaskWithSubtasks(ctx, taskID, userID)
+	}
+	dueAt := *task.DueAt
+
+	if err := s.loadReminders(ctx, task); err != nil {
+		return nil, err
+	}
+
+	task.SetDueDate(nil)
+
+	if err := s.taskRepository.Update(ctx, task); err != nil {
+		s.logger.Error("failed to clear due date", zap.Error(err))
+		return nil, fmt.Errorf("ошибка удаления срока: %w", err)
+	}
+
+	for _, reminder := range task.PendingReminders() {
+		if !s.isDueReminder(dueAt, reminder.RemindAt) {
+			continue
+		}
+		if err := s.reminderRepository.Delete(ctx, reminder.ID); err != nil {
+			s.logger.Error("failed to delete due reminder", zap.Int("reminder_id", reminder.ID), zap.Error(err))
+			return nil, fmt.Errorf("ошибка удаления напоминаний: %w", err)
+		}
+	}
+
+	s.logger.Info("due date cleared", zap.Int("task_id", taskID))
+	return s.GetTaskWithSubtasks(ctx, taskID, userID)
+}
+
+// isDueReminder проверяет, рассчитано ли время напоминания от срока: в момент срока
+// или за одно из смещений TASKS_REMINDER_OFFSETS до него (см. reminderTimesForDue)
+func (s *TaskService) isDueReminder(dueAt, remindAt time.Time) bool {
+	if remindAt.Equal(dueAt) {
+		return true
+	}
+	for _, offset := range s.config.Tasks.ReminderOffsets {
+		if remindAt.Equal(dueAt.Add(-offset)) {
+			return true
+		}
+	}
+	return false
+}
+
+// reminderTimesForDue вычисляет время напоминаний для срока выполнения.
+// Напоминания, которые уже в прошлом, пропускаются; если не осталось ни одного,
+// напоминание приходит в момент срока.
+func (s *TaskService) reminderTimesForDue(dueAt time.Time) []time.Time {
+	now := time.Now()
+	seen := make(map[time.Time]bool)
+
+	var times []time.Time
+	for _, offset := range s.config.Tasks.ReminderOffsets {
+		remindAt := dueAt.Add(-offset)
+		if remindAt.Before(now) || seen[remindAt] {
+			continue
+		}
+		seen[remindAt] = true
+		times = append(times, remindAt)
+	}
+
+	if len(times) == 0 {
+		return []time.Time{dueAt}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// scheduleReminders создает напоминания задачи на указанное время
+func (s *TaskService) scheduleReminders(ctx context.Context, task *domain.Task, times []time.Time) error {
+	for _, remindAt := range times {
+		reminder := &domain.Reminder{TaskID: task.ID, RemindAt: remindAt}
+		if err := s.reminderRepository.Create(ctx, reminder); err != nil {
+			return err
+		}
+		task.Reminders = append(task.Reminders, reminder)
+	}
+
+	if len(times) > 0 {
+		task.NextReminderAt = &times[0]
+	}
+	return nil
 }
 
 // ParseTaskIDFromText извлекает ID задачи из текста
@@ -203,11 +722,11 @@ func (s *TaskService) ParseTaskIDFromText(text string) (int, error) {
 			return id, nil
 		}
 	}
-	return 0, fmt.Errorf("не удалось найти ID задачи в тексте")
+	return 0, domain.NewValidationError("task_id", "не удалось найти ID задачи в тексте")
 }
 
-// FormatTaskList форматирует список задач для отображения
-func (s *TaskService) FormatTaskList(tasks []*domain.Task) string {
+// FormatTaskList форматирует список задач для отображения в часовом поясе loc
+func (s *TaskService) FormatTaskList(tasks []*domain.Task, loc *time.Location) string {
 	if len(tasks) == 0 {
 		return "📝 Задач нет"
 	}
@@ -231,14 +750,35 @@ func (s *TaskService) FormatTaskList(tasks []*domain.Task) string {
 			priority = "🟢"
 		}
 
-		result.WriteString(fmt.Sprintf("%s %s [%d] %s\n", status, priority, task.ID, task.Title))
+		repeat := ""
+		if task.IsRecurring() {
+			repeat = " 🔁"
+		}
+
+		result.WriteString(fmt.Sprintf("%s %s [%d] %s%s\n", status, priority, task.ID, task.Title, repeat))
 
 		if task.Description != "" {
 			result.WriteString(fmt.Sprintf("   💬 %s\n", task.Description))
 		}
 
-		if task.NotifyAt != nil {
-			result.WriteString(fmt.Sprintf("   ⏰ %s\n", task.NotifyAt.Format("02.01.2006 15:04")))
+		if tags := formatTags(task); tags != "" || task.EstimateMinutes > 0 {
+			line := tags
+			if task.EstimateMinutes > 0 {
+				line = strings.TrimSpace(fmt.Sprintf("%s ⏱️ %s", line, formatEstimate(task.EstimateMinutes)))
+			}
+			result.WriteString(fmt.Sprintf("   🏷️ %s\n", line))
+		}
+
+		if task.DueAt != nil {
+			overdue := ""
+			if task.IsOverdue() {
+				overdue = " 🔥"
+			}
+			result.WriteString(fmt.Sprintf("   📅 до %s%s\n", task.DueAt.In(loc).Format("02.01.2006 15:04"), overdue))
+		}
+
+		if task.NextReminderAt != nil {
+			result.WriteString(fmt.Sprintf("   ⏰ %s\n", task.NextReminderAt.In(loc).Format("02.01.2006 15:04")))
 		}
 
 		result.WriteString("\n")
@@ -247,11 +787,13 @@ func (s *TaskService) FormatTaskList(tasks []*domain.Task) string {
 	return result.String()
 }
 
-// FormatTask форматирует одну задачу для отображения
-func (s *TaskService) FormatTask(task *domain.Task) string {
+// FormatTask форматирует одну задачу для отображения в часовом поясе loc
+func (s *TaskService) FormatTask(task *domain.Task, loc *time.Location) string {
 	status := "⏳ Не выполнена"
 	if task.IsCompleted() {
 		status = "✅ Выполнена"
+	} else if task.IsOverdue() {
+		status = "🔥 Просрочена"
 	}
 
 	priority := ""
@@ -267,21 +809,87 @@ func (s *TaskService) FormatTask(task *domain.Task) string {
 	result := fmt.Sprintf("📋 Задача [%d]\n\n", task.ID)
 	result += fmt.Sprintf("📌 Название: %s\n", task.Title)
 
+	if task.IsSubtask() {
+		result += fmt.Sprintf("↳ Подзадача задачи [%d]\n", *task.ParentID)
+	}
+
 	if task.Description != "" {
 		result += fmt.Sprintf("💬 Описание: %s\n", task.Description)
 	}
 
 	result += fmt.Sprintf("📊 Статус: %s\n", status)
 	result += fmt.Sprintf("🎯 Приоритет: %s\n", priority)
-	result += fmt.Sprintf("📅 Создана: %s\n", task.CreatedAt.Format("02.01.2006 15:04"))
 
-	if task.NotifyAt != nil {
-		result += fmt.Sprintf("⏰ Уведомление: %s\n", task.NotifyAt.Format("02.01.2006 15:04"))
+	if tags := formatTags(task); tags != "" {
+		result += fmt.Sprintf("🏷️ Теги: %s\n", tags)
+	}
+
+	if task.EstimateMinutes > 0 {
+		result += fmt.Sprintf("⏱️ Оценка: %s\n", formatEstimate(task.EstimateMinutes))
+	}
+
+	result += fmt.Sprintf("📅 Создана: %s\n", task.CreatedAt.In(loc).Format("02.01.2006 15:04"))
+
+	if task.DueAt != nil {
+		result += fmt.Sprintf("📅 Срок: %s\n", task.DueAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	if len(task.Reminders) > 0 {
+		result += "⏰ Напоминания:\n"
+		for _, reminder := range task.Reminders {
+			line := fmt.Sprintf("   • %s", reminder.RemindAt.In(loc).Format("02.01.2006 15:04"))
+			if reminder.IsSent() {
+				line += " ✓ отправлено"
+			}
+			result += line + "\n"
+		}
+	} else if task.NextReminderAt != nil {
+		result += fmt.Sprintf("⏰ Уведомление: %s\n", task.NextReminderAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	if task.IsRecurring() {
+		if recurrence, err := domain.ParseRecurrence(task.Recurrence); err == nil {
+			result += fmt.Sprintf("🔁 Повтор: %s\n", recurrence.Describe())
+		}
 	}
 
 	if task.CompletedAt != nil {
-		result += fmt.Sprintf("✅ Завершена: %s\n", task.CompletedAt.Format("02.01.2006 15:04"))
+		result += fmt.Sprintf("✅ Завершена: %s\n", task.CompletedAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	if len(task.Subtasks) > 0 {
+		done, total := task.SubtaskProgress()
+		result += fmt.Sprintf("\n☑️ Подзадачи: %d/%d выполнено\n", done, total)
+		for _, subtask := range task.Subtasks {
+			mark := "⬜"
+			if subtask.IsCompleted() {
+				mark = "✅"
+			}
+			result += fmt.Sprintf("%s [%d] %s\n", mark, subtask.ID, subtask.Title)
+		}
 	}
 
 	return result
 }
+
+// formatTags форматирует теги задачи в виде "#tag1 #tag2"
+func formatTags(task *domain.Task) string {
+	tags := task.TagList()
+	for i, tag := range tags {
+		tags[i] = "#" + tag
+	}
+	return strings.Join(tags, " ")
+}
+
+// formatEstimate форматирует оценку трудозатрат: "30 мин", "2 ч", "1 ч 30 мин"
+func formatEstimate(minutes int) string {
+	hours, minutes := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d мин", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%d ч", hours)
+	default:
+		return fmt.Sprintf("%d ч %d мин", hours, minutes)
+	}
+}
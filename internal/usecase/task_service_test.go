@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todolist/config"
+	"todolist/internal/domain"
+)
+
+// memoryTaskRepository хранит одну задачу. Остальные методы не нужны тестам
+// и паникуют через встроенный nil-интерфейс.
+type memoryTaskRepository struct {
+	domain.TaskRepository
+	task *domain.Task
+}
+
+func (r *memoryTaskRepository) GetByID(ctx context.Context, id int) (*domain.Task, error) {
+	if r.task == nil || r.task.ID != id {
+		return nil, domain.NotFound(domain.EntityTask, int64(id))
+	}
+	task := *r.task
+	return &task, nil
+}
+
+func (r *memoryTaskRepository) GetSubtasks(ctx context.Context, parentID int) ([]*domain.Task, error) {
+	return nil, nil
+}
+
+func (r *memoryTaskRepository) Update(ctx context.Context, task *domain.Task) error {
+	saved := *task
+	r.task = &saved
+	return nil
+}
+
+// memoryReminderRepository хранит напоминания в памяти
+type memoryReminderRepository struct {
+	reminders []*domain.Reminder
+}
+
+func (r *memoryReminderRepository) Create(ctx context.Context, reminder *domain.Reminder) error {
+	reminder.ID = len(r.reminders) + 1
+	r.reminders = append(r.reminders, reminder)
+	return nil
+}
+
+func (r *memoryReminderRepository) GetByID(ctx context.Context, id int) (*domain.Reminder, error) {
+	for _, reminder := range r.reminders {
+		if reminder.ID == id {
+			return reminder, nil
+		}
+	}
+	return nil, domain.NotFound(domain.EntityReminder, int64(id))
+}
+
+func (r *memoryReminderRepository) GetByTaskID(ctx context.Context, taskID int) ([]*domain.Reminder, error) {
+	var result []*domain.Reminder
+	for _, reminder := range r.reminders {
+		if reminder.TaskID == taskID {
+			result = append(result, reminder)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryReminderRepository) Delete(ctx context.Context, id int) error {
+	for i, reminder := range r.reminders {
+		if reminder.ID == id {
+			r.reminders = append(r.reminders[:i], r.reminders[i+1:]...)
+			return nil
+		}
+	}
+	return domain.NotFound(domain.EntityReminder, int64(id))
+}
+
+func (r *memoryReminderRepository) DeletePending(ctx context.Context, taskID int) error {
+	kept := r.reminders[:0]
+	for _, reminder := range r.reminders {
+		if reminder.TaskID != taskID || reminder.IsSent() {
+			kept = append(kept, reminder)
+		}
+	}
+	r.reminders = kept
+	return nil
+}
+
+func TestClearTaskDueDate(t *testing.T) {
+	const userID, taskID = 1, 7
+	cfg := &config.Config{Tasks: config.TasksConfig{ReminderOffsets: []time.Duration{24 * time.Hour, time.Hour}}}
+	dueAt := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	manual := dueAt.Add(-30 * time.Minute)
+
+	newService := func(status domain.TaskStatus) (*TaskService, *memoryReminderRepository) {
+		tasks := &memoryTaskRepository{task: &domain.Task{ID: taskID, UserID: userID, Status: status, Title: "Отчет"}}
+		reminders := &memoryReminderRepository{}
+		return NewTaskService(tasks, reminders, nil, cfg, zap.NewNop()), reminders
+	}
+
+	t.Run("убирает напоминания от срока", func(t *testing.T) {
+		service, reminders := newService(domain.TaskStatusPending)
+		if _, err := service.SetTaskDueDate(context.Background(), taskID, userID, dueAt); err != nil {
+			t.Fatalf("SetTaskDueDate: %v", err)
+		}
+		if _, err := service.AddReminder(context.Background(), taskID, userID, manual); err != nil {
+			t.Fatalf("AddReminder: %v", err)
+		}
+
+		task, err := service.ClearTaskDueDate(context.Background(), taskID, userID)
+		if err != nil {
+			t.Fatalf("ClearTaskDueDate: %v", err)
+		}
+		if task.DueAt != nil {
+			t.Fatalf("due date = %v, want none", task.DueAt)
+		}
+		if len(reminders.reminders) != 1 || !reminders.reminders[0].RemindAt.Equal(manual) {
+			t.Fatalf("reminders = %+v, want only the manual one at %v", reminders.reminders, manual)
+		}
+	})
+
+	for _, status := range []domain.TaskStatus{domain.TaskStatusCompleted, domain.TaskStatusDeleted} {
+		t.Run(string(status), func(t *testing.T) {
+			service, _ := newService(status)
+			if _, err := service.ClearTaskDueDate(context.Background(), taskID, userID); !errors.Is(err, domain.ErrConflict) {
+				t.Fatalf("ClearTaskDueDate error = %v, want ErrConflict", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package usecase
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"todolist/config"
+	"todolist/internal/domain"
+	"todolist/internal/totp"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// recoveryCodeCount - сколько кодов восстановления выдается за раз
+	recoveryCodeCount = 10
+	// recoveryCodeSize - размер кода восстановления в байтах (8 символов base32)
+	recoveryCodeSize = 5
+	// totpSkew - сколько соседних шагов принимается, чтобы учесть расхождение часов телефона
+	totpSkew = 1
+)
+
+var (
+	// errInvalidCode не уточняет, какой код ожидался: TOTP или код восстановления
+	errInvalidCode = domain.NewValidationError("code", "неверный код подтверждения")
+	// errTwoFactorUnavailable возвращается, если на сервере не задан AUTH_TOTP_KEY
+	errTwoFactorUnavailable = domain.Conflict(domain.EntityUser, 0, "двухфакторная аутентификация не настроена на сервере")
+	errTwoFactorEnabled     = domain.Conflict(domain.EntityUser, 0, "двухфакторная аутентификация уже включена")
+	errTwoFactorDisabled    = domain.Conflict(domain.EntityUser, 0, "двухфакторная аутентификация не включена")
+	errTwoFactorNotEnrolled = domain.Conflict(domain.EntityUser, 0, "сначала получите QR-код командой /2fa on")
+)
+
+// TwoFactorEnrollment содержит данные для добавления аккаунта в приложение-аутентификатор
+type TwoFactorEnrollment struct {
+	// Secret - секрет в base32 для ручного ввода
+	Secret string
+	// URI - ссылка otpauth:// для QR-кода
+	URI string
+}
+
+// TwoFactorService предоставляет методы двухфакторной аутентификации по TOTP.
+// Секрет хранится в базе зашифрованным AES-GCM, коды восстановления - только в виде SHA-256.
+type TwoFactorService struct {
+	userRepository     domain.UserRepository
+	recoveryRepository domain.RecoveryCodeRepository
+	config             *config.Config
+	logger             *zap.Logger
+	// aead - шифр секретов, nil если AUTH_TOTP_KEY не задан
+	aead cipher.AEAD
+}
+
+// NewTwoFactorService создает новый экземпляр TwoFactorService.
+// Ключ AES-256 получается из AUTH_TOTP_KEY через SHA-256.
+func NewTwoFactorService(
+	userRepository domain.UserRepository,
+	recoveryRepository domain.RecoveryCodeRepository,
+	config *config.Config,
+	logger *zap.Logger,
+) (*TwoFactorService, error) {
+	s := &TwoFactorService{
+		userRepository:     userRepository,
+		recoveryRepository: recoveryRepository,
+		config:             config,
+		logger:             logger,
+	}
+
+	if config.Auth.TOTPKey == "" {
+		return s, nil
+	}
+
+	key := sha256.Sum256([]byte(config.Auth.TOTPKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %w", err)
+	}
+
+	s.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %w", err)
+	}
+
+	return s, nil
+}
+
+// Available проверяет, можно ли подключить двухфакторную аутентификацию
+func (s *TwoFactorService) Available() bool {
+	return s.aead != nil
+}
+
+// Enroll создает новый секрет для пользователя. Вход не требует кода,
+// пока пользователь не подтвердит секрет через Confirm.
+func (s *TwoFactorService) Enroll(ctx context.Context, user *domain.User) (*TwoFactorEnrollment, error) {
+	if !s.Available() {
+		return nil, errTwoFactorUnavailable
+	}
+
+	if user.TOTPEnabled {
+		return nil, errTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.logger.Error("failed to generate totp secret", zap.Error(err))
+		return nil, fmt.Errorf("ошибка подключения двухфакторной аутентификации: %w", err)
+	}
+
+	encrypted, err := s.encrypt(secret)
+	if err != nil {
+		s.logger.Error("failed to encrypt totp secret", zap.Error(err))
+		return nil, fmt.Errorf("ошибка подключения двухфакторной аутентификации: %w", err)
+	}
+
+	if err := s.userRepository.SetTOTPState(ctx, user.ID, encrypted, false); err != nil {
+		s.logger.Error("failed to save totp secret", zap.Error(err))
+		return nil, fmt.Errorf("ошибка подключения двухфакторной аутентификации: %w", err)
+	}
+	user.TOTPSecret = encrypted
+
+	account := user.Username
+	if account == "" {
+		account = fmt.Sprintf("%d", user.TelegramID)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(secret, s.config.Auth.TOTPIssuer, account),
+	}, nil
+}
+
+// Confirm включает двухфакторную аутентификацию после проверки первого кода из приложения
+// и возвращает коды восстановления. Коды показываются один раз.
+func (s *TwoFactorService) Confirm(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	if !s.Available() {
+		return nil, errTwoFactorUnavailable
+	}
+
+	if user.TOTPEnabled {
+		return nil, errTwoFactorEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, errTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepository.SetTOTPState(ctx, user.ID, user.TOTPSecret, true); err != nil {
+		s.logger.Error("failed to enable totp", zap.Error(err))
+		return nil, fmt.Errorf("ошибка подключения двухфакторной аутентификации: %w", err)
+	}
+	user.TOTPEnabled = true
+
+	s.logger.Info("two-factor authentication enabled", zap.Int64("user_id", user.ID))
+	return codes, nil
+}
+
+// Disable отключает двухфакторную аутентификацию. Требует код из приложения или код восстановления.
+func (s *TwoFactorService) Disable(ctx context.Context, user *domain.User, code string) error {
+	if !user.TOTPEnabled {
+		return errTwoFactorDisabled
+	}
+
+	if err := s.Verify(ctx, user, code); err != nil {
+		return err
+	}
+
+	if err := s.recoveryRepository.Replace(ctx, user.ID, nil); err != nil {
+		s.logger.Error("failed to delete recovery codes", zap.Error(err))
+		return fmt.Errorf("ошибка отключения двухфакторной аутентификации: %w", err)
+	}
+
+	if err := s.userRepository.SetTOTPState(ctx, user.ID, "", false); err != nil {
+		s.logger.Error("failed to disable totp", zap.Error(err))
+		return fmt.Errorf("ошибка отключения двухфакторной аутентификации: %w", err)
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+
+	s.logger.Info("two-factor authentication disabled", zap.Int64("user_id", user.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми. Требует код из приложения:
+// кодом восстановления нельзя выпустить новые коды.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, errTwoFactorDisabled
+	}
+
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("recovery codes regenerated", zap.Int64("user_id", user.ID))
+	return codes, nil
+}
+
+// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+func (s *TwoFactorService) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	count, err := s.recoveryRepository.CountUnused(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to count recovery codes", zap.Error(err))
+		return 0, fmt.Errorf("ошибка получения кодов восстановления: %w", err)
+	}
+	return count, nil
+}
+
+// Verify проверяет код из приложения (6 цифр) или одноразовый код восстановления
+func (s *TwoFactorService) Verify(ctx context.Context, user *domain.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits && isDigits(code) {
+		return s.verifyTOTP(ctx, user, code)
+	}
+
+	err := s.recoveryRepository.Use(ctx, user.ID, hashCode(code), time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errInvalidCode
+		}
+		s.logger.Error("failed to use recovery code", zap.Error(err))
+		return fmt.Errorf("ошибка проверки кода: %w", err)
+	}
+
+	s.logger.Info("recovery code used", zap.Int64("user_id", user.ID))
+	return nil
+}
+
+// verifyTOTP проверяет код из приложения и запоминает его шаг, чтобы код нельзя было использовать повторно
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, user *domain.User, code string) error {
+	if !s.Available() {
+		s.logger.Error("totp key is not configured", zap.Int64("user_id", user.ID))
+		return fmt.Errorf("ошибка проверки кода")
+	}
+
+	secret, err := s.decrypt(user.TOTPSecret)
+	if err != nil {
+		s.logger.Error("failed to decrypt totp secret", zap.Int64("user_id", user.ID), zap.Error(err))
+		return fmt.Errorf("ошибка проверки кода: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return errInvalidCode
+	}
+
+	if err := s.userRepository.UseTOTPStep(ctx, user.ID, step); err != nil {
+		return err
+	}
+
+	user.TOTPLastStep = step
+	return nil
+}
+
+// issueRecoveryCodes генерирует новые коды восстановления и сохраняет их хеши.
+// Коды возвращаются в виде XXXX-XXXX, дефис при вводе не обязателен.
+func (s *TwoFactorService) issueRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateCode(recoveryCodeSize)
+		if err != nil {
+			s.logger.Error("failed to generate recovery code", zap.Error(err))
+			return nil, fmt.Errorf("ошибка создания кодов восстановления: %w", err)
+		}
+
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashCode(code))
+	}
+
+	if err := s.recoveryRepository.Replace(ctx, userID, hashes); err != nil {
+		s.logger.Error("failed to save recovery codes", zap.Error(err))
+		return nil, fmt.Errorf("ошибка создания кодов восстановления: %w", err)
+	}
+
+	return codes, nil
+}
+
+// encrypt шифрует секрет и возвращает nonce вместе с шифртекстом в base64
+func (s *TwoFactorService) encrypt(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt расшифровывает секрет, сохраненный encrypt
+func (s *TwoFactorService) decrypt(encrypted string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("encrypted secret is too short")
+	}
+
+	secret, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
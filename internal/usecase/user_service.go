@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"todolist/config"
+	"todolist/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// UserService предоставляет методы для работы с настройками пользователя
+type UserService struct {
+	userRepository domain.UserRepository
+	config         *config.Config
+	logger         *zap.Logger
+}
+
+// NewUserService создает новый экземпляр UserService
+func NewUserService(userRepository domain.UserRepository, config *config.Config, logger *zap.Logger) *UserService {
+	return &UserService{
+		userRepository: userRepository,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// GetLocation возвращает часовой пояс пользователя или часовой пояс по умолчанию
+func (s *UserService) GetLocation(ctx context.Context, userID int64) *time.Location {
+	return userLocation(ctx, s.userRepository, s.config, userID)
+}
+
+// SetTimezone устанавливает часовой пояс пользователя по имени IANA (например, Europe/Berlin).
+// Пустое имя или "off" сбрасывает часовой пояс на значение по умолчанию.
+func (s *UserService) SetTimezone(ctx context.Context, userID int64, name string) (*domain.User, error) {
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	switch strings.ToLower(name) {
+	case "", "off", "default", "нет", "-":
+		name = ""
+	case "utc", "gmt":
+		name = "UTC"
+	default:
+		if _, err := time.LoadLocation(name); err != nil {
+			return nil, domain.NewValidationError("timezone", fmt.Sprintf("неизвестный часовой пояс: %s", name))
+		}
+	}
+
+	if err := s.userRepository.SetTimezone(ctx, userID, name); err != nil {
+		s.logger.Error("failed to set timezone", zap.Error(err))
+		return nil, fmt.Errorf("ошибка сохранения часового пояса: %w", err)
+	}
+	user.Timezone = name
+
+	s.logger.Info("timezone set", zap.Int64("user_id", userID), zap.String("timezone", name))
+	return user, nil
+}
+
+// SetTimezoneFromLocation определяет часовой пояс по координатам и сохраняет его.
+// Без базы границ часовых поясов смещение вычисляется по долготе (15° на час),
+// поэтому результат - зона вида Etc/GMT-3 без учета летнего времени.
+func (s *UserService) SetTimezoneFromLocation(ctx context.Context, userID int64, latitude, longitude float64) (*domain.User, error) {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return nil, domain.NewValidationError("location", "некорректные координаты")
+	}
+
+	return s.SetTimezone(ctx, userID, timezoneForLongitude(longitude))
+}
+
+// timezoneForLongitude возвращает зону Etc/GMT для долготы.
+// Знак в именах Etc/GMT инвертирован: UTC+3 - это Etc/GMT-3.
+func timezoneForLongitude(longitude float64) string {
+	offset := int(math.Round(longitude / 15))
+	switch {
+	case offset == 0:
+		return "UTC"
+	case offset > 0:
+		return fmt.Sprintf("Etc/GMT-%d", offset)
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	}
+}
+
+// userLocation загружает часовой пояс пользователя, при ошибке возвращает часовой пояс по умолчанию
+func userLocation(ctx context.Context, userRepository domain.UserRepository, cfg *config.Config, userID int64) *time.Location {
+	fallback := cfg.Bot.Location()
+
+	user, err := userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return fallback
+	}
+
+	return user.Location(fallback)
+}
@@ -0,0 +1,2 @@
+-- Удаление правила повторения задач
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence;
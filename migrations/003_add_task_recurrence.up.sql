@@ -0,0 +1,4 @@
+-- Добавление правила повторения задач
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN tasks.recurrence IS 'Правило повторения задачи в формате RRULE, пустая строка - без повторения';
@@ -0,0 +1,5 @@
+-- Удаление подзадач
+DROP INDEX IF EXISTS idx_tasks_parent_id;
+
+DELETE FROM tasks WHERE parent_id IS NOT NULL;
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_id;
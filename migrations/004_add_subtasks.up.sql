@@ -0,0 +1,6 @@
+-- Добавление подзадач (пунктов чек-листа)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id);
+
+COMMENT ON COLUMN tasks.parent_id IS 'ID родительской задачи, NULL для задач верхнего уровня';
@@ -0,0 +1,6 @@
+-- Добавление срока выполнения задач
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_due_at ON tasks(due_at);
+
+COMMENT ON COLUMN tasks.due_at IS 'Срок выполнения задачи, не сбрасывается после отправки напоминания';
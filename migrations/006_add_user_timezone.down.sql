@@ -0,0 +1,2 @@
+-- Удаление часового пояса пользователя
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
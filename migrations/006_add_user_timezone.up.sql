@@ -0,0 +1,4 @@
+-- Добавление часового пояса пользователя
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN users.timezone IS 'Часовой пояс IANA (например, Europe/Berlin), пустая строка - часовой пояс по умолчанию';
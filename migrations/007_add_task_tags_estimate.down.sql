@@ -0,0 +1,3 @@
+-- Удаление тегов и оценки трудозатрат задач
+ALTER TABLE tasks DROP COLUMN IF EXISTS estimate_minutes;
+ALTER TABLE tasks DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,6 @@
+-- Добавление тегов и оценки трудозатрат задач
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tags VARCHAR(500) NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_minutes INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN tasks.tags IS 'Теги задачи через запятую без символа #';
+COMMENT ON COLUMN tasks.estimate_minutes IS 'Оценка трудозатрат в минутах, 0 - не задана';
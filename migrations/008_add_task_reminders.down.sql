@@ -0,0 +1,16 @@
+-- Возврат к одному напоминанию в tasks.notify_at
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS notify_at TIMESTAMP;
+
+-- Сохраняем ближайшее неотправленное напоминание каждой задачи
+UPDATE tasks t SET notify_at = r.remind_at
+FROM (
+    SELECT task_id, MIN(remind_at) AS remind_at
+    FROM task_reminders
+    WHERE sent_at IS NULL
+    GROUP BY task_id
+) r
+WHERE r.task_id = t.id;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_notify_at ON tasks(notify_at);
+
+DROP TABLE IF EXISTS task_reminders;
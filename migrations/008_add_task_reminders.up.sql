@@ -0,0 +1,22 @@
+-- Перенос напоминаний в отдельную таблицу: у задачи может быть несколько напоминаний
+CREATE TABLE IF NOT EXISTS task_reminders (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    remind_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (task_id, remind_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_reminders_pending ON task_reminders(remind_at) WHERE sent_at IS NULL;
+
+-- Переносим существующие неотправленные напоминания
+INSERT INTO task_reminders (task_id, remind_at)
+SELECT id, notify_at FROM tasks WHERE notify_at IS NOT NULL AND status = 'pending'
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_tasks_notify_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS notify_at;
+
+COMMENT ON TABLE task_reminders IS 'Напоминания о задачах';
+COMMENT ON COLUMN task_reminders.sent_at IS 'Время отправки напоминания, NULL - еще не отправлено';
@@ -0,0 +1,2 @@
+-- Удаление очереди отправки уведомлений
+DROP TABLE IF EXISTS notification_outbox;
@@ -0,0 +1,19 @@
+-- Очередь отправки уведомлений с повторными попытками
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    reminder_id INTEGER NOT NULL REFERENCES task_reminders(id) ON DELETE CASCADE,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    locked_at TIMESTAMP,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_pending ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_sending ON notification_outbox(locked_at) WHERE status = 'sending';
+
+COMMENT ON TABLE notification_outbox IS 'Очередь отправки напоминаний: pending, sending, sent, dead, cancelled';
+COMMENT ON COLUMN notification_outbox.attempts IS 'Количество попыток отправки, ответы 429 не учитываются';
@@ -0,0 +1,18 @@
+-- Возврат ссылки заметок на Telegram ID пользователя
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint
+        WHERE conname = 'notes_user_id_fkey'
+          AND pg_get_constraintdef(oid) LIKE '%users(id)%') THEN
+        ALTER TABLE notes DROP CONSTRAINT notes_user_id_fkey;
+
+        UPDATE notes n SET user_id = u.telegram_id
+        FROM users u
+        WHERE n.user_id = u.id;
+
+        ALTER TABLE notes ADD CONSTRAINT notes_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(telegram_id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+COMMENT ON COLUMN notes.user_id IS NULL;
@@ -0,0 +1,19 @@
+-- Заметки ссылаются на внутренний ID пользователя, как и задачи.
+-- Раньше notes.user_id хранил Telegram ID и ссылался на users(telegram_id).
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint
+        WHERE conname = 'notes_user_id_fkey'
+          AND pg_get_constraintdef(oid) LIKE '%users(telegram_id)%') THEN
+        ALTER TABLE notes DROP CONSTRAINT notes_user_id_fkey;
+
+        UPDATE notes n SET user_id = u.id
+        FROM users u
+        WHERE n.user_id = u.telegram_id;
+
+        ALTER TABLE notes ADD CONSTRAINT notes_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+COMMENT ON COLUMN notes.user_id IS 'Внутренний ID пользователя (users.id)';
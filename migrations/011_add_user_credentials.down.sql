@@ -0,0 +1,4 @@
+-- Удаление личных паролей и приглашений
+DROP TABLE IF EXISTS invites;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
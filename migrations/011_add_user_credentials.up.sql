@@ -0,0 +1,17 @@
+-- Личные пароли пользователей и одноразовые приглашения
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN users.password_hash IS 'bcrypt-хеш личного пароля, пустая строка - пароль не установлен';
+
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE invites IS 'Одноразовые коды приглашения, выданные администраторами';
+COMMENT ON COLUMN invites.code_hash IS 'SHA-256 кода приглашения, сам код не хранится';
@@ -0,0 +1,2 @@
+-- Удаление журнала попыток входа
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,12 @@
+-- Журнал попыток входа для защиты от подбора пароля
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL,
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_telegram_id ON login_attempts(telegram_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_failed ON login_attempts(created_at) WHERE NOT success;
+
+COMMENT ON TABLE login_attempts IS 'Попытки входа через /start, по ним вычисляется блокировка после неудачных попыток';
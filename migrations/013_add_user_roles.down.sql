@@ -0,0 +1,2 @@
+-- Удаление ролей пользователей
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,5 @@
+-- Роли пользователей
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin'));
+
+COMMENT ON COLUMN users.role IS 'Роль пользователя: user, admin';
@@ -0,0 +1,4 @@
+-- Удаление времени последней активности сессий
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_activity_at;
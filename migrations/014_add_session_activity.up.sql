@@ -0,0 +1,6 @@
+-- Время последней активности для скользящего продления сессий
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_activity_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+COMMENT ON COLUMN sessions.last_activity_at IS 'Время последнего действия пользователя, от него продлевается expires_at';
@@ -0,0 +1,21 @@
+-- Двухфакторная аутентификация (TOTP)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN users.totp_secret IS 'Секрет TOTP, зашифрованный AES-GCM ключом из AUTH_TOTP_KEY';
+COMMENT ON COLUMN users.totp_enabled IS 'Вход требует код из приложения-аутентификатора';
+COMMENT ON COLUMN users.totp_last_step IS 'Временной шаг последнего принятого кода, защищает от повторного использования';
+
+-- Одноразовые коды восстановления на случай потери телефона
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+COMMENT ON TABLE recovery_codes IS 'Коды восстановления двухфакторной аутентификации, хранится только SHA-256';
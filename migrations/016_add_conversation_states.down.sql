@@ -0,0 +1,2 @@
+-- Удаление состояний диалогов
+DROP TABLE IF EXISTS conversation_states;
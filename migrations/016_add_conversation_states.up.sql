@@ -0,0 +1,12 @@
+-- Состояния многошаговых диалогов, чтобы незаконченный диалог переживал перезапуск бота
+CREATE TABLE IF NOT EXISTS conversation_states (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL,
+    step INTEGER NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE conversation_states IS 'Текущий диалог пользователя с ботом, не больше одного на пользователя';
+COMMENT ON COLUMN conversation_states.data IS 'Данные диалога, формат определяет обработчик телеграм бота';
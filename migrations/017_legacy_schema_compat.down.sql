@@ -0,0 +1,2 @@
+-- Индексы и комментарии принадлежат миграции 001 и удаляются ее откатом
+SELECT 1;
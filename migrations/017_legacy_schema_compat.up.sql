@@ -0,0 +1,18 @@
+-- Базы, созданные до появления schema_migrations методом CreateTables, не получили индексы
+-- и комментарии миграции 001: Migrator отмечает 001 примененной, не выполняя ее повторно.
+-- Колонка tasks.notify_at в таких базах уже удалена, поэтому ее индекс здесь не создается.
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_telegram_id ON sessions(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_priority ON tasks(priority);
+
+COMMENT ON TABLE users IS 'Таблица пользователей системы';
+COMMENT ON TABLE sessions IS 'Активные сессии пользователей';
+COMMENT ON TABLE tasks IS 'Задачи пользователей';
+
+COMMENT ON COLUMN users.telegram_id IS 'Уникальный ID пользователя в Telegram';
+COMMENT ON COLUMN tasks.status IS 'Статус задачи: pending, completed, deleted';
+COMMENT ON COLUMN tasks.priority IS 'Приоритет задачи: low, medium, high';
@@ -0,0 +1,16 @@
+-- Возврат к TIMESTAMP со временем в UTC. Колонки заметок изначально созданы как TIMESTAMPTZ.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp with time zone'
+          AND table_name NOT IN ('schema_migrations', 'notes')
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMP USING %I AT TIME ZONE ''UTC''',
+            col.table_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
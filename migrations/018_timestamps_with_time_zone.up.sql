@@ -0,0 +1,28 @@
+-- Перевод временных меток в TIMESTAMPTZ.
+-- Прежние версии бота записывали в колонки TIMESTAMP местное время сервера, текущая - время в UTC.
+-- Существующие значения считаются записанными в часовом поясе DB_LEGACY_TIMEZONE
+-- (параметр todolist.legacy_timezone, по умолчанию UTC). Миграция выполняется до того,
+-- как новая версия что-либо запишет, поэтому все значения в колонках TIMESTAMP - прежние.
+-- Исключение - sessions.last_activity_at: миграция 014 заполнила ее в сессии с часовым поясом UTC.
+DO $$
+DECLARE
+    legacy_zone TEXT := COALESCE(NULLIF(current_setting('todolist.legacy_timezone', true), ''), 'UTC');
+    zone TEXT;
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp without time zone'
+          AND table_name <> 'schema_migrations'
+    LOOP
+        zone := legacy_zone;
+        IF col.table_name = 'sessions' AND col.column_name = 'last_activity_at' THEN
+            zone := 'UTC';
+        END IF;
+
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE %L',
+            col.table_name, col.column_name, col.column_name, zone);
+    END LOOP;
+END $$;
@@ -0,0 +1,2 @@
+-- Индекс для периодической очистки истекших диалогов
+CREATE INDEX IF NOT EXISTS idx_conversation_states_expires_at ON conversation_states(expires_at);
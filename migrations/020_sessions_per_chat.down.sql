@@ -0,0 +1,8 @@
+-- Возврат к одной сессии на Telegram ID: остается сессия личного чата
+DELETE FROM sessions WHERE chat_id <> telegram_id;
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_telegram_id_chat_id_key;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_pkey;
+ALTER TABLE sessions DROP COLUMN IF EXISTS chat_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS id;
+ALTER TABLE sessions ADD PRIMARY KEY (telegram_id);
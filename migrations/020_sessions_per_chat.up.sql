@@ -0,0 +1,14 @@
+-- Сессия в каждом чате, где пользователь вошел: Telegram не сообщает боту устройство,
+-- поэтому местом входа считается чат. Существующие сессии относятся к личному чату с ботом,
+-- ID которого совпадает с Telegram ID пользователя.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS chat_id BIGINT;
+
+UPDATE sessions SET chat_id = telegram_id WHERE chat_id IS NULL;
+ALTER TABLE sessions ALTER COLUMN chat_id SET NOT NULL;
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_pkey;
+ALTER TABLE sessions ADD PRIMARY KEY (id);
+ALTER TABLE sessions ADD CONSTRAINT sessions_telegram_id_chat_id_key UNIQUE (telegram_id, chat_id);
+
+COMMENT ON COLUMN sessions.chat_id IS 'Чат, в котором пользователь вошел; у личного чата совпадает с telegram_id';